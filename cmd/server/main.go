@@ -60,6 +60,18 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			if err := runMigrate(cfg, args[1:]); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		default:
+			log.Fatalf("Unknown command %q", args[0])
+		}
+		return
+	}
+
 	srv, err := server.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
@@ -74,7 +86,7 @@ func main() {
 	<-quit
 	log.Println("Shutting down servers...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
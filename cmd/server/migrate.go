@@ -0,0 +1,96 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/db"
+)
+
+const migrateUsage = `usage: headmail [-config file] migrate <command>
+
+commands:
+  up [N]     apply all pending migrations, or the next N
+  down [N]   revert the last N applied migrations (default 1)
+  status     list migrations and whether they are applied`
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	steps := 0
+	if args[0] == "down" {
+		steps = 1
+	}
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid step count %q\n%s", args[1], migrateUsage)
+		}
+		steps = n
+	}
+
+	provider, err := db.GetProvider(cfg.Database.Type)
+	if err != nil {
+		return err
+	}
+	conn, err := provider.New(cfg.Database)
+	if err != nil {
+		return err
+	}
+	m, ok := conn.(db.Migrator)
+	if !ok {
+		return fmt.Errorf("database type '%s' does not support migrations", cfg.Database.Type)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		n, err := m.MigrateUp(ctx, steps)
+		fmt.Printf("applied %d migration(s)\n", n)
+		return err
+	case "down":
+		n, err := m.MigrateDown(ctx, steps)
+		fmt.Printf("reverted %d migration(s)\n", n)
+		return err
+	case "status":
+		if len(args) != 1 {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		statuses, err := m.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(statuses)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+func printMigrationStatus(statuses []db.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status := "pending"
+		appliedAt := "-"
+		if s.Applied {
+			status = "applied"
+			if s.Unknown {
+				status = "applied (unknown to this build)"
+			}
+			appliedAt = time.Unix(*s.AppliedAt, 0).UTC().Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	return w.Flush()
+}
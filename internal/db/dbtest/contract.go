@@ -0,0 +1,988 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package dbtest contains the repository contract test suite shared by all
+// storage providers. Each provider runs it from its own tests so every
+// backend is held to the same behavior.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OpenFunc returns an empty, fully migrated database for a single test.
+type OpenFunc func(t *testing.T) repository.DB
+
+// Run runs the repository contract suite against the databases returned by open.
+func Run(t *testing.T, open OpenFunc) {
+	t.Run("Transaction", func(t *testing.T) { testTransaction(t, open(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, open(t)) })
+	t.Run("Subscriber", func(t *testing.T) { testSubscriber(t, open(t)) })
+	t.Run("ListMembership", func(t *testing.T) { testListMembership(t, open(t)) })
+	t.Run("ListStatus", func(t *testing.T) { testListStatus(t, open(t)) })
+	t.Run("Campaign", func(t *testing.T) { testCampaign(t, open(t)) })
+	t.Run("Delivery", func(t *testing.T) { testDelivery(t, open(t)) })
+	t.Run("Template", func(t *testing.T) { testTemplate(t, open(t)) })
+	t.Run("Event", func(t *testing.T) { testEvent(t, open(t)) })
+	t.Run("Suppression", func(t *testing.T) { testSuppression(t, open(t)) })
+	t.Run("APIKey", func(t *testing.T) { testAPIKey(t, open(t)) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, open(t)) })
+	t.Run("Link", func(t *testing.T) { testLink(t, open(t)) })
+	t.Run("Queue", func(t *testing.T) { testQueue(t, open(t)) })
+	t.Run("QueueReleaseExpired", func(t *testing.T) { testQueueReleaseExpired(t, open(t)) })
+	t.Run("QueueDefer", func(t *testing.T) { testQueueDefer(t, open(t)) })
+	t.Run("QueueClaimFilter", func(t *testing.T) { testQueueClaimFilter(t, open(t)) })
+	t.Run("QueueInspection", func(t *testing.T) { testQueueInspection(t, open(t)) })
+}
+
+func testTransaction(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.ListRepository()
+
+	t.Run("rollback", func(t *testing.T) {
+		err := repository.Transactional0(db, ctx, func(txCtx context.Context) error {
+			require.NoError(t, repo.Create(txCtx, newList("tx-rollback")))
+			return errors.New("abort")
+		})
+		require.Error(t, err)
+
+		_, err = repo.GetByID(ctx, "tx-rollback")
+		assert.Error(t, err)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		err := repository.Transactional0(db, ctx, func(txCtx context.Context) error {
+			return repo.Create(txCtx, newList("tx-commit"))
+		})
+		require.NoError(t, err)
+
+		list, err := repo.GetByID(ctx, "tx-commit")
+		require.NoError(t, err)
+		assert.Equal(t, "tx-commit", list.Name)
+	})
+}
+
+func testList(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.ListRepository()
+
+	a := newList("list-a", "go", "test")
+	b := newList("list-b", "rust", "test")
+	require.NoError(t, repo.Create(ctx, a))
+	require.NoError(t, repo.Create(ctx, b))
+
+	got, err := repo.GetByID(ctx, "list-a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "test"}, got.Tags)
+
+	got.Description = "updated"
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, "list-a")
+	require.NoError(t, err)
+	assert.Equal(t, "updated", got.Description)
+
+	lists, total, err := repo.List(ctx, repository.ListFilter{Tags: []string{"test"}}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, lists, 2)
+
+	lists, total, err = repo.List(ctx, repository.ListFilter{Tags: []string{"go", "test"}}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, lists, 1)
+	assert.Equal(t, "list-a", lists[0].ID)
+
+	_, total, err = repo.List(ctx, repository.ListFilter{Search: "list-b"}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	require.NoError(t, repo.Delete(ctx, "list-b"))
+	_, total, err = repo.List(ctx, repository.ListFilter{}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func testSubscriber(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.SubscriberRepository()
+	require.NoError(t, db.ListRepository().Create(ctx, newList("sub-list")))
+
+	now := time.Now().Unix()
+	sub := &domain.Subscriber{
+		ID:        "sub-1",
+		Email:     "one@example.com",
+		Name:      "One",
+		Status:    domain.SubscriberStatusEnabled,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Lists: []domain.SubscriberList{
+			{ListID: "sub-list", Status: domain.SubscriberListStatusConfirmed, SubscribedAt: &now, CreatedAt: now, UpdatedAt: now},
+		},
+	}
+	require.NoError(t, repo.Create(ctx, sub))
+
+	got, err := repo.GetByEmail(ctx, "one@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "sub-1", got.ID)
+	require.Len(t, got.Lists, 1)
+	assert.Equal(t, "sub-list", got.Lists[0].ListID)
+
+	dup := &domain.Subscriber{ID: "sub-dup", Email: "one@example.com", Status: domain.SubscriberStatusEnabled}
+	err = repo.Create(ctx, dup)
+	var uniqueErr *repository.ErrUniqueConstraintFailed
+	assert.ErrorAs(t, err, &uniqueErr)
+
+	_, err = repo.GetByID(ctx, "missing")
+	var notFound *repository.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	got.Name = "Renamed"
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, "sub-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Name)
+
+	// BulkUpsert updates existing subscribers by email and creates new ones.
+	bulk := []*domain.Subscriber{
+		{Email: "one@example.com", Name: "One Again", Status: domain.SubscriberStatusEnabled, UpdatedAt: now},
+		{Email: "two@example.com", Name: "Two", Status: domain.SubscriberStatusEnabled, CreatedAt: now, UpdatedAt: now,
+			Lists: []domain.SubscriberList{{ListID: "sub-list", Status: domain.SubscriberListStatusConfirmed, CreatedAt: now, UpdatedAt: now}}},
+	}
+	require.NoError(t, repo.BulkUpsert(ctx, bulk))
+	assert.Equal(t, "sub-1", bulk[0].ID)
+	assert.NotEmpty(t, bulk[1].ID)
+
+	subs, total, err := repo.List(ctx, repository.SubscriberFilter{ListID: "sub-list"}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, subs, 2)
+
+	stream, err := repo.ListStream(ctx, repository.SubscriberFilter{ListID: "sub-list", Status: domain.SubscriberStatusEnabled})
+	require.NoError(t, err)
+	var emails []string
+	for s := range stream {
+		emails = append(emails, s.Email)
+	}
+	assert.ElementsMatch(t, []string{"one@example.com", "two@example.com"}, emails)
+
+	require.NoError(t, repo.Delete(ctx, "sub-1"))
+	_, total, err = repo.List(ctx, repository.SubscriberFilter{}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func testListMembership(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	lists := db.ListRepository()
+	subs := db.SubscriberRepository()
+
+	require.NoError(t, lists.Create(ctx, newList("members")))
+	for _, id := range []string{"m-1", "m-2", "m-3"} {
+		require.NoError(t, subs.Create(ctx, &domain.Subscriber{ID: id, Email: id + "@example.com", Status: domain.SubscriberStatusEnabled}))
+	}
+
+	require.NoError(t, lists.AddSubscribers(ctx, "members", []string{"m-1", "m-2"}))
+	// adding an existing member is ignored
+	require.NoError(t, lists.AddSubscribers(ctx, "members", []string{"m-2"}))
+	count, err := lists.GetSubscriberCount(ctx, "members")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, lists.RemoveSubscribers(ctx, "members", []string{"m-1"}))
+	count, err = lists.GetSubscriberCount(ctx, "members")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, lists.ReplaceSubscribers(ctx, "members", []string{"m-1", "m-3"}))
+	got, _, err := subs.List(ctx, repository.SubscriberFilter{ListID: "members"}, page(1, 10))
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range got {
+		ids = append(ids, s.ID)
+	}
+	assert.ElementsMatch(t, []string{"m-1", "m-3"}, ids)
+}
+
+func testListStatus(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	lists := db.ListRepository()
+	subs := db.SubscriberRepository()
+
+	require.NoError(t, lists.Create(ctx, newList("status-a")))
+	require.NoError(t, lists.Create(ctx, newList("status-b")))
+	require.NoError(t, subs.Create(ctx, &domain.Subscriber{ID: "s-1", Email: "s-1@example.com", Status: domain.SubscriberStatusEnabled}))
+	require.NoError(t, lists.AddSubscribers(ctx, "status-a", []string{"s-1"}))
+	require.NoError(t, lists.AddSubscribers(ctx, "status-b", []string{"s-1"}))
+
+	at := time.Now().Unix()
+	n, err := subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusUnsubscribed, at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// already unsubscribed memberships are not changed again
+	n, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusUnsubscribed, at+1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	got, err := subs.GetByID(ctx, "s-1")
+	require.NoError(t, err)
+	statuses := map[string]domain.SubscriberListStatus{}
+	for _, l := range got.Lists {
+		statuses[l.ListID] = l.Status
+		if l.ListID == "status-a" {
+			require.NotNil(t, l.UnsubscribedAt)
+			assert.Equal(t, at, *l.UnsubscribedAt)
+		}
+	}
+	assert.Equal(t, domain.SubscriberListStatusUnsubscribed, statuses["status-a"])
+	assert.Equal(t, domain.SubscriberListStatusConfirmed, statuses["status-b"])
+
+	// only confirmed members are counted
+	count, err := lists.GetSubscriberCount(ctx, "status-a")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// resubscribing sets subscribed_at
+	n, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusConfirmed, at+2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	count, err = lists.GetSubscriberCount(ctx, "status-a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// an empty list ID applies to all lists of the subscriber
+	n, err = subs.SetListStatus(ctx, "s-1", "", domain.SubscriberListStatusUnsubscribed, at)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, total, err := subs.List(ctx, repository.SubscriberFilter{ListID: "status-b", ListStatus: domain.SubscriberListStatusConfirmed}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+
+	// updates of all lists only move memberships to a status of higher precedence
+	_, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusComplained, at)
+	require.NoError(t, err)
+	n, err = subs.SetListStatus(ctx, "s-1", "", domain.SubscriberListStatusBounced, at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only the unsubscribed membership is bounced")
+	n, err = subs.SetListStatus(ctx, "s-1", "", domain.SubscriberListStatusUnsubscribed, at)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	got, err = subs.GetByID(ctx, "s-1")
+	require.NoError(t, err)
+	statuses = map[string]domain.SubscriberListStatus{}
+	for _, l := range got.Lists {
+		statuses[l.ListID] = l.Status
+	}
+	assert.Equal(t, map[string]domain.SubscriberListStatus{
+		"status-a": domain.SubscriberListStatusComplained,
+		"status-b": domain.SubscriberListStatusBounced,
+	}, statuses)
+
+	// so do updates of a single list
+	n, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusUnsubscribed, at)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusBounced, at)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = subs.SetListStatus(ctx, "s-1", "status-b", domain.SubscriberListStatusUnsubscribed, at)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = subs.SetListStatus(ctx, "s-1", "status-b", domain.SubscriberListStatusComplained, at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// only memberships that have had the status since before the cutoff are deleted
+	_, err = subs.SetListStatus(ctx, "s-1", "status-a", domain.SubscriberListStatusPending, at)
+	require.NoError(t, err)
+	_, err = subs.SetListStatus(ctx, "s-1", "status-b", domain.SubscriberListStatusPending, at+10)
+	require.NoError(t, err)
+	n, err = subs.DeleteListMemberships(ctx, domain.SubscriberListStatusPending, at+5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	got, err = subs.GetByID(ctx, "s-1")
+	require.NoError(t, err)
+	require.Len(t, got.Lists, 1)
+	assert.Equal(t, "status-b", got.Lists[0].ListID)
+}
+
+func testCampaign(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.CampaignRepository()
+
+	past := time.Now().Add(-time.Hour).Unix()
+	c1 := newCampaign("camp-1", domain.CampaignStatusDraft, "go", "test")
+	c1.Data = map[string]interface{}{"company": "Acme"}
+	c1.Headers = map[string]string{"X-Test": "1"}
+	c2 := newCampaign("camp-2", domain.CampaignStatusScheduled, "rust")
+	c2.ScheduledAt = &past
+	require.NoError(t, repo.Create(ctx, c1))
+	require.NoError(t, repo.Create(ctx, c2))
+
+	got, err := repo.GetByID(ctx, "camp-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", got.Data["company"])
+	assert.Equal(t, "1", got.Headers["X-Test"])
+
+	got.Subject = "Updated subject"
+	got.TemplateMJML = "<mjml></mjml>"
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, "camp-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Updated subject", got.Subject)
+	assert.Equal(t, "<mjml></mjml>", got.TemplateMJML)
+
+	campaigns, total, err := repo.List(ctx, repository.CampaignFilter{Tags: []string{"go"}}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, campaigns, 1)
+	assert.Equal(t, "camp-1", campaigns[0].ID)
+
+	_, total, err = repo.List(ctx, repository.CampaignFilter{Status: []domain.CampaignStatus{domain.CampaignStatusScheduled}}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	scheduled, err := repo.ListScheduledBefore(ctx, time.Now().Unix())
+	require.NoError(t, err)
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, "camp-2", scheduled[0].ID)
+
+	require.NoError(t, repo.IncrementStats(ctx, "camp-1", 3, 2, 1, 4, 5, 6, 7))
+	require.NoError(t, repo.IncrementStats(ctx, "camp-1", 1, 0, 0, 0, 0, 0, 0))
+	got, err = repo.GetByID(ctx, "camp-1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.RecipientCount)
+	assert.Equal(t, 2, got.DeliveredCount)
+	assert.Equal(t, 1, got.FailedCount)
+	assert.Equal(t, 4, got.OpenCount)
+	assert.Equal(t, 5, got.ClickCount)
+	assert.Equal(t, 6, got.BounceCount)
+	assert.Equal(t, 7, got.ComplaintCount)
+
+	require.NoError(t, repo.UpdateStatus(ctx, "camp-1", domain.CampaignStatusSending))
+	got, err = repo.GetByID(ctx, "camp-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.CampaignStatusSending, got.Status)
+
+	require.NoError(t, repo.Delete(ctx, "camp-2"))
+	_, total, err = repo.List(ctx, repository.CampaignFilter{}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func testDelivery(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.DeliveryRepository()
+	require.NoError(t, db.CampaignRepository().Create(ctx, newCampaign("del-camp", domain.CampaignStatusDraft)))
+
+	campaignID := "del-camp"
+	past := time.Now().Add(-time.Minute).Unix()
+	d1 := newDelivery("del-1", &campaignID, domain.DeliveryStatusIdle)
+	d1.FromName = "News"
+	d1.FromEmail = "news@example.com"
+	listID := "del-list"
+	d1.ListID = &listID
+	d2 := newDelivery("del-2", &campaignID, domain.DeliveryStatusScheduled)
+	d2.ScheduledAt = &past
+	d3 := newDelivery("del-3", nil, domain.DeliveryStatusQueued)
+	require.NoError(t, repo.Create(ctx, d1))
+	require.NoError(t, repo.BulkCreate(ctx, []*domain.Delivery{d2, d3}))
+
+	got, err := repo.GetByID(ctx, "del-1")
+	require.NoError(t, err)
+	assert.Equal(t, "value", got.Data["key"])
+	assert.Equal(t, "v", got.Headers["X-H"])
+	assert.Equal(t, []string{"t"}, got.Tags)
+	assert.Equal(t, "News", got.FromName)
+	assert.Equal(t, "news@example.com", got.FromEmail)
+	require.NotNil(t, got.ListID)
+	assert.Equal(t, "del-list", *got.ListID)
+
+	deliveries, total, err := repo.GetByCampaignID(ctx, campaignID, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, deliveries, 2)
+
+	_, total, err = repo.List(ctx, repository.DeliveryFilter{Status: string(domain.DeliveryStatusQueued)}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	due, err := repo.ListScheduledBefore(ctx, time.Now().Unix(), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "del-2", due[0].ID)
+
+	n, err := repo.UpdateSendScheduledByCampaign(ctx, campaignID, time.Now().Unix())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	got, err = repo.GetByID(ctx, "del-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusScheduled, got.Status)
+	assert.NotNil(t, got.ScheduledAt)
+
+	first, err := repo.IncrementCount(ctx, "del-1", domain.EventTypeOpened)
+	require.NoError(t, err)
+	assert.True(t, first)
+	first, err = repo.IncrementCount(ctx, "del-1", domain.EventTypeOpened)
+	require.NoError(t, err)
+	assert.False(t, first)
+	first, err = repo.IncrementCount(ctx, "del-1", domain.EventTypeClicked)
+	require.NoError(t, err)
+	assert.True(t, first)
+	first, err = repo.IncrementCount(ctx, "del-1", domain.EventTypeComplained)
+	require.NoError(t, err)
+	assert.True(t, first)
+	got, err = repo.GetByID(ctx, "del-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.OpenCount)
+	assert.Equal(t, 1, got.ClickCount)
+	assert.Equal(t, 1, got.ComplaintCount)
+	assert.NotNil(t, got.OpenedAt)
+
+	require.NoError(t, repo.UpdateStatus(ctx, "del-3", domain.DeliveryStatusSent))
+	got, err = repo.GetByID(ctx, "del-3")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusSent, got.Status)
+
+	reason := "mailbox full"
+	got.FailureReason = &reason
+	got.Attempts = 2
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, "del-3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Attempts)
+	require.NotNil(t, got.FailureReason)
+	assert.Equal(t, reason, *got.FailureReason)
+}
+
+func testTemplate(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.TemplateRepository()
+
+	tmpl := &domain.Template{Name: "Welcome", Subject: "Hi", BodyMJML: "<mjml></mjml>"}
+	require.NoError(t, repo.Create(ctx, tmpl))
+	require.NotEmpty(t, tmpl.ID)
+
+	got, err := repo.GetByID(ctx, tmpl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome", got.Name)
+
+	got.Subject = "Hello"
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, tmpl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", got.Subject)
+
+	templates, total, err := repo.List(ctx, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, templates, 1)
+
+	require.NoError(t, repo.Delete(ctx, tmpl.ID))
+	_, total, err = repo.List(ctx, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func testSuppression(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.SuppressionRepository()
+	now := time.Now().Unix()
+	past := now - 60
+
+	bounce := &domain.Suppression{ID: "sup-1", Type: domain.SuppressionTypeEmail, Value: "bob@example.com", Reason: domain.SuppressionReasonHardBounce, Source: domain.SuppressionSourceBounce, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Upsert(ctx, bounce))
+	require.NoError(t, repo.Upsert(ctx, &domain.Suppression{ID: "sup-2", Type: domain.SuppressionTypeDomain, Value: "spam.example", Reason: domain.SuppressionReasonManual, Source: domain.SuppressionSourceAdmin, CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, repo.Upsert(ctx, &domain.Suppression{ID: "sup-3", Type: domain.SuppressionTypeEmail, Value: "old@example.com", Reason: domain.SuppressionReasonManual, ExpiresAt: &past, CreatedAt: now, UpdatedAt: now}))
+
+	// upserting the same value keeps the stored ID and updates the entry
+	again := &domain.Suppression{ID: "sup-4", Type: domain.SuppressionTypeEmail, Value: "bob@example.com", Reason: domain.SuppressionReasonComplaint, Source: domain.SuppressionSourceImport, CreatedAt: now + 1, UpdatedAt: now + 1}
+	require.NoError(t, repo.Upsert(ctx, again))
+	assert.Equal(t, "sup-1", again.ID)
+	got, err := repo.GetByID(ctx, "sup-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuppressionReasonComplaint, got.Reason)
+	assert.Equal(t, domain.SuppressionSourceImport, got.Source)
+
+	got, err = repo.Match(ctx, "bob@example.com", "example.com", now)
+	require.NoError(t, err)
+	assert.Equal(t, "sup-1", got.ID)
+	got, err = repo.Match(ctx, "alice@spam.example", "spam.example", now)
+	require.NoError(t, err)
+	assert.Equal(t, "sup-2", got.ID)
+	var notFound *repository.ErrNotFound
+	_, err = repo.Match(ctx, "old@example.com", "example.com", now)
+	assert.ErrorAs(t, err, &notFound, "expired entries do not match")
+
+	entries, total, err := repo.List(ctx, repository.SuppressionFilter{Type: domain.SuppressionTypeEmail}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, entries, 2)
+	_, total, err = repo.List(ctx, repository.SuppressionFilter{Search: "spam"}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	require.NoError(t, repo.Delete(ctx, "sup-2"))
+	assert.ErrorAs(t, repo.Delete(ctx, "sup-2"), &notFound)
+	_, err = repo.Match(ctx, "alice@spam.example", "spam.example", now)
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func testAPIKey(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.APIKeyRepository()
+	now := time.Now().Unix()
+
+	tx := &domain.APIKey{ID: "key-1", Name: "tx", Prefix: "hm_aaaa", Hash: "hash-1", Scopes: []string{"tx:send"}, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, tx))
+	require.NoError(t, repo.Create(ctx, &domain.APIKey{ID: "key-2", Name: "ops", Prefix: "hm_bbbb", Hash: "hash-2", CreatedAt: now + 1, UpdatedAt: now + 1}))
+	assert.Error(t, repo.Create(ctx, &domain.APIKey{ID: "key-3", Hash: "hash-1"}), "hashes are unique")
+
+	got, err := repo.GetByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", got.ID)
+	assert.Equal(t, []string{"tx:send"}, got.Scopes)
+	assert.Nil(t, got.LastUsedAt)
+
+	require.NoError(t, repo.TouchLastUsed(ctx, "key-1", now+5))
+	got.Hash = "hash-rotated"
+	got.RevokedAt = &now
+	require.NoError(t, repo.Update(ctx, got))
+	got, err = repo.GetByID(ctx, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, "hash-rotated", got.Hash)
+	require.NotNil(t, got.LastUsedAt)
+	assert.Equal(t, now+5, *got.LastUsedAt)
+	require.NotNil(t, got.RevokedAt)
+
+	var notFound *repository.ErrNotFound
+	_, err = repo.GetByHash(ctx, "hash-1")
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorAs(t, repo.Update(ctx, &domain.APIKey{ID: "missing"}), &notFound)
+
+	keys, total, err := repo.List(ctx, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "key-2", keys[0].ID)
+	assert.Empty(t, keys[0].Scopes)
+}
+
+func testAudit(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.AuditRepository()
+	base := time.Now().Unix()
+
+	update := &domain.AuditEntry{
+		ID: "audit-1", Principal: "api_key:ci", Action: "campaign.update", EntityType: "campaign", EntityID: "camp-1",
+		Method: "PUT", Path: "/api/campaigns/camp-1", CreatedAt: base,
+		Changes: map[string]domain.AuditChange{"subject": {Before: "Hello", After: "Hi"}},
+	}
+	require.NoError(t, repo.Create(ctx, update))
+	require.NoError(t, repo.Create(ctx, &domain.AuditEntry{ID: "audit-2", Principal: "jwt:alice", Action: "list.delete", EntityType: "list", EntityID: "list-1", Method: "DELETE", Path: "/api/lists/list-1", CreatedAt: base + 10}))
+	require.NoError(t, repo.Create(ctx, &domain.AuditEntry{ID: "audit-3", Action: "queue_item.replay", EntityType: "queue_item", Method: "POST", Path: "/api/queue/replay", CreatedAt: base + 20}))
+
+	entries, total, err := repo.List(ctx, repository.AuditFilter{}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "audit-3", entries[0].ID, "newest first")
+	assert.Nil(t, entries[0].Changes)
+
+	entries, total, err = repo.List(ctx, repository.AuditFilter{EntityType: "campaign", EntityID: "camp-1"}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, entries, 1)
+	assert.Equal(t, domain.AuditChange{Before: "Hello", After: "Hi"}, entries[0].Changes["subject"])
+
+	_, total, err = repo.List(ctx, repository.AuditFilter{Principal: "jwt:alice", Action: "list.delete"}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	_, total, err = repo.List(ctx, repository.AuditFilter{Since: base + 5, Until: base + 10}, page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func testLink(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.LinkRepository()
+	now := time.Now().Unix()
+
+	first := &domain.Link{ID: "link-1", CampaignID: "camp-1", URL: "https://example.com/a", CreatedAt: now}
+	require.NoError(t, repo.Upsert(ctx, first))
+	require.NoError(t, repo.Upsert(ctx, &domain.Link{ID: "link-2", CampaignID: "camp-1", URL: "https://example.com/b", CreatedAt: now}))
+	require.NoError(t, repo.Upsert(ctx, &domain.Link{ID: "link-3", CampaignID: "camp-2", URL: "https://example.com/a", CreatedAt: now}))
+
+	require.NoError(t, repo.IncrementClicks(ctx, "link-2"))
+	require.NoError(t, repo.IncrementClicks(ctx, "link-2"))
+	var notFound *repository.ErrNotFound
+	assert.ErrorAs(t, repo.IncrementClicks(ctx, "missing"), &notFound)
+
+	// upserting an existing link keeps the stored link
+	again := &domain.Link{ID: "link-2", CampaignID: "camp-1", URL: "https://example.com/b", CreatedAt: now + 60}
+	require.NoError(t, repo.Upsert(ctx, again))
+	assert.Equal(t, 2, again.ClickCount)
+	assert.Equal(t, now, again.CreatedAt)
+
+	got, err := repo.GetByID(ctx, "link-1")
+	require.NoError(t, err)
+	assert.Equal(t, first, got)
+	_, err = repo.GetByID(ctx, "missing")
+	assert.ErrorAs(t, err, &notFound)
+
+	links, total, err := repo.ListByCampaign(ctx, "camp-1", page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, links, 2)
+	assert.Equal(t, "link-2", links[0].ID, "most clicked first")
+	assert.Equal(t, 2, links[0].ClickCount)
+
+	// limited upserts stop creating links of a campaign at the limit
+	require.NoError(t, db.CampaignRepository().Create(ctx, newCampaign("link-camp", domain.CampaignStatusSending)))
+	for i, want := range []bool{true, true, false} {
+		ok, err := repo.UpsertLimited(ctx, &domain.Link{ID: fmt.Sprintf("limited-%d", i), CampaignID: "link-camp", URL: fmt.Sprintf("https://example.com/%d", i), CreatedAt: now}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, i)
+	}
+	_, err = repo.GetByID(ctx, "limited-2")
+	assert.ErrorAs(t, err, &notFound, "links beyond the limit are not stored")
+	// existing links are returned at the limit
+	existing := &domain.Link{ID: "limited-0", CampaignID: "link-camp", URL: "https://example.com/0", CreatedAt: now + 60}
+	ok, err := repo.UpsertLimited(ctx, existing, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, now, existing.CreatedAt)
+	_, total, err = repo.ListByCampaign(ctx, "link-camp", page(1, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func testEvent(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	repo := db.EventRepository()
+	campaignID := "ev-camp"
+	require.NoError(t, db.CampaignRepository().Create(ctx, newCampaign(campaignID, domain.CampaignStatusSending)))
+	require.NoError(t, db.DeliveryRepository().Create(ctx, newDelivery("ev-del", &campaignID, domain.DeliveryStatusSent)))
+
+	base := int64(1_700_000_000) / 3600 * 3600
+	events := []*domain.DeliveryEvent{
+		{DeliveryID: "ev-del", EventType: domain.EventTypeOpened, CreatedAt: base + 10},
+		{DeliveryID: "ev-del", EventType: domain.EventTypeOpened, CreatedAt: base + 20},
+		{DeliveryID: "ev-del", EventType: domain.EventTypeClicked, CreatedAt: base + 30, EventData: map[string]interface{}{"url": "https://example.com"}},
+		{DeliveryID: "ev-del", EventType: domain.EventTypeOpened, CreatedAt: base + 3600 + 5},
+	}
+	for _, ev := range events {
+		require.NoError(t, repo.Create(ctx, ev))
+		assert.NotEmpty(t, ev.ID)
+	}
+
+	listed, err := repo.ListByCampaignAndRange(ctx, []string{campaignID}, base, base+7200)
+	require.NoError(t, err)
+	require.Len(t, listed, 4)
+	assert.Equal(t, "https://example.com", listed[2].EventData["url"])
+
+	counts, err := repo.CountByCampaignAndRange(ctx, []string{campaignID}, base, base+7200, "hour")
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{base: 3, base + 3600: 1}, counts[campaignID])
+
+	opens, err := repo.CountByCampaignAndRangeByType(ctx, []string{campaignID}, string(domain.EventTypeOpened), base, base+7200, "hour")
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{base: 2, base + 3600: 1}, opens[campaignID])
+
+	daily, err := repo.CountByCampaignAndRange(ctx, []string{campaignID}, base, base+7200, "day")
+	require.NoError(t, err)
+	var sum int64
+	for _, v := range daily[campaignID] {
+		sum += v
+	}
+	assert.Equal(t, int64(4), sum)
+
+	// distinct deliveries to a recipient with events of a type since a time
+	second := newDelivery("ev-del-2", &campaignID, domain.DeliveryStatusSent)
+	second.Email = "ev-del@example.com"
+	require.NoError(t, db.DeliveryRepository().Create(ctx, second))
+	require.NoError(t, repo.Create(ctx, &domain.DeliveryEvent{DeliveryID: "ev-del-2", EventType: domain.EventTypeOpened, CreatedAt: base + 7200}))
+	n, err := repo.CountDeliveriesByRecipient(ctx, "ev-del@example.com", domain.EventTypeOpened, base)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = repo.CountDeliveriesByRecipient(ctx, "ev-del@example.com", domain.EventTypeOpened, base+3600+6)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = repo.CountDeliveriesByRecipient(ctx, "ev-del@example.com", domain.EventTypeBounced, base)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func testQueue(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	q := db.QueueRepository()
+
+	key := "job:1"
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: uuid.NewString(), Type: "test", Payload: []byte(`{"n":1}`), UniqueKey: &key}))
+	// duplicate unique keys are ignored
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: uuid.NewString(), Type: "test", Payload: []byte(`{"n":1}`), UniqueKey: &key}))
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: uuid.NewString(), Type: "test", Payload: []byte(`{"n":2}`)}))
+
+	items, err := q.Claim(ctx, "worker-1", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, it := range items {
+		assert.Equal(t, queue.StatusReserved, it.Status)
+		require.NotNil(t, it.ReservedBy)
+		assert.Equal(t, "worker-1", *it.ReservedBy)
+	}
+
+	// reserved items are not claimed again
+	again, err := q.Claim(ctx, "worker-2", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	for _, it := range items {
+		assert.Equal(t, 1, it.Attempts)
+	}
+
+	// only the worker holding the reservation can ack or fail an item
+	assert.ErrorIs(t, q.Ack(ctx, items[0].ID, "worker-2"), queue.ErrLeaseLost)
+	assert.ErrorIs(t, q.Fail(ctx, items[0].ID, "worker-2", "boom", nil), queue.ErrLeaseLost)
+	require.NoError(t, q.Ack(ctx, items[0].ID, "worker-1"))
+	assert.ErrorIs(t, q.Ack(ctx, items[0].ID, "worker-1"), queue.ErrLeaseLost, "acked items are not reserved")
+
+	// a failed item is claimable again once its retry time has passed
+	past := time.Now().Add(-time.Second).Unix()
+	require.NoError(t, q.Fail(ctx, items[1].ID, "worker-1", "boom", &past))
+	again, err = q.Claim(ctx, "worker-2", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, 2, again[0].Attempts)
+	require.NotNil(t, again[0].LastError)
+	assert.Equal(t, "boom", *again[0].LastError)
+	assert.ErrorIs(t, q.Fail(ctx, items[1].ID, "worker-1", "boom", &past), queue.ErrLeaseLost)
+
+	// a failed item is not claimable before its retry time
+	future := time.Now().Add(time.Hour).Unix()
+	require.NoError(t, q.Fail(ctx, again[0].ID, "worker-2", "boom", &future))
+	again, err = q.Claim(ctx, "worker-2", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// without a retry time the item is dead-lettered
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: uuid.NewString(), Type: "test", Payload: []byte(`{"n":3}`)}))
+	again, err = q.Claim(ctx, "worker-2", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.NoError(t, q.Fail(ctx, again[0].ID, "worker-2", "fatal", nil))
+	dead, err := q.Get(ctx, again[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusDead, dead.Status)
+}
+
+func testQueueReleaseExpired(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	q := db.QueueRepository()
+
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: "fresh", Type: "test", Payload: []byte(`{}`)}))
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: "exhausted", Type: "test", Payload: []byte(`{}`)}))
+
+	claimed, err := q.Claim(ctx, "worker-1", 2, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	// claim "exhausted" a second time so it reaches maxAttempts
+	past := time.Now().Add(-time.Second).Unix()
+	require.NoError(t, q.Fail(ctx, "exhausted", "worker-1", "boom", &past))
+	claimed, err = q.Claim(ctx, "worker-1", 2, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 2, claimed[0].Attempts)
+
+	// leases that have not expired yet are kept
+	n, err := q.ReleaseExpired(ctx, time.Now().Add(-time.Minute).Unix(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = q.ReleaseExpired(ctx, time.Now().Add(time.Minute).Unix(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// "fresh" (1 attempt) is pending again, "exhausted" (2 attempts) is dead
+	claimed, err = q.Claim(ctx, "worker-2", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "fresh", claimed[0].ID)
+
+	// the worker whose lease expired cannot ack the item claimed by another worker
+	assert.ErrorIs(t, q.Ack(ctx, "fresh", "worker-1"), queue.ErrLeaseLost)
+	require.NoError(t, q.Ack(ctx, "fresh", "worker-2"))
+}
+
+func testQueueDefer(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	q := db.QueueRepository()
+
+	require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: "limited", Type: "test", Payload: []byte(`{}`)}))
+	claimed, err := q.Claim(ctx, "worker-1", 1, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 1, claimed[0].Attempts)
+
+	future := time.Now().Add(time.Hour).Unix()
+	assert.ErrorIs(t, q.Defer(ctx, "limited", "worker-2", future), queue.ErrLeaseLost)
+	require.NoError(t, q.Defer(ctx, "limited", "worker-1", future))
+	assert.ErrorIs(t, q.Defer(ctx, "limited", "worker-1", future), queue.ErrLeaseLost, "deferred items are not reserved")
+
+	// a deferred item is pending again without consuming an attempt
+	items, _, err := q.List(ctx, queue.Filter{Type: "test"}, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, queue.StatusPending, items[0].Status)
+	assert.Equal(t, future, items[0].AvailableAt)
+	assert.Equal(t, 0, items[0].Attempts)
+	assert.Nil(t, items[0].ReservedBy)
+
+	claimed, err = q.Claim(ctx, "worker-1", 1, queue.ClaimFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "deferred items are not available before their time")
+}
+
+func testQueueClaimFilter(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	q := db.QueueRepository()
+
+	for _, typ := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: "item-" + typ, Type: typ, Payload: []byte(`{}`)}))
+	}
+
+	claimed, err := q.Claim(ctx, "worker-1", 10, queue.ClaimFilter{Types: []string{"b"}})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "item-b", claimed[0].ID)
+
+	claimed, err = q.Claim(ctx, "worker-1", 10, queue.ClaimFilter{ExcludeTypes: []string{"a"}})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "item-c", claimed[0].ID)
+
+	claimed, err = q.Claim(ctx, "worker-1", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "item-a", claimed[0].ID)
+}
+
+func testQueueInspection(t *testing.T, db repository.DB) {
+	ctx := context.Background()
+	q := db.QueueRepository()
+
+	base := time.Now().Add(-time.Hour).Unix()
+	for i, typ := range []string{"delivery", "delivery", "delivery", "other"} {
+		require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Type:      typ,
+			Payload:   []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			CreatedAt: base + int64(i),
+		}))
+	}
+	claimed, err := q.Claim(ctx, "worker-1", 3, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	require.NoError(t, q.Ack(ctx, "item-0", "worker-1"))
+	require.NoError(t, q.Fail(ctx, "item-1", "worker-1", "smtp down", nil))
+	require.NoError(t, q.Fail(ctx, "item-2", "worker-1", "smtp down", nil))
+
+	items, total, err := q.List(ctx, queue.Filter{Type: "delivery", Status: []queue.Status{queue.StatusDead}}, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, items, 1)
+	assert.Equal(t, "item-2", items[0].ID, "newest first")
+
+	_, total, err = q.List(ctx, queue.Filter{}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+
+	item, err := q.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusDead, item.Status)
+	assert.JSONEq(t, `{"n":1}`, string(item.Payload))
+	require.NotNil(t, item.LastError)
+	assert.Equal(t, "smtp down", *item.LastError)
+
+	_, err = q.Get(ctx, "missing")
+	var notFound *repository.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	// only dead items can be replayed
+	assert.ErrorIs(t, q.Replay(ctx, "item-0"), queue.ErrNotReplayable)
+	require.NoError(t, q.Replay(ctx, "item-1"))
+	item, err = q.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, queue.StatusPending, item.Status)
+	assert.Equal(t, 0, item.Attempts)
+
+	n, err := q.ReplayMatching(ctx, queue.Filter{Type: "delivery"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	claimed, err = q.Claim(ctx, "worker-1", 10, queue.ClaimFilter{})
+	require.NoError(t, err)
+	assert.Len(t, claimed, 3)
+
+	require.NoError(t, q.Ack(ctx, "item-1", "worker-1"))
+	n, err = q.Purge(ctx, queue.Filter{Status: []queue.Status{queue.StatusDone}, CreatedBefore: base + 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = q.Get(ctx, "item-0")
+	assert.ErrorAs(t, err, &notFound)
+	_, err = q.Get(ctx, "item-1")
+	assert.NoError(t, err)
+}
+
+func newList(id string, tags ...string) *domain.List {
+	now := time.Now().Unix()
+	if tags == nil {
+		tags = []string{}
+	}
+	return &domain.List{ID: id, Name: id, Tags: tags, CreatedAt: now, UpdatedAt: now}
+}
+
+func newCampaign(id string, status domain.CampaignStatus, tags ...string) *domain.Campaign {
+	now := time.Now().Unix()
+	if tags == nil {
+		tags = []string{}
+	}
+	return &domain.Campaign{ID: id, Name: id, Status: status, Subject: "Subject", Tags: tags, CreatedAt: now, UpdatedAt: now}
+}
+
+func newDelivery(id string, campaignID *string, status domain.DeliveryStatus) *domain.Delivery {
+	deliveryType := domain.DeliveryTypeTransaction
+	if campaignID != nil {
+		deliveryType = domain.DeliveryTypeCampaign
+	}
+	return &domain.Delivery{
+		ID:         id,
+		CampaignID: campaignID,
+		Type:       deliveryType,
+		Status:     status,
+		Name:       "Recipient",
+		Email:      id + "@example.com",
+		Subject:    "Subject",
+		BodyHTML:   "<p>hi</p>",
+		Data:       map[string]interface{}{"key": "value"},
+		Headers:    map[string]string{"X-H": "v"},
+		Tags:       []string{"t"},
+		CreatedAt:  time.Now().Unix(),
+	}
+}
+
+func page(p, limit int) repository.Pagination {
+	return repository.Pagination{Page: p, Limit: limit}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *DB
+}
+
+func NewAPIKeyRepository(db *DB) repository.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func domainToAPIKeyEntity(d *domain.APIKey) (*APIKey, error) {
+	scopes := d.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &APIKey{
+		ID:         d.ID,
+		Name:       d.Name,
+		Prefix:     d.Prefix,
+		KeyHash:    d.Hash,
+		Scopes:     scopesJSON,
+		LastUsedAt: d.LastUsedAt,
+		RevokedAt:  d.RevokedAt,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+	}, nil
+}
+
+func entityToAPIKeyDomain(e *APIKey) (*domain.APIKey, error) {
+	var scopes []string
+	if len(e.Scopes) > 0 {
+		if err := json.Unmarshal(e.Scopes, &scopes); err != nil {
+			return nil, err
+		}
+	}
+	return &domain.APIKey{
+		ID:         e.ID,
+		Name:       e.Name,
+		Prefix:     e.Prefix,
+		Hash:       e.KeyHash,
+		Scopes:     scopes,
+		LastUsedAt: e.LastUsedAt,
+		RevokedAt:  e.RevokedAt,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	entity, err := domainToAPIKeyEntity(key)
+	if err != nil {
+		return err
+	}
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Create(entity).Error
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	return r.get(ctx, "id = ?", id)
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	return r.get(ctx, "key_hash = ?", hash)
+}
+
+func (r *apiKeyRepository) get(ctx context.Context, query string, arg string) (*domain.APIKey, error) {
+	var entity APIKey
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).First(&entity, query, arg).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &repository.ErrNotFound{Entity: "APIKey", ID: arg}
+		}
+		return nil, err
+	}
+	return entityToAPIKeyDomain(&entity)
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *domain.APIKey) error {
+	entity, err := domainToAPIKeyEntity(key)
+	if err != nil {
+		return err
+	}
+	db := extractTx(ctx, r.db.DB)
+	res := db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"name":       entity.Name,
+		"prefix":     entity.Prefix,
+		"key_hash":   entity.KeyHash,
+		"scopes":     entity.Scopes,
+		"revoked_at": entity.RevokedAt,
+		"updated_at": entity.UpdatedAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &repository.ErrNotFound{Entity: "APIKey", ID: key.ID}
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, pagination repository.Pagination) ([]*domain.APIKey, int, error) {
+	var entities []APIKey
+	var total int64
+
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&APIKey{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pagination.Limit).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	keys := make([]*domain.APIKey, 0, len(entities))
+	for i := range entities {
+		key, err := entityToAPIKeyDomain(&entities[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, int(total), nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id string, at int64) error {
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+type auditRepository struct {
+	db *DB
+}
+
+func NewAuditRepository(db *DB) repository.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func domainToAuditEntity(d *domain.AuditEntry) (*AuditEntry, error) {
+	var changesJSON []byte
+	if len(d.Changes) > 0 {
+		var err error
+		changesJSON, err = json.Marshal(d.Changes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &AuditEntry{
+		ID:         d.ID,
+		Principal:  d.Principal,
+		Action:     d.Action,
+		EntityType: d.EntityType,
+		EntityID:   d.EntityID,
+		Method:     d.Method,
+		Path:       d.Path,
+		Changes:    changesJSON,
+		CreatedAt:  d.CreatedAt,
+	}, nil
+}
+
+func entityToAuditDomain(e *AuditEntry) (*domain.AuditEntry, error) {
+	var changes map[string]domain.AuditChange
+	if len(e.Changes) > 0 {
+		if err := json.Unmarshal(e.Changes, &changes); err != nil {
+			return nil, err
+		}
+	}
+	return &domain.AuditEntry{
+		ID:         e.ID,
+		Principal:  e.Principal,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Method:     e.Method,
+		Path:       e.Path,
+		Changes:    changes,
+		CreatedAt:  e.CreatedAt,
+	}, nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	entity, err := domainToAuditEntity(entry)
+	if err != nil {
+		return err
+	}
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Create(entity).Error
+}
+
+func (r *auditRepository) List(ctx context.Context, filter repository.AuditFilter, pagination repository.Pagination) ([]*domain.AuditEntry, int, error) {
+	var entities []AuditEntry
+	var total int64
+
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&AuditEntry{})
+	if filter.Principal != "" {
+		query = query.Where("principal = ?", filter.Principal)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Since > 0 {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Until > 0 {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	if err := query.Order("created_at DESC").Order("id").Offset(offset).Limit(pagination.Limit).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]*domain.AuditEntry, 0, len(entities))
+	for i := range entities {
+		entry, err := entityToAuditDomain(&entities[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, int(total), nil
+}
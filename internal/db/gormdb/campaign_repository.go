@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -63,6 +63,7 @@ func domainToCampaignEntity(d *domain.Campaign) (*Campaign, error) {
 		OpenCount:      d.OpenCount,
 		ClickCount:     d.ClickCount,
 		BounceCount:    d.BounceCount,
+		ComplaintCount: d.ComplaintCount,
 	}, nil
 }
 
@@ -108,6 +109,7 @@ func entityToCampaignDomain(e *Campaign) (*domain.Campaign, error) {
 		OpenCount:      e.OpenCount,
 		ClickCount:     e.ClickCount,
 		BounceCount:    e.BounceCount,
+		ComplaintCount: e.ComplaintCount,
 	}, nil
 }
 
@@ -144,7 +146,7 @@ func (r *campaignRepository) Update(ctx context.Context, campaign *domain.Campai
 		"from_email":    entity.FromEmail,
 		"subject":       entity.Subject,
 		"template_id":   entity.TemplateID,
-		"template_html": entity.TemplateMJML,
+		"template_mjml": entity.TemplateMJML,
 		"template_text": entity.TemplateText,
 		"data":          entity.Data,
 		"tags":          entity.Tags,
@@ -183,10 +185,9 @@ func (r *campaignRepository) List(ctx context.Context, filter repository.Campaig
 	}
 	if len(filter.Tags) > 0 {
 		for _, tag := range filter.Tags {
-			query = query.Where("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", tag)
+			query = query.Where(r.db.dialect().jsonArrayContains("tags", tag))
 		}
 	}
-	// TODO: json_each(frm.tags) where json_each.value where (?)
 
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -216,7 +217,7 @@ func (r *campaignRepository) UpdateStatus(ctx context.Context, id string, status
 
 // IncrementStats atomically increments per-campaign counters.
 // Provide deltas for fields you want to change; pass 0 for no-op.
-func (r *campaignRepository) IncrementStats(ctx context.Context, id string, recipientDelta int, deliveredDelta int, failedDelta int, openDelta int, clickDelta int, bounceDelta int) error {
+func (r *campaignRepository) IncrementStats(ctx context.Context, id string, recipientDelta int, deliveredDelta int, failedDelta int, openDelta int, clickDelta int, bounceDelta int, complaintDelta int) error {
 	db := extractTx(ctx, r.db.DB)
 
 	sets := make([]string, 0)
@@ -246,6 +247,10 @@ func (r *campaignRepository) IncrementStats(ctx context.Context, id string, reci
 		sets = append(sets, "bounce_count = bounce_count + ?")
 		args = append(args, bounceDelta)
 	}
+	if complaintDelta != 0 {
+		sets = append(sets, "complaint_count = complaint_count + ?")
+		args = append(args, complaintDelta)
+	}
 
 	if len(sets) == 0 {
 		return nil
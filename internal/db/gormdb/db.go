@@ -0,0 +1,132 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package gormdb implements repository.DB on top of GORM. It is shared by the
+// SQL storage providers (sqlite, postgres, mysql), which only open the
+// connection and register themselves; dialect specific SQL is isolated in
+// dialect.go.
+package gormdb
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/headmail/headmail/internal/db/migrate"
+	pkgdb "github.com/headmail/headmail/pkg/db"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// DB holds the gorm database connection.
+type DB struct {
+	*gorm.DB
+	migrations []migrate.Migration
+}
+
+var (
+	_ repository.DB  = (*DB)(nil)
+	_ pkgdb.Migrator = (*DB)(nil)
+)
+
+// Open opens a GORM connection using the given dialector. migrations holds the
+// provider's versioned migration scripts (see package migrate); the schema is
+// not changed until MigrateUp is called.
+func Open(dialector gorm.Dialector, migrations fs.FS) (*DB, error) {
+	loaded, err := migrate.Load(migrations)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DB{DB: db, migrations: loaded}, nil
+}
+
+func (db *DB) MigrateUp(ctx context.Context, steps int) (int, error) {
+	return migrate.New(db.DB, db.migrations).MigrateUp(ctx, steps)
+}
+
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	return migrate.New(db.DB, db.migrations).MigrateDown(ctx, steps)
+}
+
+func (db *DB) MigrationStatus(ctx context.Context) ([]pkgdb.MigrationStatus, error) {
+	return migrate.New(db.DB, db.migrations).MigrationStatus(ctx)
+}
+
+func (db *DB) ListRepository() repository.ListRepository {
+	return NewListRepository(db)
+}
+
+func (db *DB) SubscriberRepository() repository.SubscriberRepository {
+	return NewSubscriberRepository(db)
+}
+
+func (db *DB) CampaignRepository() repository.CampaignRepository {
+	return NewCampaignRepository(db)
+}
+
+func (db *DB) DeliveryRepository() repository.DeliveryRepository {
+	return NewDeliveryRepository(db)
+}
+
+func (db *DB) TemplateRepository() repository.TemplateRepository {
+	return NewTemplateRepository(db)
+}
+
+func (db *DB) QueueRepository() queue.Queue {
+	return NewQueueRepository(db)
+}
+
+func (db *DB) EventRepository() repository.EventRepository {
+	return NewEventRepository(db)
+}
+
+func (db *DB) SuppressionRepository() repository.SuppressionRepository {
+	return NewSuppressionRepository(db)
+}
+
+func (db *DB) APIKeyRepository() repository.APIKeyRepository {
+	return NewAPIKeyRepository(db)
+}
+
+func (db *DB) AuditRepository() repository.AuditRepository {
+	return NewAuditRepository(db)
+}
+
+func (db *DB) LinkRepository() repository.LinkRepository {
+	return NewLinkRepository(db)
+}
+
+func (db *DB) Begin(ctx context.Context) (context.Context, error) {
+	tx := db.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return injectTx(ctx, tx), nil
+}
+
+func (db *DB) Commit(ctx context.Context) error {
+	tx, err := getExistingTx(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (db *DB) Rollback(ctx context.Context) error {
+	tx, err := getExistingTx(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+	return tx.Rollback().Error
+}
+
+// dialect returns the SQL dialect helpers for the underlying connection.
+func (db *DB) dialect() dialect {
+	return dialectFor(db.Dialector.Name())
+}
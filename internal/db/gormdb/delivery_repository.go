@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -36,29 +36,33 @@ func domainToDeliveryEntity(d *domain.Delivery) (*Delivery, error) {
 	}
 
 	return &Delivery{
-		ID:            d.ID,
-		CampaignID:    d.CampaignID,
-		Type:          d.Type,
-		Status:        d.Status,
-		Name:          d.Name,
-		Email:         d.Email,
-		Subject:       d.Subject,
-		BodyHTML:      d.BodyHTML,
-		BodyText:      d.BodyText,
-		MessageID:     d.MessageID,
-		Data:          dataJSON,
-		Headers:       headersJSON,
-		Tags:          tagsJSON,
-		CreatedAt:     d.CreatedAt,
-		ScheduledAt:   d.ScheduledAt,
-		Attempts:      d.Attempts,
-		SentAt:        d.SentAt,
-		OpenedAt:      d.OpenedAt,
-		FailedAt:      d.FailedAt,
-		FailureReason: d.FailureReason,
-		OpenCount:     d.OpenCount,
-		ClickCount:    d.ClickCount,
-		BounceCount:   d.BounceCount,
+		ID:             d.ID,
+		CampaignID:     d.CampaignID,
+		ListID:         d.ListID,
+		Type:           d.Type,
+		Status:         d.Status,
+		Name:           d.Name,
+		Email:          d.Email,
+		FromName:       d.FromName,
+		FromEmail:      d.FromEmail,
+		Subject:        d.Subject,
+		BodyHTML:       d.BodyHTML,
+		BodyText:       d.BodyText,
+		MessageID:      d.MessageID,
+		Data:           dataJSON,
+		Headers:        headersJSON,
+		Tags:           tagsJSON,
+		CreatedAt:      d.CreatedAt,
+		ScheduledAt:    d.ScheduledAt,
+		Attempts:       d.Attempts,
+		SentAt:         d.SentAt,
+		OpenedAt:       d.OpenedAt,
+		FailedAt:       d.FailedAt,
+		FailureReason:  d.FailureReason,
+		OpenCount:      d.OpenCount,
+		ClickCount:     d.ClickCount,
+		BounceCount:    d.BounceCount,
+		ComplaintCount: d.ComplaintCount,
 	}, nil
 }
 
@@ -79,29 +83,33 @@ func entityToDeliveryDomain(e *Delivery) (*domain.Delivery, error) {
 	}
 
 	return &domain.Delivery{
-		ID:            e.ID,
-		CampaignID:    e.CampaignID,
-		Type:          e.Type,
-		Status:        e.Status,
-		Name:          e.Name,
-		Email:         e.Email,
-		Subject:       e.Subject,
-		BodyHTML:      e.BodyHTML,
-		BodyText:      e.BodyText,
-		MessageID:     e.MessageID,
-		Data:          data,
-		Headers:       headers,
-		Tags:          tags,
-		CreatedAt:     e.CreatedAt,
-		ScheduledAt:   e.ScheduledAt,
-		Attempts:      e.Attempts,
-		SentAt:        e.SentAt,
-		OpenedAt:      e.OpenedAt,
-		FailedAt:      e.FailedAt,
-		FailureReason: e.FailureReason,
-		OpenCount:     e.OpenCount,
-		ClickCount:    e.ClickCount,
-		BounceCount:   e.BounceCount,
+		ID:             e.ID,
+		CampaignID:     e.CampaignID,
+		ListID:         e.ListID,
+		Type:           e.Type,
+		Status:         e.Status,
+		Name:           e.Name,
+		Email:          e.Email,
+		FromName:       e.FromName,
+		FromEmail:      e.FromEmail,
+		Subject:        e.Subject,
+		BodyHTML:       e.BodyHTML,
+		BodyText:       e.BodyText,
+		MessageID:      e.MessageID,
+		Data:           data,
+		Headers:        headers,
+		Tags:           tags,
+		CreatedAt:      e.CreatedAt,
+		ScheduledAt:    e.ScheduledAt,
+		Attempts:       e.Attempts,
+		SentAt:         e.SentAt,
+		OpenedAt:       e.OpenedAt,
+		FailedAt:       e.FailedAt,
+		FailureReason:  e.FailureReason,
+		OpenCount:      e.OpenCount,
+		ClickCount:     e.ClickCount,
+		BounceCount:    e.BounceCount,
+		ComplaintCount: e.ComplaintCount,
 	}, nil
 }
 
@@ -261,6 +269,12 @@ func (r *deliveryRepository) IncrementCount(ctx context.Context, id string, even
 			return false, err
 		}
 		return isFirst, nil
+	case domain.EventTypeComplained:
+		isFirst := entity.ComplaintCount == 0
+		if err := db.WithContext(ctx).Model(&Delivery{}).Where("id = ?", id).Update("complaint_count", gorm.Expr("complaint_count + 1")).Error; err != nil {
+			return false, err
+		}
+		return isFirst, nil
 	default:
 		return false, nil
 	}
@@ -0,0 +1,87 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dialect isolates the few SQL fragments that differ between databases.
+type dialect interface {
+	// jsonArrayContains returns a condition that matches rows whose JSON array
+	// column contains the given string value.
+	jsonArrayContains(column string, value string) clause.Expression
+	// timeBucket returns an expression that truncates a unix timestamp column to
+	// a bucket. The expression takes the bucket size (in seconds) twice as arguments.
+	timeBucket(column string) string
+}
+
+func dialectFor(name string) dialect {
+	switch name {
+	case "postgres":
+		return postgresDialect{}
+	case "mysql":
+		return mysqlDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+// integerDivision implements timeBucket for databases whose "/" truncates integers.
+type integerDivision struct{}
+
+func (integerDivision) timeBucket(column string) string {
+	return "((" + column + " / ?) * ?)"
+}
+
+type sqliteDialect struct {
+	integerDivision
+}
+
+func (sqliteDialect) jsonArrayContains(column string, value string) clause.Expression {
+	return clause.Expr{
+		SQL:  "EXISTS (SELECT 1 FROM json_each(" + column + ") WHERE value = ?)",
+		Vars: []interface{}{value},
+	}
+}
+
+type postgresDialect struct {
+	integerDivision
+}
+
+func (postgresDialect) jsonArrayContains(column string, value string) clause.Expression {
+	needle, _ := json.Marshal([]string{value})
+	return clause.Expr{
+		SQL:  column + "::jsonb @> ?::jsonb",
+		Vars: []interface{}{string(needle)},
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) jsonArrayContains(column string, value string) clause.Expression {
+	needle, _ := json.Marshal(value)
+	return clause.Expr{
+		SQL:  "JSON_CONTAINS(" + column + ", ?)",
+		Vars: []interface{}{string(needle)},
+	}
+}
+
+// timeBucket uses DIV because "/" is decimal division in MySQL.
+func (mysqlDialect) timeBucket(column string) string {
+	return "((" + column + " DIV ?) * ?)"
+}
+
+// isUniqueViolation reports whether err was caused by a unique constraint.
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
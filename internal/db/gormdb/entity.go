@@ -0,0 +1,190 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/queue"
+)
+
+// List is the GORM model for a mailing list.
+type List struct {
+	ID          string `gorm:"column:id;primaryKey"`
+	Name        string `gorm:"column:name"`
+	Description string `gorm:"column:description"`
+	Tags        JSON   `gorm:"column:tags"`
+	CreatedAt   int64  `gorm:"column:created_at"`
+	UpdatedAt   int64  `gorm:"column:updated_at"`
+	DeletedAt   *int64 `gorm:"column:deleted_at;index"`
+
+	ConfirmationTemplateID *string `gorm:"column:confirmation_template_id"`
+}
+
+// Subscriber is the GORM model for a subscriber.
+type Subscriber struct {
+	ID        string                  `gorm:"column:id;primaryKey"`
+	Email     string                  `gorm:"column:email;size:255;uniqueIndex"`
+	Name      string                  `gorm:"column:name"`
+	Status    domain.SubscriberStatus `gorm:"column:status"`
+	CreatedAt int64                   `gorm:"column:created_at"`
+	UpdatedAt int64                   `gorm:"column:updated_at"`
+	DeletedAt *int64                  `gorm:"column:deleted_at"`
+	Lists     []SubscriberList        `gorm:"foreignKey:SubscriberID"`
+}
+
+// SubscriberList is the GORM model for the join table between subscribers and lists.
+type SubscriberList struct {
+	SubscriberID   string                      `gorm:"column:subscriber_id;primaryKey"`
+	ListID         string                      `gorm:"column:list_id;primaryKey"`
+	Status         domain.SubscriberListStatus `gorm:"column:status"`
+	SubscribedAt   *int64                      `gorm:"column:subscribed_at"`
+	UnsubscribedAt *int64                      `gorm:"column:unsubscribed_at"`
+	CreatedAt      int64                       `gorm:"column:created_at"`
+	UpdatedAt      int64                       `gorm:"column:updated_at"`
+}
+
+// Campaign is the GORM model for a campaign.
+type Campaign struct {
+	ID             string                `gorm:"column:id;primaryKey"`
+	Name           string                `gorm:"column:name"`
+	TemplateID     *string               `gorm:"column:template_id"`
+	Status         domain.CampaignStatus `gorm:"column:status"`
+	FromName       string                `gorm:"column:from_name"`
+	FromEmail      string                `gorm:"column:from_email"`
+	Subject        string                `gorm:"column:subject"`
+	TemplateMJML   string                `gorm:"column:template_mjml"`
+	TemplateText   string                `gorm:"column:template_text"`
+	Data           JSON                  `gorm:"column:data"`
+	Tags           JSON                  `gorm:"column:tags"`
+	Headers        JSON                  `gorm:"column:headers"`
+	UTMParams      JSON                  `gorm:"column:utm_params"`
+	ScheduledAt    *int64                `gorm:"column:scheduled_at"`
+	SentAt         *int64                `gorm:"column:sent_at"`
+	CreatedAt      int64                 `gorm:"column:created_at;index:,sort:desc"`
+	UpdatedAt      int64                 `gorm:"column:updated_at"`
+	DeletedAt      *int64                `gorm:"column:deleted_at;index"`
+	RecipientCount int                   `gorm:"column:recipient_count"`
+	DeliveredCount int                   `gorm:"column:delivered_count"`
+	FailedCount    int                   `gorm:"column:failed_count"`
+	OpenCount      int                   `gorm:"column:open_count"`
+	ClickCount     int                   `gorm:"column:click_count"`
+	BounceCount    int                   `gorm:"column:bounce_count"`
+	ComplaintCount int                   `gorm:"column:complaint_count"`
+}
+
+// Delivery is the GORM model for a delivery.
+type Delivery struct {
+	ID             string                `gorm:"column:id;primaryKey"`
+	CampaignID     *string               `gorm:"column:campaign_id"`
+	ListID         *string               `gorm:"column:list_id"`
+	Type           domain.DeliveryType   `gorm:"column:type"`
+	Status         domain.DeliveryStatus `gorm:"column:status"`
+	Name           string                `gorm:"column:name"`
+	Email          string                `gorm:"column:email"`
+	FromName       string                `gorm:"column:from_name"`
+	FromEmail      string                `gorm:"column:from_email"`
+	Subject        string                `gorm:"column:subject"`
+	BodyHTML       string                `gorm:"column:body_html"`
+	BodyText       string                `gorm:"column:body_text"`
+	MessageID      *string               `gorm:"column:message_id"`
+	Data           JSON                  `gorm:"column:data"`
+	Headers        JSON                  `gorm:"column:headers"`
+	Tags           JSON                  `gorm:"column:tags"`
+	CreatedAt      int64                 `gorm:"column:created_at;index:,sort:desc"`
+	ScheduledAt    *int64                `gorm:"column:scheduled_at"`
+	Attempts       int                   `gorm:"column:attempts"`
+	SentAt         *int64                `gorm:"column:sent_at"`
+	OpenedAt       *int64                `gorm:"column:opened_at"`
+	FailedAt       *int64                `gorm:"column:failed_at"`
+	FailureReason  *string               `gorm:"column:failure_reason"`
+	OpenCount      int                   `gorm:"column:open_count"`
+	ClickCount     int                   `gorm:"column:click_count"`
+	BounceCount    int                   `gorm:"column:bounce_count"`
+	ComplaintCount int                   `gorm:"column:complaint_count"`
+}
+
+// DeliveryEvent is the GORM model for a delivery event.
+type DeliveryEvent struct {
+	ID         string           `gorm:"column:id;primaryKey"`
+	DeliveryID string           `gorm:"column:delivery_id"`
+	EventType  domain.EventType `gorm:"column:event_type"`
+	EventData  JSON             `gorm:"column:event_data"`
+	UserAgent  *string          `gorm:"column:user_agent"`
+	IPAddress  *string          `gorm:"column:ip_address"`
+	URL        *string          `gorm:"column:url"`
+	CreatedAt  int64            `gorm:"column:created_at"`
+}
+
+// Template is the GORM model for a template.
+type Template struct {
+	ID        string `gorm:"column:id;primaryKey"`
+	CreatedAt int64  `gorm:"column:created_at"`
+	UpdatedAt int64  `gorm:"column:updated_at"`
+	DeletedAt *int64 `gorm:"column:deleted_at"`
+	Name      string `gorm:"column:name"`
+	Subject   string `gorm:"column:subject"`
+	BodyMJML  string `gorm:"column:body_mjml"`
+}
+
+// QueueItem is the GORM model for a generic queue entry.
+type QueueItem struct {
+	ID          string       `gorm:"column:id;primaryKey"`
+	Type        string       `gorm:"column:type"`
+	Payload     JSON         `gorm:"column:payload"`
+	UniqueKey   *string      `gorm:"column:unique_key;size:255;uniqueIndex"`
+	Status      queue.Status `gorm:"column:status"`
+	ReservedBy  *string      `gorm:"column:reserved_by"`
+	ReservedAt  *int64       `gorm:"column:reserved_at"`
+	Attempts    int          `gorm:"column:attempts"`
+	AvailableAt int64        `gorm:"column:available_at"`
+	LastError   *string      `gorm:"column:last_error"`
+	CreatedAt   int64        `gorm:"column:created_at"`
+}
+
+// Suppression is the GORM model for a suppression list entry.
+type Suppression struct {
+	ID        string                   `gorm:"column:id;primaryKey"`
+	Type      domain.SuppressionType   `gorm:"column:type"`
+	Value     string                   `gorm:"column:value"`
+	Reason    domain.SuppressionReason `gorm:"column:reason"`
+	Source    string                   `gorm:"column:source"`
+	ExpiresAt *int64                   `gorm:"column:expires_at"`
+	CreatedAt int64                    `gorm:"column:created_at"`
+	UpdatedAt int64                    `gorm:"column:updated_at"`
+}
+
+// APIKey is the GORM model for a stored admin API key.
+type APIKey struct {
+	ID         string `gorm:"column:id;primaryKey"`
+	Name       string `gorm:"column:name"`
+	Prefix     string `gorm:"column:prefix"`
+	KeyHash    string `gorm:"column:key_hash"`
+	Scopes     JSON   `gorm:"column:scopes"`
+	LastUsedAt *int64 `gorm:"column:last_used_at"`
+	RevokedAt  *int64 `gorm:"column:revoked_at"`
+	CreatedAt  int64  `gorm:"column:created_at"`
+	UpdatedAt  int64  `gorm:"column:updated_at"`
+}
+
+// AuditEntry is the GORM model for an audit log entry.
+type AuditEntry struct {
+	ID         string `gorm:"column:id;primaryKey"`
+	Principal  string `gorm:"column:principal"`
+	Action     string `gorm:"column:action"`
+	EntityType string `gorm:"column:entity_type"`
+	EntityID   string `gorm:"column:entity_id"`
+	Method     string `gorm:"column:method"`
+	Path       string `gorm:"column:path"`
+	Changes    JSON   `gorm:"column:changes"`
+	CreatedAt  int64  `gorm:"column:created_at"`
+}
+
+// Link is the GORM model for a tracked link.
+type Link struct {
+	ID         string `gorm:"column:id;primaryKey"`
+	CampaignID string `gorm:"column:campaign_id"`
+	URL        string `gorm:"column:url"`
+	ClickCount int    `gorm:"column:click_count"`
+	CreatedAt  int64  `gorm:"column:created_at"`
+}
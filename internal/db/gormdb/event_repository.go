@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -111,7 +111,7 @@ func (r *eventRepository) CountByCampaignAndRange(ctx context.Context, campaignI
 
 	// Raw SQL: join delivery_events -> deliveries to get campaign_id, compute bucket = (created_at / bucketSeconds) * bucketSeconds
 	rows, err := db.WithContext(ctx).Raw(
-		`SELECT deliveries.campaign_id, ((delivery_events.created_at / ?) * ?) as bucket, COUNT(*) as cnt
+		`SELECT deliveries.campaign_id, `+r.db.dialect().timeBucket("delivery_events.created_at")+` as bucket, COUNT(*) as cnt
 		 FROM delivery_events
 		 JOIN deliveries ON deliveries.id = delivery_events.delivery_id
 		 WHERE deliveries.campaign_id IN ? AND delivery_events.created_at BETWEEN ? AND ?
@@ -148,7 +148,7 @@ func (r *eventRepository) CountByCampaignAndRangeByType(ctx context.Context, cam
 	}
 
 	rows, err := db.WithContext(ctx).Raw(
-		`SELECT deliveries.campaign_id, ((delivery_events.created_at / ?) * ?) as bucket, COUNT(*) as cnt
+		`SELECT deliveries.campaign_id, `+r.db.dialect().timeBucket("delivery_events.created_at")+` as bucket, COUNT(*) as cnt
 		 FROM delivery_events
 		 JOIN deliveries ON deliveries.id = delivery_events.delivery_id
 		 WHERE deliveries.campaign_id IN ? AND delivery_events.event_type = ? AND delivery_events.created_at BETWEEN ? AND ?
@@ -173,3 +173,17 @@ func (r *eventRepository) CountByCampaignAndRangeByType(ctx context.Context, cam
 	}
 	return result, nil
 }
+
+func (r *eventRepository) CountDeliveriesByRecipient(ctx context.Context, email string, eventType domain.EventType, since int64) (int, error) {
+	db := extractTx(ctx, r.db.DB)
+	var count int64
+	err := db.WithContext(ctx).Model(&DeliveryEvent{}).
+		Joins("JOIN deliveries ON deliveries.id = delivery_events.delivery_id").
+		Where("deliveries.email = ? AND delivery_events.event_type = ? AND delivery_events.created_at >= ?", email, eventType, since).
+		Distinct("delivery_events.delivery_id").
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"database/sql/driver"
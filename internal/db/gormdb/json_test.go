@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -16,7 +16,7 @@ import (
 
 func TestTransaction(t *testing.T) {
 	gormDB := setupTestDB(t)
-	db := &DB{gormDB}
+	db := &DB{DB: gormDB}
 	repo := NewCampaignRepository(db)
 
 	ctx := context.Background()
@@ -61,7 +61,7 @@ func TestTransaction(t *testing.T) {
 
 func TestCampaignRepository_List_WithTags(t *testing.T) {
 	db := setupTestDB(t)
-	repo := NewCampaignRepository(&DB{db})
+	repo := NewCampaignRepository(&DB{DB: db})
 
 	ctx := context.Background()
 
@@ -0,0 +1,149 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type linkRepository struct {
+	db *DB
+}
+
+func NewLinkRepository(db *DB) repository.LinkRepository {
+	return &linkRepository{db: db}
+}
+
+func domainToLinkEntity(d *domain.Link) *Link {
+	return &Link{
+		ID:         d.ID,
+		CampaignID: d.CampaignID,
+		URL:        d.URL,
+		ClickCount: d.ClickCount,
+		CreatedAt:  d.CreatedAt,
+	}
+}
+
+func entityToLinkDomain(e *Link) *domain.Link {
+	return &domain.Link{
+		ID:         e.ID,
+		CampaignID: e.CampaignID,
+		URL:        e.URL,
+		ClickCount: e.ClickCount,
+		CreatedAt:  e.CreatedAt,
+	}
+}
+
+// errLinkLimit rolls back links created beyond the limit of their campaign.
+var errLinkLimit = errors.New("campaign link limit reached")
+
+func (r *linkRepository) Upsert(ctx context.Context, link *domain.Link) error {
+	entity := domainToLinkEntity(link)
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoNothing: true,
+	}).Create(entity).Error; err != nil {
+		return err
+	}
+	return r.loadStored(ctx, link)
+}
+
+func (r *linkRepository) UpsertLimited(ctx context.Context, link *domain.Link, limit int) (bool, error) {
+	entity := domainToLinkEntity(link)
+	db := extractTx(ctx, r.db.DB)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoNothing: true,
+		}).Create(entity)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		// the conditional update locks the campaign row, so concurrent inserts of
+		// its links are counted one at a time
+		result = tx.Table("campaigns").
+			Where("id = ? AND link_count < ?", link.CampaignID, limit).
+			Update("link_count", gorm.Expr("link_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLinkLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errLinkLimit) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, r.loadStored(ctx, link)
+}
+
+// loadStored sets URL, ClickCount and CreatedAt of link to those of the stored link.
+func (r *linkRepository) loadStored(ctx context.Context, link *domain.Link) error {
+	db := extractTx(ctx, r.db.DB)
+	var stored Link
+	if err := db.WithContext(ctx).First(&stored, "id = ?", link.ID).Error; err != nil {
+		return err
+	}
+	link.URL = stored.URL
+	link.ClickCount = stored.ClickCount
+	link.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (r *linkRepository) GetByID(ctx context.Context, id string) (*domain.Link, error) {
+	var entity Link
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).First(&entity, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &repository.ErrNotFound{Entity: "Link", ID: id}
+		}
+		return nil, err
+	}
+	return entityToLinkDomain(&entity), nil
+}
+
+func (r *linkRepository) ListByCampaign(ctx context.Context, campaignID string, pagination repository.Pagination) ([]*domain.Link, int, error) {
+	var entities []Link
+	var total int64
+
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&Link{}).Where("campaign_id = ?", campaignID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	if err := query.Order("click_count DESC").Order("created_at").Order("id").Offset(offset).Limit(pagination.Limit).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	links := make([]*domain.Link, 0, len(entities))
+	for i := range entities {
+		links = append(links, entityToLinkDomain(&entities[i]))
+	}
+	return links, int(total), nil
+}
+
+func (r *linkRepository) IncrementClicks(ctx context.Context, id string) error {
+	db := extractTx(ctx, r.db.DB)
+	result := db.WithContext(ctx).Model(&Link{}).Where("id = ?", id).Update("click_count", gorm.Expr("click_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &repository.ErrNotFound{Entity: "Link", ID: id}
+	}
+	return nil
+}
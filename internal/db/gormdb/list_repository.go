@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -38,6 +38,8 @@ func domainToListEntity(d *domain.List) (*List, error) {
 		CreatedAt:   d.CreatedAt,
 		UpdatedAt:   d.UpdatedAt,
 		DeletedAt:   d.DeletedAt,
+
+		ConfirmationTemplateID: d.ConfirmationTemplateID,
 	}, nil
 }
 
@@ -50,6 +52,8 @@ func entityToListDomain(e *List) (*domain.List, error) {
 		CreatedAt:   e.CreatedAt,
 		UpdatedAt:   e.UpdatedAt,
 		DeletedAt:   e.DeletedAt,
+
+		ConfirmationTemplateID: e.ConfirmationTemplateID,
 	}
 	if err := json.Unmarshal(e.Tags, &d.Tags); err != nil {
 		return nil, err
@@ -71,6 +75,9 @@ func (r *listRepository) GetByID(ctx context.Context, id string) (*domain.List,
 	var entity List
 	db := extractTx(ctx, r.db.DB)
 	if err := db.WithContext(ctx).First(&entity, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &repository.ErrNotFound{Entity: "List", ID: id}
+		}
 		return nil, err
 	}
 	return entityToListDomain(&entity)
@@ -108,7 +115,7 @@ func (r *listRepository) List(ctx context.Context, filter repository.ListFilter,
 
 	if len(filter.Tags) > 0 {
 		for _, tag := range filter.Tags {
-			query = query.Where("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)", tag)
+			query = query.Where(r.db.dialect().jsonArrayContains("tags", tag))
 		}
 	}
 
@@ -136,7 +143,9 @@ func (r *listRepository) List(ctx context.Context, filter repository.ListFilter,
 func (r *listRepository) GetSubscriberCount(ctx context.Context, listID string) (int, error) {
 	var count int64
 	db := extractTx(ctx, r.db.DB)
-	if err := db.WithContext(ctx).Model(&SubscriberList{}).Where("list_id = ?", listID).Count(&count).Error; err != nil {
+	if err := db.WithContext(ctx).Model(&SubscriberList{}).
+		Where("list_id = ? AND status = ?", listID, domain.SubscriberListStatusConfirmed).
+		Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return int(count), nil
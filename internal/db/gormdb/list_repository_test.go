@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -27,7 +27,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 
 func TestListRepository_Create(t *testing.T) {
 	db := setupTestDB(t)
-	repo := NewListRepository(&DB{db})
+	repo := NewListRepository(&DB{DB: db})
 
 	ctx := context.Background()
 	now := time.Now().Unix()
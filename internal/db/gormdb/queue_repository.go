@@ -0,0 +1,338 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// queueRepository implements queue.Queue using GORM.
+type queueRepository struct {
+	db *DB
+}
+
+func NewQueueRepository(db *DB) queue.Queue {
+	return &queueRepository{
+		db: db,
+	}
+}
+
+func entityFromDomain(item *queue.QueueItem) *QueueItem {
+	return &QueueItem{
+		ID:          item.ID,
+		Type:        item.Type,
+		Payload:     JSON(item.Payload),
+		UniqueKey:   item.UniqueKey,
+		Status:      item.Status,
+		ReservedBy:  item.ReservedBy,
+		ReservedAt:  item.ReservedAt,
+		Attempts:    item.Attempts,
+		AvailableAt: item.AvailableAt,
+		LastError:   item.LastError,
+		CreatedAt:   item.CreatedAt,
+	}
+}
+
+func domainFromEntity(e *QueueItem) *queue.QueueItem {
+	return &queue.QueueItem{
+		ID:          e.ID,
+		Type:        e.Type,
+		Payload:     json.RawMessage(e.Payload),
+		UniqueKey:   e.UniqueKey,
+		Status:      e.Status,
+		ReservedBy:  e.ReservedBy,
+		ReservedAt:  e.ReservedAt,
+		Attempts:    e.Attempts,
+		AvailableAt: e.AvailableAt,
+		LastError:   e.LastError,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// Enqueue inserts a new item into the queue. If UniqueKey is set and an item
+// with the same key already exists, the call is a no-op.
+func (r *queueRepository) Enqueue(ctx context.Context, item *queue.QueueItem) error {
+	db := extractTx(ctx, r.db.DB)
+
+	// If unique key is provided, check for existence first to avoid unique constraint error.
+	if item.UniqueKey != nil {
+		var existing QueueItem
+		err := db.WithContext(ctx).Where("unique_key = ?", *item.UniqueKey).First(&existing).Error
+		if err == nil {
+			// already exists -> ignore
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	entity := entityFromDomain(item)
+	if entity.Status == "" {
+		entity.Status = queue.StatusPending
+	}
+	if entity.CreatedAt == 0 {
+		entity.CreatedAt = time.Now().Unix()
+	}
+	if entity.AvailableAt == 0 {
+		entity.AvailableAt = entity.CreatedAt
+	}
+	return db.WithContext(ctx).Create(entity).Error
+}
+
+// Claim atomically reserves up to `limit` pending items that are ready and returns them.
+func (r *queueRepository) Claim(ctx context.Context, workerID string, limit int, filter queue.ClaimFilter) ([]*queue.QueueItem, error) {
+	return repository.Transactional1[[]*queue.QueueItem](r.db, ctx, func(txCtx context.Context) ([]*queue.QueueItem, error) {
+		tx := extractTx(txCtx, r.db.DB)
+
+		now := time.Now().Unix()
+		var ids []string
+		// select candidate ids; rows locked by concurrent claimers are skipped
+		// on databases that support it.
+		query := tx.WithContext(ctx).
+			Model(&QueueItem{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND available_at <= ?", queue.StatusPending, now)
+		if len(filter.Types) > 0 {
+			query = query.Where("type IN ?", filter.Types)
+		}
+		if len(filter.ExcludeTypes) > 0 {
+			query = query.Where("type NOT IN ?", filter.ExcludeTypes)
+		}
+		if err := query.
+			Order("available_at ASC, created_at ASC").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+
+		if len(ids) == 0 {
+			return []*queue.QueueItem{}, nil
+		}
+
+		// attempt to update selected rows to reserved atomically
+		if err := tx.WithContext(ctx).
+			Model(&QueueItem{}).
+			Where("id IN ?", ids).
+			Where("status = ?", queue.StatusPending).
+			Updates(map[string]interface{}{
+				"status":      queue.StatusReserved,
+				"reserved_by": workerID,
+				"reserved_at": now,
+				"attempts":    gorm.Expr("attempts + 1"),
+			}).Error; err != nil {
+			return nil, err
+		}
+
+		// fetch the reserved items to return
+		var entities []QueueItem
+		if err := tx.WithContext(ctx).Where("id IN ?", ids).Find(&entities).Error; err != nil {
+			return nil, err
+		}
+
+		var items []*queue.QueueItem
+		for _, e := range entities {
+			items = append(items, domainFromEntity(&e))
+		}
+		return items, nil
+	})
+}
+
+// Ack deletes the queue item (marks done).
+func (r *queueRepository) Ack(ctx context.Context, id string, workerID string) error {
+	tx := extractTx(ctx, r.db.DB)
+	result := tx.WithContext(ctx).
+		Model(&QueueItem{}).
+		Where("id = ? AND status = ? AND reserved_by = ?", id, queue.StatusReserved, workerID).
+		Update("status", queue.StatusDone)
+	return leaseResult(result)
+}
+
+// Fail records the error and either reschedules the item or moves it to the dead-letter state.
+func (r *queueRepository) Fail(ctx context.Context, id string, workerID string, reason string, retryAt *int64) error {
+	tx := extractTx(ctx, r.db.DB)
+	updates := map[string]interface{}{
+		"last_error":  reason,
+		"reserved_by": nil,
+		"reserved_at": nil,
+	}
+	if retryAt != nil {
+		updates["status"] = queue.StatusPending
+		updates["available_at"] = *retryAt
+	} else {
+		updates["status"] = queue.StatusDead
+	}
+	result := tx.WithContext(ctx).
+		Model(&QueueItem{}).
+		Where("id = ? AND status = ? AND reserved_by = ?", id, queue.StatusReserved, workerID).
+		Updates(updates)
+	return leaseResult(result)
+}
+
+// leaseResult returns ErrLeaseLost when an update guarded by the reservation matched no row.
+func leaseResult(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return queue.ErrLeaseLost
+	}
+	return nil
+}
+
+// Defer returns the item to pending and gives back the attempt its claim counted.
+func (r *queueRepository) Defer(ctx context.Context, id string, workerID string, availableAt int64) error {
+	tx := extractTx(ctx, r.db.DB)
+	result := tx.WithContext(ctx).
+		Model(&QueueItem{}).
+		Where("id = ? AND status = ? AND reserved_by = ?", id, queue.StatusReserved, workerID).
+		Updates(map[string]interface{}{
+			"status":       queue.StatusPending,
+			"available_at": availableAt,
+			"attempts":     gorm.Expr("attempts - 1"),
+			"reserved_by":  nil,
+			"reserved_at":  nil,
+		})
+	return leaseResult(result)
+}
+
+// ReleaseExpired returns items with an expired reservation to pending, or dead-letters
+// them when they already used up their attempts.
+func (r *queueRepository) ReleaseExpired(ctx context.Context, reservedBefore int64, maxAttempts int) (int, error) {
+	return repository.Transactional1[int](r.db, ctx, func(txCtx context.Context) (int, error) {
+		tx := extractTx(txCtx, r.db.DB)
+		expired := tx.WithContext(ctx).
+			Model(&QueueItem{}).
+			Where("status = ? AND reserved_at < ?", queue.StatusReserved, reservedBefore).
+			Session(&gorm.Session{})
+
+		var total int64
+		if maxAttempts > 0 {
+			res := expired.
+				Where("attempts >= ?", maxAttempts).
+				Updates(map[string]interface{}{
+					"status":     queue.StatusDead,
+					"last_error": "lease expired",
+				})
+			if res.Error != nil {
+				return 0, res.Error
+			}
+			total += res.RowsAffected
+		}
+
+		res := expired.Updates(map[string]interface{}{
+			"status":      queue.StatusPending,
+			"reserved_by": nil,
+			"reserved_at": nil,
+			"last_error":  "lease expired",
+		})
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		total += res.RowsAffected
+		return int(total), nil
+	})
+}
+
+func applyQueueFilter(query *gorm.DB, filter queue.Filter) *gorm.DB {
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if len(filter.Status) > 0 {
+		query = query.Where("status IN ?", filter.Status)
+	}
+	if filter.CreatedBefore > 0 {
+		query = query.Where("created_at < ?", filter.CreatedBefore)
+	}
+	return query
+}
+
+// List returns queue items matching the filter, newest first.
+func (r *queueRepository) List(ctx context.Context, filter queue.Filter, offset int, limit int) ([]*queue.QueueItem, int, error) {
+	db := extractTx(ctx, r.db.DB)
+	query := applyQueueFilter(db.WithContext(ctx).Model(&QueueItem{}), filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entities []QueueItem
+	if err := query.Order("created_at DESC, id").Offset(offset).Limit(limit).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*queue.QueueItem, 0, len(entities))
+	for i := range entities {
+		items = append(items, domainFromEntity(&entities[i]))
+	}
+	return items, int(total), nil
+}
+
+// Get returns a queue item by id.
+func (r *queueRepository) Get(ctx context.Context, id string) (*queue.QueueItem, error) {
+	db := extractTx(ctx, r.db.DB)
+	var entity QueueItem
+	if err := db.WithContext(ctx).First(&entity, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &repository.ErrNotFound{Entity: "QueueItem", ID: id}
+		}
+		return nil, err
+	}
+	return domainFromEntity(&entity), nil
+}
+
+// replayUpdates resets an item so it is claimed again as if newly enqueued.
+// last_error is kept for reference.
+func replayUpdates() map[string]interface{} {
+	return map[string]interface{}{
+		"status":       queue.StatusPending,
+		"attempts":     0,
+		"available_at": time.Now().Unix(),
+		"reserved_by":  nil,
+		"reserved_at":  nil,
+	}
+}
+
+// Replay returns a dead item to pending.
+func (r *queueRepository) Replay(ctx context.Context, id string) error {
+	return repository.Transactional0(r.db, ctx, func(txCtx context.Context) error {
+		item, err := r.Get(txCtx, id)
+		if err != nil {
+			return err
+		}
+		if item.Status != queue.StatusDead {
+			return queue.ErrNotReplayable
+		}
+		tx := extractTx(txCtx, r.db.DB)
+		return tx.WithContext(ctx).
+			Model(&QueueItem{}).
+			Where("id = ? AND status = ?", id, queue.StatusDead).
+			Updates(replayUpdates()).
+			Error
+	})
+}
+
+// ReplayMatching returns all dead items matching the filter to pending.
+func (r *queueRepository) ReplayMatching(ctx context.Context, filter queue.Filter) (int, error) {
+	filter.Status = []queue.Status{queue.StatusDead}
+	db := extractTx(ctx, r.db.DB)
+	res := applyQueueFilter(db.WithContext(ctx).Model(&QueueItem{}), filter).Updates(replayUpdates())
+	return int(res.RowsAffected), res.Error
+}
+
+// Purge deletes queue items matching the filter.
+func (r *queueRepository) Purge(ctx context.Context, filter queue.Filter) (int, error) {
+	db := extractTx(ctx, r.db.DB)
+	res := applyQueueFilter(db.WithContext(ctx).Model(&QueueItem{}), filter).Delete(&QueueItem{})
+	return int(res.RowsAffected), res.Error
+}
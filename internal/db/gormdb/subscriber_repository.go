@@ -1,11 +1,10 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -86,7 +85,7 @@ func (r *subscriberRepository) Create(ctx context.Context, subscriber *domain.Su
 	}
 	err := db.WithContext(ctx).Create(subscriberToCreate).Error
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		if isUniqueViolation(err) {
 			return &repository.ErrUniqueConstraintFailed{Cause: err}
 		}
 		return err
@@ -133,7 +132,7 @@ func (r *subscriberRepository) Update(ctx context.Context, subscriber *domain.Su
 
 	// Update subscriber basic info
 	if err := db.WithContext(ctx).Model(&Subscriber{}).Where("id = ?", entity.ID).Updates(entity).Error; err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		if isUniqueViolation(err) {
 			return &repository.ErrUniqueConstraintFailed{Cause: err}
 		}
 		return err
@@ -294,3 +293,53 @@ func (r *subscriberRepository) BulkUpsert(ctx context.Context, subscribers []*do
 		return nil
 	})
 }
+
+func (r *subscriberRepository) SetListStatus(ctx context.Context, subscriberID string, listID string, status domain.SubscriberListStatus, at int64) (int, error) {
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&SubscriberList{}).
+		Where("subscriber_id = ? AND status <> ?", subscriberID, status)
+	if listID != "" {
+		query = query.Where("list_id = ?", listID)
+	}
+	// memberships only move to a status of higher precedence, e.g. an unsubscribe does
+	// not replace a complaint; confirming or resetting a single membership is explicit
+	if listID == "" || status.Precedence() > 0 {
+		var lower []domain.SubscriberListStatus
+		for _, s := range domain.SubscriberListStatuses {
+			if s.Precedence() < status.Precedence() {
+				lower = append(lower, s)
+			}
+		}
+		if len(lower) == 0 {
+			return 0, nil
+		}
+		query = query.Where("status IN ?", lower)
+	}
+
+	updates := map[string]interface{}{
+		"status":     status,
+		"updated_at": at,
+	}
+	switch status {
+	case domain.SubscriberListStatusConfirmed:
+		updates["subscribed_at"] = at
+	case domain.SubscriberListStatusUnsubscribed:
+		updates["unsubscribed_at"] = at
+	}
+	res := query.Updates(updates)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return int(res.RowsAffected), nil
+}
+
+func (r *subscriberRepository) DeleteListMemberships(ctx context.Context, status domain.SubscriberListStatus, updatedBefore int64) (int, error) {
+	db := extractTx(ctx, r.db.DB)
+	res := db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", status, updatedBefore).
+		Delete(&SubscriberList{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return int(res.RowsAffected), nil
+}
@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -15,7 +15,7 @@ import (
 
 func TestSubscriberRepository_CreateAndGet(t *testing.T) {
 	db := setupTestDB(t)
-	repo := NewSubscriberRepository(&DB{db})
+	repo := NewSubscriberRepository(&DB{DB: db})
 
 	ctx := context.Background()
 	now := time.Now().Unix()
@@ -0,0 +1,140 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package gormdb
+
+import (
+	"context"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type suppressionRepository struct {
+	db *DB
+}
+
+func NewSuppressionRepository(db *DB) repository.SuppressionRepository {
+	return &suppressionRepository{db: db}
+}
+
+func domainToSuppressionEntity(d *domain.Suppression) *Suppression {
+	return &Suppression{
+		ID:        d.ID,
+		Type:      d.Type,
+		Value:     d.Value,
+		Reason:    d.Reason,
+		Source:    d.Source,
+		ExpiresAt: d.ExpiresAt,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+func entityToSuppressionDomain(e *Suppression) *domain.Suppression {
+	return &domain.Suppression{
+		ID:        e.ID,
+		Type:      e.Type,
+		Value:     e.Value,
+		Reason:    e.Reason,
+		Source:    e.Source,
+		ExpiresAt: e.ExpiresAt,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func (r *suppressionRepository) Upsert(ctx context.Context, suppression *domain.Suppression) error {
+	entity := domainToSuppressionEntity(suppression)
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "value"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "source", "expires_at", "updated_at"}),
+	}).Create(entity).Error; err != nil {
+		return err
+	}
+
+	var stored Suppression
+	if err := db.WithContext(ctx).First(&stored, "type = ? AND value = ?", entity.Type, entity.Value).Error; err != nil {
+		return err
+	}
+	suppression.ID = stored.ID
+	suppression.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (r *suppressionRepository) GetByID(ctx context.Context, id string) (*domain.Suppression, error) {
+	var entity Suppression
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).First(&entity, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &repository.ErrNotFound{Entity: "Suppression", ID: id}
+		}
+		return nil, err
+	}
+	return entityToSuppressionDomain(&entity), nil
+}
+
+func (r *suppressionRepository) Delete(ctx context.Context, id string) error {
+	db := extractTx(ctx, r.db.DB)
+	res := db.WithContext(ctx).Delete(&Suppression{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &repository.ErrNotFound{Entity: "Suppression", ID: id}
+	}
+	return nil
+}
+
+func (r *suppressionRepository) List(ctx context.Context, filter repository.SuppressionFilter, pagination repository.Pagination) ([]*domain.Suppression, int, error) {
+	var entities []Suppression
+	var total int64
+
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&Suppression{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+	if filter.Search != "" {
+		query = query.Where("value LIKE ?", "%"+filter.Search+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pagination.Limit).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+
+	suppressions := make([]*domain.Suppression, 0, len(entities))
+	for i := range entities {
+		suppressions = append(suppressions, entityToSuppressionDomain(&entities[i]))
+	}
+	return suppressions, int(total), nil
+}
+
+func (r *suppressionRepository) Match(ctx context.Context, email string, domainName string, now int64) (*domain.Suppression, error) {
+	var entity Suppression
+	db := extractTx(ctx, r.db.DB)
+	err := db.WithContext(ctx).
+		Where("(type = ? AND value = ?) OR (type = ? AND value = ?)",
+			domain.SuppressionTypeEmail, email, domain.SuppressionTypeDomain, domainName).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at").
+		First(&entity).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &repository.ErrNotFound{Entity: "Suppression", ID: email}
+		}
+		return nil, err
+	}
+	return entityToSuppressionDomain(&entity), nil
+}
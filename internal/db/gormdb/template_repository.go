@@ -1,7 +1,7 @@
 // Copyright 2025 JC-Lab
 // SPDX-License-Identifier: AGPL-3.0-or-later
 
-package sqlite
+package gormdb
 
 import (
 	"context"
@@ -27,12 +27,14 @@ func (r *templateRepository) Create(ctx context.Context, template *domain.Templa
 	template.UpdatedAt = template.CreatedAt
 
 	gormTemplate := toTemplateGorm(template)
-	return r.db.WithContext(ctx).Create(gormTemplate).Error
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Create(gormTemplate).Error
 }
 
 func (r *templateRepository) GetByID(ctx context.Context, id string) (*domain.Template, error) {
 	var gormTemplate Template
-	if err := r.db.WithContext(ctx).First(&gormTemplate, "id = ?", id).Error; err != nil {
+	db := extractTx(ctx, r.db.DB)
+	if err := db.WithContext(ctx).First(&gormTemplate, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return toTemplateDomain(&gormTemplate), nil
@@ -41,11 +43,13 @@ func (r *templateRepository) GetByID(ctx context.Context, id string) (*domain.Te
 func (r *templateRepository) Update(ctx context.Context, template *domain.Template) error {
 	template.UpdatedAt = time.Now().Unix()
 	gormTemplate := toTemplateGorm(template)
-	return r.db.WithContext(ctx).Save(gormTemplate).Error
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Save(gormTemplate).Error
 }
 
 func (r *templateRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Model(&Template{}).
+	db := extractTx(ctx, r.db.DB)
+	return db.WithContext(ctx).Model(&Template{}).
 		Where("id = ?", id).
 		Update("deleted_at", time.Now().Unix()).
 		Error
@@ -55,7 +59,8 @@ func (r *templateRepository) List(ctx context.Context, pagination repository.Pag
 	var gormTemplates []*Template
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&Template{})
+	db := extractTx(ctx, r.db.DB)
+	query := db.WithContext(ctx).Model(&Template{})
 
 	query = query.Where("deleted_at IS NULL")
 
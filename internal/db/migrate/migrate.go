@@ -0,0 +1,245 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package migrate applies versioned SQL migrations and records them in the
+// schema_migrations table.
+//
+// Migrations are read from files named NNNN_name.up.sql and NNNN_name.down.sql.
+// Every migration runs in its own transaction together with its bookkeeping
+// row. Note that MySQL commits DDL implicitly, so a failing MySQL migration
+// may be left partially applied.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/headmail/headmail/pkg/db"
+	"gorm.io/gorm"
+)
+
+// TableName is the name of the table that records applied migrations.
+const TableName = "schema_migrations"
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads migrations from the root of fsys, sorted by version.
+// Every version must have an up script; the down script is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileNamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedMigration is the GORM model for a schema_migrations row.
+type appliedMigration struct {
+	Version   int64  `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string `gorm:"column:name"`
+	AppliedAt int64  `gorm:"column:applied_at"`
+}
+
+func (appliedMigration) TableName() string {
+	return TableName
+}
+
+// Migrator applies a fixed set of migrations to a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+var _ db.Migrator = (*Migrator)(nil)
+
+// New creates a Migrator for the given migrations, which must be sorted by version.
+func New(gdb *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: gdb, migrations: migrations}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec(
+		"CREATE TABLE IF NOT EXISTS " + TableName + " (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at BIGINT NOT NULL)",
+	).Error
+}
+
+// applied returns the applied migrations by version. A missing table means nothing
+// was applied; it is not created, so reading the status never changes the schema.
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	if !m.db.WithContext(ctx).Migrator().HasTable(TableName) {
+		return map[int64]appliedMigration{}, nil
+	}
+	var rows []appliedMigration
+	if err := m.db.WithContext(ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// MigrateUp applies up to steps pending migrations, or all of them when steps <= 0.
+func (m *Migrator) MigrateUp(ctx context.Context, steps int) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range m.migrations {
+		if steps > 0 && count >= steps {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, mig.Up); err != nil {
+				return err
+			}
+			return tx.Create(&appliedMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().Unix()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown reverts up to steps applied migrations, newest first.
+func (m *Migrator) MigrateDown(ctx context.Context, steps int) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if steps > 0 && count >= steps {
+			break
+		}
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s cannot be reverted: no down script", mig.Version, mig.Name)
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, mig.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&appliedMigration{Version: mig.Version}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrationStatus lists the known and applied migrations in version order.
+func (m *Migrator) MigrationStatus(ctx context.Context) ([]db.MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]db.MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := db.MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, db.MigrationStatus{
+			Version:   row.Version,
+			Name:      row.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func execScript(tx *gorm.DB, script string) error {
+	for _, stmt := range SplitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0002_add_color.up.sql":       {Data: []byte("ALTER TABLE things ADD COLUMN color text;")},
+		"0002_add_color.down.sql":     {Data: []byte("ALTER TABLE things DROP COLUMN color;")},
+		"0001_create_things.up.sql":   {Data: []byte("-- things; with a comment\nCREATE TABLE things (id text PRIMARY KEY, note text DEFAULT 'a;b');\nINSERT INTO things (id) VALUES ('x');")},
+		"0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+	}
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// a single connection keeps the in-memory database alive across statements
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations())
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_things", migrations[0].Name)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "ALTER TABLE things DROP COLUMN color;", migrations[1].Down)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(fstest.MapFS{"create_things.sql": {Data: []byte("")}})
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE a;")}})
+	assert.Error(t, err, "missing up script")
+
+	_, err = Load(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Error(t, err, "conflicting names")
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrations, err := Load(testMigrations())
+	require.NoError(t, err)
+	m := New(db, migrations)
+
+	statuses, err := m.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+	assert.False(t, db.Migrator().HasTable(TableName), "reading the status must not change the schema")
+
+	n, err := m.MigrateUp(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	var note string
+	require.NoError(t, db.Raw("SELECT note FROM things WHERE id = 'x'").Scan(&note).Error)
+	assert.Equal(t, "a;b", note)
+
+	n, err = m.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, db.Migrator().HasColumn("things", "color"))
+
+	statuses, err = m.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// nothing left to apply
+	n, err = m.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = m.MigrateDown(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, db.Migrator().HasColumn("things", "color"))
+
+	n, err = m.MigrateDown(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, db.Migrator().HasTable("things"))
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := New(db, []Migration{
+		{Version: 1, Name: "ok", Up: "CREATE TABLE a (id text);"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE b (id text); CREATE TABLE broken (;"},
+	})
+
+	n, err := m.MigrateUp(ctx, 0)
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, db.Migrator().HasTable("b"), "failed migration must be rolled back")
+
+	statuses, err := m.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrator_UnknownApplied(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrations, err := Load(testMigrations())
+	require.NoError(t, err)
+	_, err = New(db, migrations).MigrateUp(ctx, 0)
+	require.NoError(t, err)
+
+	// an older binary only knows the first migration
+	statuses, err := New(db, migrations[:1]).MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Unknown)
+	assert.True(t, statuses[1].Unknown)
+	assert.True(t, statuses[1].Applied)
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- leading comment; ignored
+CREATE TABLE a (s text DEFAULT 'x;y', "we;ird" text);
+/* block; comment */
+INSERT INTO a VALUES ('it''s;', '');
+
+-- trailing comment`
+	assert.Equal(t, []string{
+		`CREATE TABLE a (s text DEFAULT 'x;y', "we;ird" text)`,
+		`INSERT INTO a VALUES ('it''s;', '')`,
+	}, SplitStatements(script))
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package migrate
+
+import "strings"
+
+// SplitStatements splits a SQL script into statements on semicolons.
+// Semicolons inside quoted strings, quoted identifiers and comments are ignored.
+// Comment-only statements are dropped.
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end - 1
+			continue
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(script) {
+				if script[end] == c {
+					// a doubled quote is an escaped quote
+					if end+1 < len(script) && script[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(script) {
+				end = len(script) - 1
+			}
+			current.WriteString(script[i : end+1])
+			hasCode = true
+			i = end
+			continue
+		case c == ';':
+			flush()
+			continue
+		}
+
+		if !hasCode && (c == ' ' || c == '\t' || c == '\n' || c == '\r') {
+			continue
+		}
+		current.WriteByte(c)
+		hasCode = true
+	}
+	flush()
+	return statements
+}
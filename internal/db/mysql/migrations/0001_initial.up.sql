@@ -0,0 +1,132 @@
+-- Initial schema. IF NOT EXISTS lets databases created by earlier releases
+-- (which used GORM AutoMigrate) adopt versioned migrations in place. MySQL has
+-- no CREATE INDEX IF NOT EXISTS, so indexes are declared inline.
+CREATE TABLE IF NOT EXISTS `lists` (
+    `id` varchar(191) NOT NULL,
+    `name` longtext,
+    `description` longtext,
+    `tags` json,
+    `created_at` bigint,
+    `updated_at` bigint,
+    `deleted_at` bigint,
+    PRIMARY KEY (`id`),
+    INDEX `idx_lists_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `subscribers` (
+    `id` varchar(191) NOT NULL,
+    `email` varchar(255),
+    `name` longtext,
+    `status` longtext,
+    `created_at` bigint,
+    `updated_at` bigint,
+    `deleted_at` bigint,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_subscribers_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `subscriber_lists` (
+    `subscriber_id` varchar(191) NOT NULL,
+    `list_id` varchar(191) NOT NULL,
+    `status` longtext,
+    `subscribed_at` bigint,
+    `unsubscribed_at` bigint,
+    `created_at` bigint,
+    `updated_at` bigint,
+    PRIMARY KEY (`subscriber_id`, `list_id`),
+    CONSTRAINT `fk_subscribers_lists` FOREIGN KEY (`subscriber_id`) REFERENCES `subscribers`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `campaigns` (
+    `id` varchar(191) NOT NULL,
+    `name` longtext,
+    `template_id` longtext,
+    `status` longtext,
+    `from_name` longtext,
+    `from_email` longtext,
+    `subject` longtext,
+    `template_mjml` longtext,
+    `template_text` longtext,
+    `data` json,
+    `tags` json,
+    `headers` json,
+    `utm_params` json,
+    `scheduled_at` bigint,
+    `sent_at` bigint,
+    `created_at` bigint,
+    `updated_at` bigint,
+    `deleted_at` bigint,
+    `recipient_count` bigint,
+    `delivered_count` bigint,
+    `failed_count` bigint,
+    `open_count` bigint,
+    `click_count` bigint,
+    `bounce_count` bigint,
+    PRIMARY KEY (`id`),
+    INDEX `idx_campaigns_deleted_at` (`deleted_at`),
+    INDEX `idx_campaigns_created_at` (`created_at` desc)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `deliveries` (
+    `id` varchar(191) NOT NULL,
+    `campaign_id` longtext,
+    `type` longtext,
+    `status` longtext,
+    `name` longtext,
+    `email` varchar(255),
+    `subject` longtext,
+    `body_html` longtext,
+    `body_text` longtext,
+    `message_id` longtext,
+    `data` json,
+    `headers` json,
+    `tags` json,
+    `created_at` bigint,
+    `scheduled_at` bigint,
+    `attempts` bigint,
+    `sent_at` bigint,
+    `opened_at` bigint,
+    `failed_at` bigint,
+    `failure_reason` longtext,
+    `open_count` bigint,
+    `click_count` bigint,
+    `bounce_count` bigint,
+    PRIMARY KEY (`id`),
+    INDEX `idx_deliveries_created_at` (`created_at` desc)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `delivery_events` (
+    `id` varchar(191) NOT NULL,
+    `delivery_id` longtext,
+    `event_type` longtext,
+    `event_data` json,
+    `user_agent` longtext,
+    `ip_address` longtext,
+    `url` longtext,
+    `created_at` bigint,
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `templates` (
+    `id` varchar(191) NOT NULL,
+    `created_at` bigint,
+    `updated_at` bigint,
+    `deleted_at` bigint,
+    `name` longtext,
+    `subject` longtext,
+    `body_mjml` longtext,
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `queue_items` (
+    `id` varchar(191) NOT NULL,
+    `type` longtext,
+    `payload` json,
+    `unique_key` varchar(255),
+    `status` longtext,
+    `reserved_by` longtext,
+    `reserved_at` bigint,
+    `created_at` bigint,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_queue_items_unique_key` (`unique_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
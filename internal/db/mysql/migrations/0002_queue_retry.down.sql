@@ -0,0 +1,8 @@
+UPDATE `queue_items` SET `status` = 'failed' WHERE `status` = 'dead';
+
+ALTER TABLE `queue_items`
+    DROP INDEX `idx_queue_items_status_available_at`,
+    DROP COLUMN `last_error`,
+    DROP COLUMN `available_at`,
+    DROP COLUMN `attempts`,
+    MODIFY `status` longtext;
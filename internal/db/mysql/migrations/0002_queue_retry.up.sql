@@ -0,0 +1,10 @@
+-- status is narrowed from longtext so it can be indexed.
+ALTER TABLE `queue_items`
+    MODIFY `status` varchar(32),
+    ADD COLUMN `attempts` bigint NOT NULL DEFAULT 0,
+    ADD COLUMN `available_at` bigint NOT NULL DEFAULT 0,
+    ADD COLUMN `last_error` longtext,
+    ADD INDEX `idx_queue_items_status_available_at` (`status`, `available_at`);
+
+-- "failed" was terminal before retries existed; it is now the dead-letter state.
+UPDATE `queue_items` SET `status` = 'dead' WHERE `status` = 'failed';
@@ -0,0 +1,3 @@
+ALTER TABLE `deliveries`
+    ADD COLUMN `from_name` longtext,
+    ADD COLUMN `from_email` longtext;
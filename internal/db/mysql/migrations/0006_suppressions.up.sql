@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `suppressions` (
+    `id` varchar(191) NOT NULL,
+    `type` varchar(32),
+    `value` varchar(191),
+    `reason` longtext,
+    `source` longtext,
+    `expires_at` bigint,
+    `created_at` bigint,
+    `updated_at` bigint,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_suppressions_type_value` (`type`, `value`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+ALTER TABLE `deliveries`
+    DROP COLUMN `complaint_count`;
+ALTER TABLE `campaigns`
+    DROP COLUMN `complaint_count`;
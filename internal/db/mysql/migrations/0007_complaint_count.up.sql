@@ -0,0 +1,4 @@
+ALTER TABLE `campaigns`
+    ADD COLUMN `complaint_count` bigint NOT NULL DEFAULT 0;
+ALTER TABLE `deliveries`
+    ADD COLUMN `complaint_count` bigint NOT NULL DEFAULT 0;
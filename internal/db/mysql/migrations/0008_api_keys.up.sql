@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS `api_keys`;
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` varchar(191) NOT NULL,
+    `name` longtext,
+    `prefix` longtext,
+    `key_hash` varchar(191),
+    `scopes` json,
+    `last_used_at` bigint,
+    `revoked_at` bigint,
+    `created_at` bigint,
+    `updated_at` bigint,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_api_keys_key_hash` (`key_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS `audit_entries`;
+CREATE TABLE IF NOT EXISTS `audit_entries` (
+    `id` varchar(191) NOT NULL,
+    `principal` varchar(191),
+    `action` varchar(191),
+    `entity_type` varchar(64),
+    `entity_id` varchar(191),
+    `method` varchar(16),
+    `path` longtext,
+    `changes` json,
+    `created_at` bigint,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_entries_entity` (`entity_type`, `entity_id`),
+    INDEX `idx_audit_entries_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `links` (
+    `id` varchar(191) NOT NULL,
+    `campaign_id` varchar(191),
+    `url` longtext,
+    `click_count` bigint DEFAULT 0,
+    `created_at` bigint,
+    PRIMARY KEY (`id`),
+    INDEX `idx_links_campaign_id` (`campaign_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,38 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mysql
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+
+	"github.com/headmail/headmail/internal/db/gormdb"
+	"github.com/headmail/headmail/pkg/config"
+	"gorm.io/driver/mysql"
+)
+
+// New opens a connection to the MySQL/MariaDB database using GORM.
+// The schema is managed by the versioned migrations in the migrations directory.
+// cfg.URL is a go-sql-driver DSN, e.g. "user:pass@tcp(host:3306)/headmail?charset=utf8mb4".
+func New(cfg config.DatabaseConfig) (*gormdb.DB, error) {
+	db, err := gormdb.Open(mysql.Open(cfg.URL), migrationFS())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Database connection established.")
+	return db, nil
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func migrationFS() fs.FS {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,131 @@
+-- Initial schema. IF NOT EXISTS lets databases created by earlier releases
+-- (which used GORM AutoMigrate) adopt versioned migrations in place.
+CREATE TABLE IF NOT EXISTS lists (
+    id text,
+    name text,
+    description text,
+    tags jsonb,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_lists_deleted_at ON lists(deleted_at);
+
+CREATE TABLE IF NOT EXISTS subscribers (
+    id text,
+    email varchar(255),
+    name text,
+    status text,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscribers_email ON subscribers(email);
+
+CREATE TABLE IF NOT EXISTS subscriber_lists (
+    subscriber_id text,
+    list_id text,
+    status text,
+    subscribed_at bigint,
+    unsubscribed_at bigint,
+    created_at bigint,
+    updated_at bigint,
+    PRIMARY KEY (subscriber_id, list_id),
+    CONSTRAINT fk_subscribers_lists FOREIGN KEY (subscriber_id) REFERENCES subscribers(id)
+);
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id text,
+    name text,
+    template_id text,
+    status text,
+    from_name text,
+    from_email text,
+    subject text,
+    template_mjml text,
+    template_text text,
+    data jsonb,
+    tags jsonb,
+    headers jsonb,
+    utm_params jsonb,
+    scheduled_at bigint,
+    sent_at bigint,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    recipient_count bigint,
+    delivered_count bigint,
+    failed_count bigint,
+    open_count bigint,
+    click_count bigint,
+    bounce_count bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_campaigns_deleted_at ON campaigns(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_campaigns_created_at ON campaigns(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    id text,
+    campaign_id text,
+    type text,
+    status text,
+    name text,
+    email varchar(255),
+    subject text,
+    body_html text,
+    body_text text,
+    message_id text,
+    data jsonb,
+    headers jsonb,
+    tags jsonb,
+    created_at bigint,
+    scheduled_at bigint,
+    attempts bigint,
+    sent_at bigint,
+    opened_at bigint,
+    failed_at bigint,
+    failure_reason text,
+    open_count bigint,
+    click_count bigint,
+    bounce_count bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_deliveries_created_at ON deliveries(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS delivery_events (
+    id text,
+    delivery_id text,
+    event_type text,
+    event_data jsonb,
+    user_agent text,
+    ip_address text,
+    url text,
+    created_at bigint,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id text,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    name text,
+    subject text,
+    body_mjml text,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS queue_items (
+    id text,
+    type text,
+    payload jsonb,
+    unique_key varchar(255),
+    status text,
+    reserved_by text,
+    reserved_at bigint,
+    created_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queue_items_unique_key ON queue_items(unique_key);
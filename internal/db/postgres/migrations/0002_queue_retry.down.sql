@@ -0,0 +1,7 @@
+UPDATE queue_items SET status = 'failed' WHERE status = 'dead';
+
+DROP INDEX IF EXISTS idx_queue_items_status_available_at;
+ALTER TABLE queue_items
+    DROP COLUMN last_error,
+    DROP COLUMN available_at,
+    DROP COLUMN attempts;
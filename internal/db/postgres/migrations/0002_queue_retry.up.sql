@@ -0,0 +1,8 @@
+ALTER TABLE queue_items
+    ADD COLUMN attempts bigint NOT NULL DEFAULT 0,
+    ADD COLUMN available_at bigint NOT NULL DEFAULT 0,
+    ADD COLUMN last_error text;
+CREATE INDEX idx_queue_items_status_available_at ON queue_items(status, available_at);
+
+-- "failed" was terminal before retries existed; it is now the dead-letter state.
+UPDATE queue_items SET status = 'dead' WHERE status = 'failed';
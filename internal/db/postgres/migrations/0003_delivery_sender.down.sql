@@ -0,0 +1,3 @@
+ALTER TABLE deliveries
+    DROP COLUMN from_email,
+    DROP COLUMN from_name;
@@ -0,0 +1,3 @@
+ALTER TABLE deliveries
+    ADD COLUMN from_name text,
+    ADD COLUMN from_email text;
@@ -0,0 +1,2 @@
+ALTER TABLE deliveries
+    DROP COLUMN list_id;
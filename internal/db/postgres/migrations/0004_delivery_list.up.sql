@@ -0,0 +1,2 @@
+ALTER TABLE deliveries
+    ADD COLUMN list_id text;
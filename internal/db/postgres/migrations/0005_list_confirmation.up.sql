@@ -0,0 +1,2 @@
+ALTER TABLE lists
+    ADD COLUMN confirmation_template_id text;
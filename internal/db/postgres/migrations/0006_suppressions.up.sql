@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS suppressions (
+    id text,
+    type text,
+    value text,
+    reason text,
+    source text,
+    expires_at bigint,
+    created_at bigint,
+    updated_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_suppressions_type_value ON suppressions(type, value);
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS api_keys;
+CREATE TABLE IF NOT EXISTS api_keys (
+    id text,
+    name text,
+    prefix text,
+    key_hash text,
+    scopes jsonb,
+    last_used_at bigint,
+    revoked_at bigint,
+    created_at bigint,
+    updated_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
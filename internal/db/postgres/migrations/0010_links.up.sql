@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS links (
+    id text,
+    campaign_id text,
+    url text,
+    click_count bigint DEFAULT 0,
+    created_at bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_links_campaign_id ON links(campaign_id);
@@ -0,0 +1,3 @@
+ALTER TABLE campaigns
+    ADD COLUMN link_count bigint NOT NULL DEFAULT 0;
+UPDATE campaigns SET link_count = (SELECT COUNT(*) FROM links WHERE links.campaign_id = campaigns.id);
@@ -0,0 +1,45 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/headmail/headmail/internal/db/dbtest"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestContract runs the repository contract suite against the database given by
+// HEADMAIL_TEST_POSTGRES_DSN. The public schema of that database is dropped
+// before every subtest, so never point it at a database holding real data.
+func TestContract(t *testing.T) {
+	dsn := os.Getenv("HEADMAIL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("HEADMAIL_TEST_POSTGRES_DSN is not set")
+	}
+
+	dbtest.Run(t, func(t *testing.T) repository.DB {
+		reset, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, reset.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public").Error)
+		if sqlDB, err := reset.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+
+		db, err := New(config.DatabaseConfig{URL: dsn})
+		require.NoError(t, err)
+		_, err = db.MigrateUp(context.Background(), 0)
+		require.NoError(t, err)
+		sqlDB, err := db.DB.DB()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		return db
+	})
+}
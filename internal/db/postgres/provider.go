@@ -0,0 +1,22 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package postgres
+
+import (
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/db"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// provider implements the db.Provider interface for PostgreSQL.
+type provider struct{}
+
+func init() {
+	db.RegisterProvider("postgres", &provider{})
+}
+
+// New creates a new PostgreSQL database connection.
+func (p *provider) New(cfg config.DatabaseConfig) (repository.DB, error) {
+	return New(cfg)
+}
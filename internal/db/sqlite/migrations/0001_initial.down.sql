@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `queue_items`;
+DROP TABLE IF EXISTS `templates`;
+DROP TABLE IF EXISTS `delivery_events`;
+DROP TABLE IF EXISTS `deliveries`;
+DROP TABLE IF EXISTS `campaigns`;
+DROP TABLE IF EXISTS `subscriber_lists`;
+DROP TABLE IF EXISTS `subscribers`;
+DROP TABLE IF EXISTS `lists`;
@@ -0,0 +1,131 @@
+-- Initial schema. IF NOT EXISTS lets databases created by earlier releases
+-- (which used GORM AutoMigrate) adopt versioned migrations in place.
+CREATE TABLE IF NOT EXISTS `lists` (
+    `id` text,
+    `name` text,
+    `description` text,
+    `tags` JSON,
+    `created_at` integer,
+    `updated_at` integer,
+    `deleted_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_lists_deleted_at` ON `lists`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `subscribers` (
+    `id` text,
+    `email` text,
+    `name` text,
+    `status` text,
+    `created_at` integer,
+    `updated_at` integer,
+    `deleted_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_subscribers_email` ON `subscribers`(`email`);
+
+CREATE TABLE IF NOT EXISTS `subscriber_lists` (
+    `subscriber_id` text,
+    `list_id` text,
+    `status` text,
+    `subscribed_at` integer,
+    `unsubscribed_at` integer,
+    `created_at` integer,
+    `updated_at` integer,
+    PRIMARY KEY (`subscriber_id`, `list_id`),
+    CONSTRAINT `fk_subscribers_lists` FOREIGN KEY (`subscriber_id`) REFERENCES `subscribers`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `campaigns` (
+    `id` text,
+    `name` text,
+    `template_id` text,
+    `status` text,
+    `from_name` text,
+    `from_email` text,
+    `subject` text,
+    `template_mjml` text,
+    `template_text` text,
+    `data` JSON,
+    `tags` JSON,
+    `headers` JSON,
+    `utm_params` JSON,
+    `scheduled_at` integer,
+    `sent_at` integer,
+    `created_at` integer,
+    `updated_at` integer,
+    `deleted_at` integer,
+    `recipient_count` integer,
+    `delivered_count` integer,
+    `failed_count` integer,
+    `open_count` integer,
+    `click_count` integer,
+    `bounce_count` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_campaigns_deleted_at` ON `campaigns`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_campaigns_created_at` ON `campaigns`(`created_at` desc);
+
+CREATE TABLE IF NOT EXISTS `deliveries` (
+    `id` text,
+    `campaign_id` text,
+    `type` text,
+    `status` text,
+    `name` text,
+    `email` text,
+    `subject` text,
+    `body_html` text,
+    `body_text` text,
+    `message_id` text,
+    `data` JSON,
+    `headers` JSON,
+    `tags` JSON,
+    `created_at` integer,
+    `scheduled_at` integer,
+    `attempts` integer,
+    `sent_at` integer,
+    `opened_at` integer,
+    `failed_at` integer,
+    `failure_reason` text,
+    `open_count` integer,
+    `click_count` integer,
+    `bounce_count` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_deliveries_created_at` ON `deliveries`(`created_at` desc);
+
+CREATE TABLE IF NOT EXISTS `delivery_events` (
+    `id` text,
+    `delivery_id` text,
+    `event_type` text,
+    `event_data` JSON,
+    `user_agent` text,
+    `ip_address` text,
+    `url` text,
+    `created_at` integer,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `templates` (
+    `id` text,
+    `created_at` integer,
+    `updated_at` integer,
+    `deleted_at` integer,
+    `name` text,
+    `subject` text,
+    `body_mjml` text,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `queue_items` (
+    `id` text,
+    `type` text,
+    `payload` JSON,
+    `unique_key` text,
+    `status` text,
+    `reserved_by` text,
+    `reserved_at` integer,
+    `created_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_queue_items_unique_key` ON `queue_items`(`unique_key`);
@@ -0,0 +1,7 @@
+ALTER TABLE `queue_items` ADD COLUMN `attempts` integer NOT NULL DEFAULT 0;
+ALTER TABLE `queue_items` ADD COLUMN `available_at` integer NOT NULL DEFAULT 0;
+ALTER TABLE `queue_items` ADD COLUMN `last_error` text;
+CREATE INDEX `idx_queue_items_status_available_at` ON `queue_items`(`status`, `available_at`);
+
+-- "failed" was terminal before retries existed; it is now the dead-letter state.
+UPDATE `queue_items` SET `status` = 'dead' WHERE `status` = 'failed';
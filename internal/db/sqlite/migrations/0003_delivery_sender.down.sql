@@ -0,0 +1,2 @@
+ALTER TABLE `deliveries` DROP COLUMN `from_email`;
+ALTER TABLE `deliveries` DROP COLUMN `from_name`;
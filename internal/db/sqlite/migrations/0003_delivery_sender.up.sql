@@ -0,0 +1,2 @@
+ALTER TABLE `deliveries` ADD COLUMN `from_name` text;
+ALTER TABLE `deliveries` ADD COLUMN `from_email` text;
@@ -0,0 +1 @@
+ALTER TABLE `lists` DROP COLUMN `confirmation_template_id`;
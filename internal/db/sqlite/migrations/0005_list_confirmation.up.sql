@@ -0,0 +1 @@
+ALTER TABLE `lists` ADD COLUMN `confirmation_template_id` text;
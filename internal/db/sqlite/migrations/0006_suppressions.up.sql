@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `suppressions` (
+    `id` text,
+    `type` text,
+    `value` text,
+    `reason` text,
+    `source` text,
+    `expires_at` integer,
+    `created_at` integer,
+    `updated_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_suppressions_type_value` ON `suppressions`(`type`, `value`);
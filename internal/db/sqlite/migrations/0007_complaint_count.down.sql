@@ -0,0 +1,2 @@
+ALTER TABLE `deliveries` DROP COLUMN `complaint_count`;
+ALTER TABLE `campaigns` DROP COLUMN `complaint_count`;
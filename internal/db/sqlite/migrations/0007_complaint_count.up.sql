@@ -0,0 +1,2 @@
+ALTER TABLE `campaigns` ADD COLUMN `complaint_count` integer NOT NULL DEFAULT 0;
+ALTER TABLE `deliveries` ADD COLUMN `complaint_count` integer NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS `api_keys`;
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` text,
+    `name` text,
+    `prefix` text,
+    `key_hash` text,
+    `scopes` JSON,
+    `last_used_at` integer,
+    `revoked_at` integer,
+    `created_at` integer,
+    `updated_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_keys_key_hash` ON `api_keys`(`key_hash`);
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS `audit_entries`;
+CREATE TABLE IF NOT EXISTS `audit_entries` (
+    `id` text,
+    `principal` text,
+    `action` text,
+    `entity_type` text,
+    `entity_id` text,
+    `method` text,
+    `path` text,
+    `changes` JSON,
+    `created_at` integer,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_entries_entity` ON `audit_entries`(`entity_type`, `entity_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_entries_created_at` ON `audit_entries`(`created_at`);
@@ -0,0 +1 @@
+ALTER TABLE `campaigns` DROP COLUMN `link_count`;
@@ -0,0 +1,53 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/internal/db/dbtest"
+	"github.com/headmail/headmail/pkg/config"
+	pkgdb "github.com/headmail/headmail/pkg/db"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContract(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) repository.DB {
+		db, err := New(config.DatabaseConfig{
+			URL: "file:" + uuid.NewString() + "?mode=memory&cache=shared",
+		})
+		require.NoError(t, err)
+		_, err = db.MigrateUp(context.Background(), 0)
+		require.NoError(t, err)
+		sqlDB, err := db.DB.DB()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		return db
+	})
+}
+
+func TestMigrations_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(config.DatabaseConfig{URL: "file:" + uuid.NewString() + "?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	sqlDB, err := db.DB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	applied, err := db.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	require.Positive(t, applied)
+
+	reverted, err := db.MigrateDown(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, applied, reverted)
+	require.False(t, db.Migrator().HasTable("lists"))
+
+	_, err = db.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	require.NoError(t, pkgdb.CheckSchema(ctx, db))
+}
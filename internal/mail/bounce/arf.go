@@ -0,0 +1,94 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package bounce
+
+import (
+	"bufio"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/emersion/go-message/textproto"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// Feedback is an RFC 5965 feedback report, sent by mailbox providers' feedback loops
+// when a recipient marks a message as spam.
+type Feedback struct {
+	// FeedbackType is abuse, fraud, virus, not-spam or other.
+	FeedbackType     string
+	UserAgent        string
+	SourceIP         string
+	OriginalMailFrom string
+	OriginalRcptTo   string
+	ReportedDomain   string
+	ArrivalDate      string
+}
+
+// IsComplaint reports whether the feedback is a complaint about the message.
+func (f *Feedback) IsComplaint() bool {
+	switch f.FeedbackType {
+	case "abuse", "fraud":
+		return true
+	default:
+		return false
+	}
+}
+
+// readFeedbackReport reads the fields of a message/feedback-report part.
+func (r *Report) readFeedbackReport(body io.Reader) error {
+	header, err := textproto.ReadHeader(bufio.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	field := func(name string) string {
+		return strings.Join(strings.Fields(header.Get(name)), " ")
+	}
+	r.Feedback = &Feedback{
+		FeedbackType:     strings.ToLower(field("Feedback-Type")),
+		UserAgent:        field("User-Agent"),
+		SourceIP:         field("Source-IP"),
+		OriginalMailFrom: strings.Trim(field("Original-Mail-From"), "<>"),
+		OriginalRcptTo:   strings.Trim(field("Original-Rcpt-To"), "<>"),
+		ReportedDomain:   field("Reported-Domain"),
+		ArrivalDate:      field("Arrival-Date"),
+	}
+	return nil
+}
+
+// complaintEvent converts a feedback report into a complaint event, or returns nil when
+// the feedback is not a complaint.
+func (r *Report) complaintEvent() *receiver.Event {
+	if !r.Feedback.IsComplaint() {
+		return nil
+	}
+	event := &receiver.Event{
+		Type:         receiver.EventTypeComplaint,
+		DeliveryID:   r.DeliveryID,
+		MessageID:    r.MessageID,
+		Subject:      r.Subject,
+		FeedbackType: r.Feedback.FeedbackType,
+		Reason:       "complaint (" + r.Feedback.FeedbackType + ")",
+	}
+	if r.Feedback.UserAgent != "" {
+		event.Reason += " reported by " + r.Feedback.UserAgent
+	}
+	recipient := r.Feedback.OriginalRcptTo
+	if recipient == "" {
+		recipient = r.originalRecipient
+	}
+	if recipient != "" {
+		event.BouncedRecipients = []string{recipient}
+	}
+	return event
+}
+
+// firstAddress returns the first address of an address list header, or "" when it has none.
+func firstAddress(s string) string {
+	addrs, err := mail.ParseAddressList(s)
+	if err != nil || len(addrs) == 0 {
+		return ""
+	}
+	return addrs[0].Address
+}
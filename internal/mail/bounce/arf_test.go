@@ -0,0 +1,65 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package bounce
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Feedback(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    *receiver.Event
+	}{
+		{
+			fixture: "arf-abuse.eml",
+			want: &receiver.Event{
+				Type:              receiver.EventTypeComplaint,
+				DeliveryID:        "d-arf",
+				MessageID:         "arf-1@fbl.example.net",
+				Subject:           "FW: Newsletter",
+				BouncedRecipients: []string{"judy@example.net"},
+				FeedbackType:      "abuse",
+				Reason:            "complaint (abuse) reported by SomeGenerator/1.0",
+			},
+		},
+		{
+			// the recipient is taken from the original when the report omits it
+			fixture: "arf-redacted.eml",
+			want: &receiver.Event{
+				Type:              receiver.EventTypeComplaint,
+				DeliveryID:        "d-arf-redacted",
+				MessageID:         "arf-2@fbl.example.com",
+				Subject:           "Complaint about message from 203.0.113.1",
+				BouncedRecipients: []string{"kim@example.com"},
+				FeedbackType:      "abuse",
+				Reason:            "complaint (abuse) reported by ExampleFBL/2.1",
+			},
+		},
+		{
+			fixture: "arf-not-spam.eml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			require.NoError(t, err)
+			defer f.Close()
+
+			report, err := Parse(f)
+			require.NoError(t, err)
+			assert.Equal(t, "arf", report.Format)
+			require.NotNil(t, report.Feedback)
+			assert.Empty(t, report.Recipients)
+			assert.Equal(t, tt.want, report.Event())
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package bounce
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// diagnosticRules classify failures by the diagnostic text of the remote server. They
+// are checked in order before the status code, since many servers answer every failure
+// with a generic code such as 5.0.0 or 5.5.0.
+var diagnosticRules = []struct {
+	pattern    *regexp.Regexp
+	bounceType receiver.BounceType
+}{
+	{regexp.MustCompile(`(?i)mailbox (is )?full|over ?quota|quota exceeded|exceeded (the )?storage|insufficient (system )?storage`), receiver.BounceTypeSoft},
+	{regexp.MustCompile(`(?i)try (again )?later|temporar(il)?y|grey ?listed|gray ?listed|rate limit|too many (connections|messages)|deferred`), receiver.BounceTypeTransient},
+	{regexp.MustCompile(`(?i)spam|blocked|black ?list|block ?list|listed (at|in|on|by)|reputation|dmarc|spf|dkim|policy|content rejected`), receiver.BounceTypeSoft},
+	{regexp.MustCompile(`(?i)user unknown|unknown user|no such (user|mailbox|recipient)|does ?n[o']t exist|recipient ?not ?found|invalid (recipient|mailbox|address)|mailbox (unavailable|not found|disabled)|account (has been )?disabled|host (or domain name )?not found|domain not found|unrouteable`), receiver.BounceTypeHard},
+}
+
+// statusRules classify failures by enhanced status code (RFC 3463). The first rule
+// whose prefix matches wins.
+var statusRules = []struct {
+	prefix     string
+	bounceType receiver.BounceType
+}{
+	{"5.1.", receiver.BounceTypeHard},  // bad mailbox or domain
+	{"5.2.2", receiver.BounceTypeSoft}, // mailbox full
+	{"5.2.3", receiver.BounceTypeSoft}, // message too large
+	{"5.2.", receiver.BounceTypeHard},  // mailbox disabled
+	{"5.3.", receiver.BounceTypeSoft},  // mail system full or not accepting mail
+	{"5.4.7", receiver.BounceTypeSoft}, // gave up retrying
+	{"5.4.", receiver.BounceTypeHard},  // no route to the domain
+	{"5.5.", receiver.BounceTypeHard},  // protocol; commonly used for unknown mailboxes
+	{"5.6.", receiver.BounceTypeSoft},  // content
+	{"5.7.", receiver.BounceTypeSoft},  // security or policy, e.g. spam blocks
+	{"5.", receiver.BounceTypeHard},
+	{"4.", receiver.BounceTypeTransient},
+}
+
+var (
+	enhancedStatusPattern = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+	basicStatusPattern    = regexp.MustCompile(`(?:^|\s|#)([245])\d\d[\s-]`)
+)
+
+// Classify returns the bounce type of a recipient from its RFC 3464 action, enhanced
+// status code and diagnostic text.
+func Classify(action, status, diagnostic string) receiver.BounceType {
+	if strings.EqualFold(action, "delayed") {
+		return receiver.BounceTypeTransient
+	}
+	if status == "" {
+		status = statusFromText(diagnostic)
+	}
+	for _, rule := range diagnosticRules {
+		if rule.pattern.MatchString(diagnostic) {
+			// the sending server gave up, so a temporary failure is no longer temporary
+			if rule.bounceType == receiver.BounceTypeTransient && strings.HasPrefix(status, "5.") {
+				return receiver.BounceTypeSoft
+			}
+			return rule.bounceType
+		}
+	}
+	for _, rule := range statusRules {
+		if strings.HasPrefix(status, rule.prefix) {
+			return rule.bounceType
+		}
+	}
+	if strings.EqualFold(action, "failed") {
+		return receiver.BounceTypeHard
+	}
+	return receiver.BounceTypeSoft
+}
+
+// statusFromText extracts the enhanced status code from a server response, falling back
+// to the class of its basic reply code (550 becomes 5.0.0).
+func statusFromText(s string) string {
+	if m := enhancedStatusPattern.FindString(s); m != "" {
+		return m
+	}
+	if m := basicStatusPattern.FindStringSubmatch(s + " "); m != nil {
+		return m[1] + ".0.0"
+	}
+	return ""
+}
+
+// severity orders bounce types from the least to the most severe.
+func severity(t receiver.BounceType) int {
+	switch t {
+	case receiver.BounceTypeHard:
+		return 3
+	case receiver.BounceTypeSoft:
+		return 2
+	case receiver.BounceTypeTransient:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package bounce parses bounce messages into structured, classified recipient failures,
+// and feedback loop (ARF) messages into complaints.
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+var (
+	doubleMessageIdPattern = regexp.MustCompile("^<<(.+)>>$")
+	// deliveryHeaderPattern finds the delivery header in original headers quoted as text.
+	deliveryHeaderPattern = regexp.MustCompile(`(?mi)^` + regexp.QuoteMeta(mailer.HeadmailDeliveryHeaderName) + `:[ \t]*(\S+)`)
+)
+
+// Report is a parsed bounce or feedback message.
+type Report struct {
+	// DeliveryID is recovered from the headers of the returned original message.
+	DeliveryID   string
+	MessageID    string
+	Subject      string
+	ReportingMTA string
+	Recipients   []receiver.RecipientStatus
+	// Feedback is set for feedback loop reports, which have no recipients.
+	Feedback *Feedback
+	// Format names where the report was read from: arf, dsn, x-failed-recipients or
+	// one of the plain-text bounce formats such as postfix, exchange and gmail.
+	Format string
+	// ReturnedTo lists the addresses the report was sent to, from its To, Delivered-To
+	// and X-Original-To headers and the Original-Recipient fields of a DSN. With VERP
+	// one of them carries the delivery ID.
+	ReturnedTo []string
+
+	// originalRecipient is the To address of the returned original message.
+	originalRecipient string
+}
+
+// Parse reads a bounce or feedback message. RFC 5965 feedback reports are read from their
+// message/feedback-report part and RFC 3464 delivery status notifications from their
+// message/delivery-status part; other messages are matched against the plain-text bounce
+// formats of common servers. A message that is neither gives a report without
+// recipients or feedback.
+func Parse(r io.Reader) (*Report, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := message.Read(bytes.NewReader(raw))
+	if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+		return nil, err
+	}
+
+	report := &Report{}
+	h := mail.Header{Header: entity.Header}
+	report.Subject, _ = h.Subject()
+	report.MessageID, _ = h.MessageID()
+	if report.MessageID == "" {
+		if matches := doubleMessageIdPattern.FindStringSubmatch(h.Get("Message-Id")); len(matches) > 0 {
+			report.MessageID = matches[1]
+		}
+	}
+	for _, name := range []string{"Delivered-To", "X-Original-To", "Original-Recipient", "To"} {
+		for _, value := range h.Values(name) {
+			if addr := firstAddress(strings.TrimPrefix(value, "rfc822;")); addr != "" {
+				report.ReturnedTo = append(report.ReturnedTo, addr)
+			}
+		}
+	}
+
+	var texts []string
+	err = entity.Walk(func(path []int, part *message.Entity, err error) error {
+		if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+			return err
+		}
+		mediaType, _, _ := part.Header.ContentType()
+		switch strings.ToLower(mediaType) {
+		case "message/feedback-report":
+			return report.readFeedbackReport(part.Body)
+		case "message/delivery-status", "message/global-delivery-status":
+			return report.readDeliveryStatus(part.Body)
+		case "message/rfc822", "message/global", "text/rfc822-headers", "message/global-headers":
+			return report.readOriginalHeaders(part.Body)
+		case "text/plain", "":
+			body, err := io.ReadAll(part.Body)
+			if err != nil {
+				return err
+			}
+			texts = append(texts, string(body))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if report.DeliveryID == "" {
+		// plain-text bounces quote the original headers in the body
+		if m := deliveryHeaderPattern.FindSubmatch(raw); m != nil {
+			report.DeliveryID = string(m[1])
+		}
+	}
+	if report.Feedback != nil {
+		report.Format = "arf"
+		return report, nil
+	}
+	if len(report.Recipients) > 0 {
+		report.Format = "dsn"
+	} else {
+		report.Recipients, report.Format = parseText(texts)
+	}
+	if len(report.Recipients) == 0 {
+		// Exim lists failed recipients in a header
+		report.Format = "x-failed-recipients"
+		for _, addr := range strings.Split(h.Get("X-Failed-Recipients"), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				report.Recipients = append(report.Recipients, receiver.RecipientStatus{
+					Recipient:      addr,
+					Action:         "failed",
+					DiagnosticCode: findDiagnostic(texts),
+				})
+			}
+		}
+	}
+
+	if len(report.Recipients) == 0 {
+		report.Format = ""
+	}
+	for i := range report.Recipients {
+		rcpt := &report.Recipients[i]
+		if rcpt.Status == "" {
+			rcpt.Status = statusFromText(rcpt.DiagnosticCode)
+		}
+		rcpt.BounceType = Classify(rcpt.Action, rcpt.Status, rcpt.DiagnosticCode)
+	}
+	return report, nil
+}
+
+// readDeliveryStatus reads the per-message and per-recipient fields of a delivery status part.
+func (r *Report) readDeliveryStatus(body io.Reader) error {
+	br := bufio.NewReader(body)
+	perMessage := true
+	for {
+		header, err := textproto.ReadHeader(br)
+		if err != nil {
+			return nil
+		}
+		if header.Len() == 0 {
+			// skip extra blank lines between field groups
+			if _, err := br.Peek(1); err != nil {
+				return nil
+			}
+			continue
+		}
+		if perMessage {
+			r.ReportingMTA = typedValue(header.Get("Reporting-MTA"))
+			perMessage = false
+			continue
+		}
+
+		if original, err := parseAddress(header.Get("Original-Recipient")); err == nil {
+			r.ReturnedTo = append(r.ReturnedTo, original)
+		}
+		recipient, err := parseAddress(header.Get("Final-Recipient"))
+		if err != nil {
+			if recipient, err = parseAddress(header.Get("Original-Recipient")); err != nil {
+				continue
+			}
+		}
+		action := strings.ToLower(strings.TrimSpace(removeComments(header.Get("Action"))))
+		switch action {
+		case "delivered", "relayed", "expanded":
+			// success notifications are not bounces
+			continue
+		}
+		status, _, _ := strings.Cut(strings.TrimSpace(removeComments(header.Get("Status"))), " ")
+		r.Recipients = append(r.Recipients, receiver.RecipientStatus{
+			Recipient:      recipient,
+			Action:         action,
+			Status:         status,
+			DiagnosticCode: typedValue(header.Get("Diagnostic-Code")),
+			RemoteMTA:      typedValue(header.Get("Remote-MTA")),
+		})
+	}
+}
+
+// ResolveReturnPath sets the delivery ID of a bounce from the VERP address the report was
+// returned to. Unlike the delivery header of the returned original, which anyone can put
+// in a forged bounce, the VERP address is authenticated by its tag, so with a return path
+// configured the header is ignored: a bounce that was not returned to a valid VERP address
+// is left without a delivery ID. Feedback reports are not returned to the envelope sender
+// and keep the ID from the header.
+func (r *Report) ResolveReturnPath(returnPath *mailer.ReturnPath) {
+	if returnPath == nil || r.Feedback != nil {
+		return
+	}
+	r.DeliveryID = ""
+	for _, addr := range r.ReturnedTo {
+		if id := returnPath.DeliveryID(addr); id != "" {
+			r.DeliveryID = id
+			return
+		}
+	}
+}
+
+// readOriginalHeaders recovers the delivery ID from the returned original message.
+func (r *Report) readOriginalHeaders(body io.Reader) error {
+	header, err := textproto.ReadHeader(bufio.NewReader(body))
+	if err != nil {
+		// truncated originals are common; the body is searched as a fallback
+		return nil
+	}
+	if id := strings.TrimSpace(header.Get(mailer.HeadmailDeliveryHeaderName)); id != "" && r.DeliveryID == "" {
+		r.DeliveryID = id
+	}
+	if r.originalRecipient == "" {
+		r.originalRecipient = firstAddress(header.Get("To"))
+	}
+	return nil
+}
+
+// Event converts the report into a receiver event: a complaint for feedback reports, or a
+// bounce summarizing the most severe recipient failure. It returns nil when the report is
+// neither.
+func (r *Report) Event() *receiver.Event {
+	if r.Feedback != nil {
+		return r.complaintEvent()
+	}
+	if len(r.Recipients) == 0 {
+		return nil
+	}
+	worst := r.Recipients[0]
+	event := &receiver.Event{
+		Type:       receiver.EventTypeBounce,
+		DeliveryID: r.DeliveryID,
+		MessageID:  r.MessageID,
+		Subject:    r.Subject,
+		Recipients: r.Recipients,
+	}
+	for _, rcpt := range r.Recipients {
+		event.BouncedRecipients = append(event.BouncedRecipients, rcpt.Recipient)
+		if severity(rcpt.BounceType) > severity(worst.BounceType) {
+			worst = rcpt
+		}
+	}
+	event.BounceType = worst.BounceType
+	event.Status = worst.Status
+	event.DiagnosticCode = worst.DiagnosticCode
+	event.RemoteMTA = worst.RemoteMTA
+	event.Permanent = worst.BounceType == receiver.BounceTypeHard
+
+	event.Reason = string(worst.BounceType) + " bounce"
+	if worst.Status != "" {
+		event.Reason += " (" + worst.Status + ")"
+	}
+	if worst.DiagnosticCode != "" {
+		event.Reason += ": " + worst.DiagnosticCode
+	}
+	return event
+}
+
+// typedValue strips the type of a DSN field such as "smtp; 550 5.1.1 unknown" or
+// "dns; mx.example.com" and unfolds it.
+func typedValue(s string) string {
+	if _, value, ok := strings.Cut(s, ";"); ok {
+		s = value
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func parseAddress(s string) (string, error) {
+	s = removeComments(s)
+	t := strings.SplitN(s, ";", 2)
+	// ../rfc/3464:513 ../rfc/6533:250
+	addrType := strings.ToLower(strings.TrimSpace(t[0]))
+	if len(t) != 2 {
+		return "", fmt.Errorf("missing semicolon that splits address type and address")
+	} else if addrType != "rfc822" && addrType != "utf-8" {
+		return "", fmt.Errorf("unrecognized address type %q, expected rfc822", addrType)
+	}
+	return strings.Trim(strings.TrimSpace(t[1]), "<>"), nil
+}
+
+func removeComments(s string) string {
+	n := 0
+	r := ""
+	for _, c := range s {
+		if c == '(' {
+			n++
+		} else if c == ')' && n > 0 {
+			n--
+		} else if n == 0 {
+			r += string(c)
+		}
+	}
+	return r
+}
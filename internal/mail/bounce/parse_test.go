@@ -0,0 +1,290 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package bounce
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/headmail/headmail/pkg/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		format     string
+		deliveryID string
+		recipients []receiver.RecipientStatus
+	}{
+		{
+			fixture:    "dsn-hard.eml",
+			format:     "dsn",
+			deliveryID: "d-hard",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "nobody@example.com",
+				Action:         "failed",
+				Status:         "5.1.1",
+				DiagnosticCode: "550 5.1.1 <nobody@example.com>: Recipient address rejected: User unknown in virtual mailbox table",
+				RemoteMTA:      "mx.example.com",
+				BounceType:     receiver.BounceTypeHard,
+			}},
+		},
+		{
+			fixture:    "dsn-mailbox-full.eml",
+			format:     "dsn",
+			deliveryID: "d-full",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "full@example.com",
+				Action:         "failed",
+				Status:         "5.2.2",
+				DiagnosticCode: "552 5.2.2 Mailbox size limit exceeded",
+				RemoteMTA:      "mx.example.com",
+				BounceType:     receiver.BounceTypeSoft,
+			}},
+		},
+		{
+			fixture:    "dsn-delayed.eml",
+			format:     "dsn",
+			deliveryID: "d-delayed",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "slow@example.com",
+				Action:         "delayed",
+				Status:         "4.4.1",
+				DiagnosticCode: "connect to mx.example.com[203.0.113.9]:25: Connection timed out",
+				BounceType:     receiver.BounceTypeTransient,
+			}},
+		},
+		{
+			fixture:    "dsn-spam-block.eml",
+			format:     "dsn",
+			deliveryID: "d-spam",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "alice@example.net",
+				Action:         "failed",
+				Status:         "5.0.0",
+				DiagnosticCode: "554 Service unavailable; Client host [198.51.100.7] blocked using zen.spamhaus.org",
+				RemoteMTA:      "mx.example.net",
+				BounceType:     receiver.BounceTypeSoft,
+			}},
+		},
+		{
+			fixture:    "postfix-plain.eml",
+			format:     "postfix",
+			deliveryID: "d-plain",
+			recipients: []receiver.RecipientStatus{
+				{
+					Recipient:      "ghost@example.com",
+					Action:         "failed",
+					Status:         "5.1.1",
+					DiagnosticCode: "550 5.1.1 <ghost@example.com>... No such user (in reply to RCPT TO command)",
+					RemoteMTA:      "mx1.example.com",
+					BounceType:     receiver.BounceTypeHard,
+				},
+				{
+					Recipient:      "typo@exmaple.com",
+					Action:         "failed",
+					DiagnosticCode: "Host or domain name not found. Name service error for name=exmaple.com type=A: Host not found",
+					BounceType:     receiver.BounceTypeHard,
+				},
+			},
+		},
+		{
+			fixture:    "exchange-ndr.eml",
+			format:     "exchange",
+			deliveryID: "d-exchange",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "carol@corp.example.com",
+				Action:         "failed",
+				Status:         "5.1.10",
+				DiagnosticCode: "550 5.1.10 RESOLVER.ADR.RecipientNotFound; Recipient not found by SMTP address lookup",
+				BounceType:     receiver.BounceTypeHard,
+			}},
+		},
+		{
+			fixture:    "exchange-legacy.eml",
+			format:     "exchange",
+			deliveryID: "d-legacy",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "dave@legacy.example.com",
+				Action:         "failed",
+				Status:         "5.2.2",
+				DiagnosticCode: "552 5.2.2 STOREDRV.Deliver; mailbox full",
+				BounceType:     receiver.BounceTypeSoft,
+			}},
+		},
+		{
+			fixture:    "gmail-not-found.eml",
+			format:     "gmail",
+			deliveryID: "d-gmail",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "erin@example.com",
+				Action:         "failed",
+				Status:         "5.1.1",
+				DiagnosticCode: "550 5.1.1 The email account that you tried to reach does not exist. Please try double-checking the recipient's email address for typos or unnecessary spaces.",
+				BounceType:     receiver.BounceTypeHard,
+			}},
+		},
+		{
+			fixture:    "gmail-delayed.eml",
+			format:     "gmail",
+			deliveryID: "d-gmail-delay",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "frank@example.com",
+				Action:         "delayed",
+				Status:         "4.7.0",
+				DiagnosticCode: "421 4.7.0 Try again later, closing connection.",
+				BounceType:     receiver.BounceTypeTransient,
+			}},
+		},
+		{
+			fixture:    "gmail-legacy.eml",
+			format:     "gmail",
+			deliveryID: "d-gmail-legacy",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "grace@example.com",
+				Action:         "failed",
+				Status:         "5.2.1",
+				DiagnosticCode: "550 5.2.1 The email account that you tried to reach is disabled.",
+				RemoteMTA:      "mx.example.com",
+				BounceType:     receiver.BounceTypeHard,
+			}},
+		},
+		{
+			fixture:    "exim-failed-recipients.eml",
+			format:     "x-failed-recipients",
+			deliveryID: "d-exim",
+			recipients: []receiver.RecipientStatus{{
+				Recipient:      "heidi@example.com",
+				Action:         "failed",
+				Status:         "5.1.1",
+				DiagnosticCode: "550 5.1.1 user unknown",
+				BounceType:     receiver.BounceTypeHard,
+			}},
+		},
+		{
+			fixture: "not-a-bounce.eml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			require.NoError(t, err)
+			defer f.Close()
+
+			report, err := Parse(f)
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, report.Format)
+			assert.Equal(t, tt.recipients, report.Recipients)
+			if tt.recipients == nil {
+				assert.Nil(t, report.Event())
+				return
+			}
+			assert.Equal(t, tt.deliveryID, report.DeliveryID)
+		})
+	}
+}
+
+func TestReport_Event(t *testing.T) {
+	report := &Report{
+		DeliveryID: "d-1",
+		Recipients: []receiver.RecipientStatus{
+			{Recipient: "a@example.com", Action: "delayed", Status: "4.4.1", BounceType: receiver.BounceTypeTransient},
+			{Recipient: "b@example.com", Action: "failed", Status: "5.1.1", DiagnosticCode: "550 5.1.1 user unknown", BounceType: receiver.BounceTypeHard},
+		},
+	}
+	event := report.Event()
+	require.NotNil(t, event)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, event.BouncedRecipients)
+	assert.Equal(t, receiver.BounceTypeHard, event.BounceType)
+	assert.True(t, event.Permanent)
+	assert.Equal(t, "5.1.1", event.Status)
+	assert.Equal(t, "hard bounce (5.1.1): 550 5.1.1 user unknown", event.Reason)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		action, status, diagnostic string
+		want                       receiver.BounceType
+	}{
+		{"failed", "5.1.1", "", receiver.BounceTypeHard},
+		{"failed", "5.7.1", "", receiver.BounceTypeSoft},
+		{"failed", "5.5.0", "mailbox full", receiver.BounceTypeSoft},
+		{"failed", "", "550 Requested action not taken: mailbox unavailable", receiver.BounceTypeHard},
+		{"failed", "5.0.0", "451 temporary local problem, try again later", receiver.BounceTypeSoft},
+		{"failed", "", "451 4.3.0 temporary local problem", receiver.BounceTypeTransient},
+		{"delayed", "5.1.1", "", receiver.BounceTypeTransient},
+		{"failed", "", "", receiver.BounceTypeHard},
+		{"", "", "", receiver.BounceTypeSoft},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Classify(tt.action, tt.status, tt.diagnostic), "%s %s %q", tt.action, tt.status, tt.diagnostic)
+	}
+}
+
+func TestReport_ResolveReturnPath(t *testing.T) {
+	key, err := signing.GenerateKey()
+	require.NoError(t, err)
+	keyring, err := signing.NewKeyring(key)
+	require.NoError(t, err)
+	returnPath, err := mailer.NewReturnPath("bounce@bounces.example.org", keyring)
+	require.NoError(t, err)
+
+	// the original is truncated before the delivery header
+	msg := "From: MAILER-DAEMON@mail.example.org\r\n" +
+		"To: " + returnPath.Address("d-verp") + "\r\n" +
+		"Subject: Undelivered Mail Returned to Sender\r\n" +
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: message/delivery-status\r\n" +
+		"\r\n" +
+		"Reporting-MTA: dns; mail.example.org\r\n" +
+		"\r\n" +
+		"Final-Recipient: rfc822; nobody@example.com\r\n" +
+		"Action: failed\r\n" +
+		"Status: 5.1.1\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/rfc822-headers\r\n" +
+		"\r\n" +
+		"Subject: Newsletter\r\n" +
+		"\r\n" +
+		"--b--\r\n"
+
+	report, err := Parse(strings.NewReader(msg))
+	require.NoError(t, err)
+	assert.Empty(t, report.DeliveryID)
+	report.ResolveReturnPath(nil)
+	assert.Empty(t, report.DeliveryID)
+	report.ResolveReturnPath(returnPath)
+	assert.Equal(t, "d-verp", report.DeliveryID)
+
+	// a forged return path is ignored
+	forged := strings.Replace(msg, "d-verp", "d-other", 1)
+	report, err = Parse(strings.NewReader(forged))
+	require.NoError(t, err)
+	report.ResolveReturnPath(returnPath)
+	assert.Empty(t, report.DeliveryID)
+
+	// the delivery header of the original does not override the VERP address, and is
+	// not trusted on its own once a return path is configured
+	withHeader := strings.Replace(msg, "Subject: Newsletter\r\n", "Subject: Newsletter\r\n"+mailer.HeadmailDeliveryHeaderName+": d-header\r\n", 1)
+	report, err = Parse(strings.NewReader(withHeader))
+	require.NoError(t, err)
+	assert.Equal(t, "d-header", report.DeliveryID)
+	report.ResolveReturnPath(returnPath)
+	assert.Equal(t, "d-verp", report.DeliveryID)
+
+	report, err = Parse(strings.NewReader(strings.Replace(withHeader, "d-verp", "d-other", 1)))
+	require.NoError(t, err)
+	report.ResolveReturnPath(returnPath)
+	assert.Empty(t, report.DeliveryID)
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package bounce
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// textFormats parse the human-readable bounces of servers that send no delivery status
+// part, or whose delivery status lacks the diagnostic. They are tried in order and the
+// first format that finds a recipient wins.
+var textFormats = []struct {
+	name  string
+	parse func(text string) []receiver.RecipientStatus
+}{
+	{"postfix", parsePostfix},
+	{"exchange", parseExchange},
+	{"gmail", parseGmail},
+}
+
+var (
+	// <nobody@example.com>: host mx.example.com[203.0.113.5] said: 550 5.1.1
+	//     <nobody@example.com>: Recipient address rejected: User unknown (in reply to RCPT TO command)
+	postfixRecipientPattern = regexp.MustCompile(`(?m)^<([^<>\s]+@[^<>\s]+)>:[ \t]*(.*(?:\r?\n[ \t]+\S.*)*)`)
+	postfixHostPattern      = regexp.MustCompile(`^host (\S+?)\[[^\]]*\] said:\s*`)
+
+	// nobody@example.com
+	// Remote Server returned '550 5.1.1 RESOLVER.ADR.RecipientNotFound; not found'
+	// or, from older servers,
+	// nobody@example.com
+	// #550 5.1.1 RESOLVER.ADR.RecipientNotFound; not found ##
+	exchangeRecipientPattern = regexp.MustCompile(`(?mi)^[ \t]*<?([^\s<>@]+@[^\s<>@]+?)>?[ \t]*\r?\n[ \t]*(?:Remote Server(?: at (\S+)[^'\r\n]*)? returned '([^'\r\n]*)'|#([^\r\n]*?)[ \t]*##)`)
+
+	gmailRecipientPattern  = regexp.MustCompile(`(?i)(?:wasn't delivered to|delivering your message to|following recipient failed permanently:)\s+<?([^\s<>]+@[^\s<>]*[A-Za-z0-9])`)
+	gmailDiagnosticPattern = regexp.MustCompile(`(?i)(?:the response (?:from the remote server )?was|the error that the other server returned was):[ \t]*\r?\n((?:[ \t]*\S.*(?:\r?\n|$))+)`)
+	gmailRemotePattern     = regexp.MustCompile(`(?i)for the recipient domain \S+ by (\S+?)\.? \[`)
+	gmailRetryPattern      = regexp.MustCompile(`(?i)will retry`)
+
+	smtpErrorPattern = regexp.MustCompile(`(?m)^[ \t#]*([45]\d\d[ -][^\r\n]*)`)
+)
+
+// parseText returns the recipient failures of the first text part in a known format,
+// and the name of the format.
+func parseText(texts []string) ([]receiver.RecipientStatus, string) {
+	for _, text := range texts {
+		for _, format := range textFormats {
+			if recipients := format.parse(text); len(recipients) > 0 {
+				return recipients, format.name
+			}
+		}
+	}
+	return nil, ""
+}
+
+func parsePostfix(text string) []receiver.RecipientStatus {
+	var recipients []receiver.RecipientStatus
+	for _, m := range postfixRecipientPattern.FindAllStringSubmatch(text, -1) {
+		rcpt := receiver.RecipientStatus{
+			Recipient:      m[1],
+			Action:         "failed",
+			DiagnosticCode: strings.Join(strings.Fields(m[2]), " "),
+		}
+		if host := postfixHostPattern.FindStringSubmatch(rcpt.DiagnosticCode); host != nil {
+			rcpt.RemoteMTA = host[1]
+			rcpt.DiagnosticCode = rcpt.DiagnosticCode[len(host[0]):]
+		}
+		recipients = append(recipients, rcpt)
+	}
+	return recipients
+}
+
+func parseExchange(text string) []receiver.RecipientStatus {
+	var recipients []receiver.RecipientStatus
+	for _, m := range exchangeRecipientPattern.FindAllStringSubmatch(text, -1) {
+		diagnostic := m[3]
+		if diagnostic == "" {
+			diagnostic = m[4]
+		}
+		recipients = append(recipients, receiver.RecipientStatus{
+			Recipient:      m[1],
+			Action:         "failed",
+			DiagnosticCode: strings.TrimSpace(diagnostic),
+			RemoteMTA:      m[2],
+		})
+	}
+	return recipients
+}
+
+func parseGmail(text string) []receiver.RecipientStatus {
+	m := gmailRecipientPattern.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	rcpt := receiver.RecipientStatus{
+		Recipient: m[1],
+		Action:    "failed",
+	}
+	if gmailRetryPattern.MatchString(text) {
+		rcpt.Action = "delayed"
+	}
+	if d := gmailDiagnosticPattern.FindStringSubmatch(text); d != nil {
+		rcpt.DiagnosticCode = strings.Join(strings.Fields(d[1]), " ")
+	}
+	if remote := gmailRemotePattern.FindStringSubmatch(text); remote != nil {
+		rcpt.RemoteMTA = remote[1]
+	}
+	return []receiver.RecipientStatus{rcpt}
+}
+
+// findDiagnostic returns the first SMTP error reply quoted in the texts.
+func findDiagnostic(texts []string) string {
+	for _, text := range texts {
+		if m := smtpErrorPattern.FindStringSubmatch(text); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+	}
+	return ""
+}
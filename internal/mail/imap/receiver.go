@@ -4,20 +4,15 @@
 package imap
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"log"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/emersion/go-imap"
 	imapclient "github.com/emersion/go-imap/client"
-	"github.com/emersion/go-message"
-	"github.com/emersion/go-message/mail"
-	"github.com/emersion/go-message/textproto"
+	"github.com/headmail/headmail/internal/mail/bounce"
 	"github.com/headmail/headmail/pkg/config"
 	"github.com/headmail/headmail/pkg/mailer"
 	"github.com/headmail/headmail/pkg/receiver"
@@ -32,9 +27,11 @@ type Receiver struct {
 
 	cancelCtx context.CancelFunc
 	doneCh    chan struct{}
-}
 
-var doubleMessageIdPattern = regexp.MustCompile("^<<(.+)>>$")
+	// ReturnPath, if set, decodes delivery IDs from VERP addresses of bounces whose
+	// returned message lacks the delivery header.
+	ReturnPath *mailer.ReturnPath
+}
 
 // NewReceiver creates a new Receiver.
 // cfg is the SMTP.Receive section from application config.
@@ -216,111 +213,16 @@ func (r *Receiver) pollOnce(ctx context.Context, c *imapclient.Client, eventCh c
 	return nil
 }
 
-// processMessage parses a single message body and creates DeliveryEvent records if bounce found.
+// processMessage parses a single message body and emits an event if it is a bounce or
+// a feedback loop complaint.
 func (r *Receiver) processMessage(ctx context.Context, body io.Reader, eventCh chan *receiver.Event) error {
-	event := &receiver.Event{}
-
-	// parse with go-message mail reader
-	mr, err := mail.CreateReader(body)
+	report, err := bounce.Parse(body)
 	if err != nil {
-		// sometimes the body may include extra data; try buffered parse
-		br := bufio.NewReader(body)
-		mr2, err2 := mail.CreateReader(br)
-		if err2 != nil {
-			return err
-		}
-		mr = mr2
-	}
-
-	// Collect top-level headers
-	h := mr.Header
-	event.Subject, _ = h.Subject()
-	event.MessageID, _ = h.MessageID()
-	if event.MessageID == "" {
-		if matches := doubleMessageIdPattern.FindStringSubmatch(h.Get("Message-Id")); len(matches) > 0 {
-			event.MessageID = matches[1]
-		}
-	}
-
-	var deliveryStatusBody mail.Header
-	var sentEntity *message.Entity
-
-	for {
-		p, err := mr.NextPart()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		switch p.Header.(type) {
-		case *mail.AttachmentHeader:
-			contentType := p.Header.Get("Content-Type")
-			if strings.HasPrefix(contentType, "message/delivery-status") {
-				r := bufio.NewReader(p.Body)
-				for {
-					header, err := textproto.ReadHeader(r)
-					if err != nil || header.Len() == 0 {
-						break
-					}
-					for k, vs := range header.Map() {
-						for _, v := range vs {
-							deliveryStatusBody.Add(k, v)
-						}
-					}
-				}
-			} else if strings.HasPrefix(contentType, "message/rfc822") {
-				sentEntity, err = message.Read(p.Body)
-				if err != nil {
-					return err
-				}
-			} else {
-				_, _ = io.Copy(io.Discard, p.Body)
-			}
-		default:
-			_, _ = io.Copy(io.Discard, p.Body)
-		}
-	}
-
-	if deliveryStatusBody.Len() == 0 || sentEntity == nil {
-		return nil
-	}
-
-	// Heuristics: check for known bounce headers or markers
-	// 1) Final-Recipient header
-	// 2) Action: failed
-	// 3) X-Failed-Recipients header
-	// event.Reason
-
-	event.DeliveryID = sentEntity.Header.Get(mailer.HeadmailDeliveryHeaderName)
-
-	// header checks (best-effort)
-	if val, err := deliveryStatusBody.Text("Final-Recipient"); err == nil && len(val) > 0 {
-		parsed, err := parseAddress(val)
-		if err == nil {
-			event.BouncedRecipients = append(event.BouncedRecipients, parsed)
-		}
-	}
-	// X-Failed-Recipients custom header (may be present)
-	if xvals, err := deliveryStatusBody.Text("X-Failed-Recipients"); err == nil && len(xvals) > 0 {
-		for _, line := range xvals {
-			sline := fmt.Sprint(line)
-			for _, addr := range strings.Split(sline, ",") {
-				addr = strings.TrimSpace(addr)
-				if addr != "" {
-					event.BouncedRecipients = append(event.BouncedRecipients, addr)
-				}
-			}
-		}
-	}
-	if val, err := deliveryStatusBody.Text("Status"); err == nil && strings.HasPrefix(val, "5.") {
-		event.Reason = "permanent failure (" + val + ")"
-	} else if val, err := deliveryStatusBody.Text("Action"); err == nil && val == "failed" {
-		event.Reason = "permanent failure"
+		return err
 	}
-
-	// If still nothing that looks like a bounce, skip
-	if len(event.BouncedRecipients) == 0 && event.Reason == "" {
+	report.ResolveReturnPath(r.ReturnPath)
+	event := report.Event()
+	if event == nil {
 		// not identified as bounce
 		return nil
 	}
@@ -329,7 +231,11 @@ func (r *Receiver) processMessage(ctx context.Context, body io.Reader, eventCh c
 		return nil
 	}
 
-	eventCh <- event
+	select {
+	case eventCh <- event:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
 	return nil
 }
@@ -0,0 +1,245 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/headmail/headmail/internal/mail/bounce"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/receiver"
+	"golang.org/x/net/netutil"
+)
+
+// shutdownTimeout is how long open sessions are waited for when the context of Start ends.
+const shutdownTimeout = 10 * time.Second
+
+var (
+	errRelayDenied = &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "Relay access denied",
+	}
+	errInvalidMessage = &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 6, 0},
+		Message:      "Message could not be parsed",
+	}
+	errShuttingDown = &gosmtp.SMTPError{
+		Code:         421,
+		EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+		Message:      "Service shutting down, try again later",
+	}
+)
+
+// Receiver is an embedded SMTP server that accepts bounces and feedback loop reports
+// sent to the bounce domain and records them like the IMAP receiver does.
+type Receiver struct {
+	cfg     config.SMTPReceiveConfig
+	domains map[string]bool
+	server  *gosmtp.Server
+
+	// ReturnPath, if set, decodes delivery IDs from the VERP addresses mail is sent to.
+	ReturnPath *mailer.ReturnPath
+
+	listener net.Listener
+
+	mu      sync.RWMutex
+	running bool
+	events  chan *receiver.Event
+	done    chan struct{}
+	stop    *sync.Once
+}
+
+// NewReceiver creates a Receiver from the SMTP.Receive section of cfg. Mail is accepted
+// for the configured domains, or for the domain of the return path.
+func NewReceiver(cfg *config.SMTPConfig) (*Receiver, error) {
+	domains := cfg.Receive.Domains
+	if len(domains) == 0 && cfg.ReturnPath != "" {
+		domains = []string{cfg.ReturnPath[strings.LastIndexByte(cfg.ReturnPath, '@')+1:]}
+	}
+	if len(domains) == 0 {
+		return nil, errors.New("smtp.receive.domains is required without smtp.return_path")
+	}
+	r := &Receiver{
+		cfg:     cfg.Receive,
+		domains: make(map[string]bool, len(domains)),
+	}
+	for _, d := range domains {
+		r.domains[strings.ToLower(d)] = true
+	}
+
+	s := gosmtp.NewServer(gosmtp.BackendFunc(r.newSession))
+	s.Domain = cfg.Receive.Hostname
+	if s.Domain == "" {
+		s.Domain = domains[0]
+	}
+	s.MaxMessageBytes = cfg.Receive.MaxMessageBytes
+	s.MaxRecipients = cfg.Receive.MaxRecipients
+	s.ReadTimeout = cfg.Receive.Timeout
+	s.WriteTimeout = cfg.Receive.Timeout
+	if cfg.Receive.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Receive.TLSCertFile, cfg.Receive.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("smtp receive tls: %w", err)
+		}
+		s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	r.server = s
+	return r, nil
+}
+
+// Start listens on the configured address and serves in background.
+func (r *Receiver) Start(ctx context.Context) (chan *receiver.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return nil, errors.New("smtp receiver already started")
+	}
+	l, err := net.Listen("tcp", r.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.MaxConnections > 0 {
+		l = netutil.LimitListener(l, r.cfg.MaxConnections)
+	}
+	r.listener = l
+	r.running = true
+	r.events = make(chan *receiver.Event, 1)
+	r.done = make(chan struct{})
+	r.stop = &sync.Once{}
+
+	go func() {
+		log.Printf("Starting smtp receiver on %s", l.Addr())
+		if err := r.server.Serve(l); err != nil && !errors.Is(err, gosmtp.ErrServerClosed) {
+			log.Printf("smtp receiver: serve failed: %v", err)
+		}
+	}()
+	done := r.done
+	go func() {
+		select {
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			_ = r.Stop(stopCtx)
+		case <-done:
+		}
+	}()
+	return r.events, nil
+}
+
+// Stop closes the listener, waits for open sessions until ctx expires and closes the
+// event channel.
+func (r *Receiver) Stop(ctx context.Context) error {
+	r.mu.RLock()
+	running, done, stop := r.running, r.done, r.stop
+	r.mu.RUnlock()
+	if !running {
+		return nil
+	}
+	// sessions waiting to hand over events give up with a temporary error
+	stop.Do(func() { close(done) })
+	err := r.server.Shutdown(ctx)
+	if err != nil {
+		_ = r.server.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		r.running = false
+		close(r.events)
+	}
+	if errors.Is(err, gosmtp.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (r *Receiver) newSession(c *gosmtp.Conn) (gosmtp.Session, error) {
+	return &session{receiver: r, remote: c.Conn().RemoteAddr().String()}, nil
+}
+
+// emit hands the event over to the consumer of the event channel.
+func (r *Receiver) emit(event *receiver.Event) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.running {
+		return errShuttingDown
+	}
+	select {
+	case r.events <- event:
+		return nil
+	case <-r.done:
+		return errShuttingDown
+	}
+}
+
+// session is one SMTP connection. Bounces are sent with a null sender, so any sender
+// is accepted; recipients must be in an accepted domain.
+type session struct {
+	receiver *Receiver
+	remote   string
+	rcpts    []string
+}
+
+func (s *session) Reset() {
+	s.rcpts = nil
+}
+
+func (s *session) Logout() error {
+	return nil
+}
+
+func (s *session) Mail(from string, opts *gosmtp.MailOptions) error {
+	return nil
+}
+
+func (s *session) Rcpt(to string, opts *gosmtp.RcptOptions) error {
+	at := strings.LastIndexByte(to, '@')
+	if at < 0 || !s.receiver.domains[strings.ToLower(to[at+1:])] {
+		return errRelayDenied
+	}
+	s.rcpts = append(s.rcpts, to)
+	return nil
+}
+
+// Data parses the message and emits an event if it is a bounce or a feedback loop
+// complaint. Other mail, e.g. replies, is accepted and dropped.
+func (s *session) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	report, err := bounce.Parse(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("smtp receiver: parse message from %s: %v", s.remote, err)
+		return errInvalidMessage
+	}
+	// the envelope recipient is the VERP address even when headers do not show it
+	report.ReturnedTo = append(append([]string{}, s.rcpts...), report.ReturnedTo...)
+	report.ResolveReturnPath(s.receiver.ReturnPath)
+	event := report.Event()
+	if event == nil {
+		// not identified as bounce
+		return nil
+	}
+	if event.DeliveryID == "" {
+		log.Printf("unknown delivery id: %+v", event)
+		return nil
+	}
+	return s.receiver.emit(event)
+}
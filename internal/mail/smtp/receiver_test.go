@@ -0,0 +1,125 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package smtp
+
+import (
+	"context"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/headmail/headmail/pkg/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bounceMessage is a DSN whose returned message was stripped, so only the VERP address
+// identifies the delivery.
+const bounceMessage = "From: MAILER-DAEMON@mail.example.org\r\n" +
+	"To: bounce@bounces.example.com\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mail.example.org\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; nobody@example.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"\r\n" +
+	"--b--\r\n"
+
+func sendMessage(addr, to, msg string) error {
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.Mail(""); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func TestReceiver(t *testing.T) {
+	key, err := signing.GenerateKey()
+	require.NoError(t, err)
+	keyring, err := signing.NewKeyring(key)
+	require.NoError(t, err)
+	returnPath, err := mailer.NewReturnPath("bounce@bounces.example.com", keyring)
+	require.NoError(t, err)
+
+	cfg := config.SMTPConfig{ReturnPath: "bounce@bounces.example.com"}
+	cfg.Receive.Addr = "127.0.0.1:0"
+	cfg.Receive.MaxMessageBytes = 4096
+	cfg.Receive.MaxConnections = 2
+	r, err := NewReceiver(&cfg)
+	require.NoError(t, err)
+	r.ReturnPath = returnPath
+	events, err := r.Start(context.Background())
+	require.NoError(t, err)
+	addr := r.listener.Addr().String()
+
+	// the delivery is taken from the envelope recipient
+	require.NoError(t, sendMessage(addr, returnPath.Address("d-smtp"), bounceMessage))
+	select {
+	case event := <-events:
+		assert.Equal(t, "d-smtp", event.DeliveryID)
+		assert.Equal(t, receiver.BounceTypeHard, event.BounceType)
+		assert.Equal(t, []string{"nobody@example.com"}, event.BouncedRecipients)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	// mail that is not a report is accepted and dropped
+	require.NoError(t, sendMessage(addr, "bounce@bounces.example.com", "Subject: Re: Newsletter\r\n\r\nThanks!\r\n"))
+
+	// other domains are not relayed
+	err = sendMessage(addr, "someone@example.net", bounceMessage)
+	var smtpErr *textproto.Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, 550, smtpErr.Code)
+
+	// oversized messages are rejected
+	err = sendMessage(addr, returnPath.Address("d-big"), bounceMessage+strings.Repeat("padding\r\n", 1024))
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, 552, smtpErr.Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, r.Stop(ctx))
+	_, ok := <-events
+	assert.False(t, ok, "no further events after stop")
+}
+
+func TestNewReceiver_RequiresDomain(t *testing.T) {
+	cfg := config.SMTPConfig{}
+	cfg.Receive.Addr = ":0"
+	_, err := NewReceiver(&cfg)
+	assert.Error(t, err)
+
+	cfg.Receive.Domains = []string{"bounces.example.com"}
+	_, err = NewReceiver(&cfg)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/headmail/headmail/internal/mail/bounce"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// mailgunPayload is a Mailgun webhook request.
+type mailgunPayload struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		ID        string `json:"id"`
+		Event     string `json:"event"`
+		Severity  string `json:"severity"`
+		Recipient string `json:"recipient"`
+		Reason    string `json:"reason"`
+		Delivery  struct {
+			Code         int    `json:"code"`
+			EnhancedCode string `json:"enhanced-code"`
+			Message      string `json:"message"`
+			Description  string `json:"description"`
+			MXHost       string `json:"mx-host"`
+		} `json:"delivery-status"`
+		Message struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+				Subject   string `json:"subject"`
+			} `json:"headers"`
+		} `json:"message"`
+	} `json:"event-data"`
+}
+
+// mailgunProvider receives Mailgun webhooks, which are signed with an HMAC of the
+// timestamp and a random token.
+type mailgunProvider struct {
+	receiver   *Receiver
+	signingKey []byte
+}
+
+func (p *mailgunProvider) parse(r *http.Request, body []byte) ([]notification, error) {
+	var payload mailgunPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid mailgun payload: %w", err)
+	}
+	sig := payload.Signature
+	mac := hmac.New(sha256.New, p.signingKey)
+	mac.Write([]byte(sig.Timestamp + sig.Token))
+	expected, err := hex.DecodeString(sig.Signature)
+	if err != nil || sig.Token == "" || !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, errUnauthorized
+	}
+	seconds, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil {
+		return nil, errUnauthorized
+	}
+	if err := p.receiver.checkTimestamp(time.Unix(seconds, 0)); err != nil {
+		return nil, err
+	}
+
+	event := mailgunToEvent(&payload)
+	if event == nil {
+		return nil, nil
+	}
+	// a replayed request repeats the token, a redelivered event its ID
+	id := payload.EventData.ID
+	if id == "" {
+		id = sig.Token
+	}
+	return []notification{{ID: id, Event: event}}, nil
+}
+
+// mailgunToEvent converts a Mailgun event into an event, or returns nil for events
+// other than failures and complaints.
+func mailgunToEvent(payload *mailgunPayload) *receiver.Event {
+	data := &payload.EventData
+	messageID := data.Message.Headers.MessageID
+	deliveryID := deliveryIDFromMessageID(messageID)
+	subject := data.Message.Headers.Subject
+	switch data.Event {
+	case "failed":
+		diagnostic := data.Delivery.Message
+		if diagnostic == "" {
+			diagnostic = data.Delivery.Description
+		}
+		if diagnostic != "" && data.Delivery.Code != 0 {
+			diagnostic = strconv.Itoa(data.Delivery.Code) + " " + diagnostic
+		}
+		rcpt := receiver.RecipientStatus{
+			Recipient:      data.Recipient,
+			Action:         "failed",
+			Status:         data.Delivery.EnhancedCode,
+			DiagnosticCode: diagnostic,
+			RemoteMTA:      data.Delivery.MXHost,
+		}
+		if data.Severity == "temporary" {
+			rcpt.Action = "delayed"
+		}
+		rcpt.BounceType = bounce.Classify(rcpt.Action, rcpt.Status, rcpt.DiagnosticCode)
+		return bounceEvent(deliveryID, messageID, subject, []receiver.RecipientStatus{rcpt})
+	case "complained":
+		return complaintEvent(deliveryID, messageID, subject, data.Recipient, "", "")
+	default:
+		return nil
+	}
+}
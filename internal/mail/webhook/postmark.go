@@ -0,0 +1,102 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// PostmarkMetadataHeader is the header that carries the delivery ID to Postmark, which
+// returns it as metadata in its webhooks.
+const PostmarkMetadataHeader = "X-PM-Metadata-" + postmarkMetadataKey
+
+const postmarkMetadataKey = "headmail-delivery"
+
+// postmarkBounceTypes maps Postmark bounce types to bounce types. Types that are not
+// failures, e.g. auto responders, are not listed.
+var postmarkBounceTypes = map[string]receiver.BounceType{
+	"HardBounce":          receiver.BounceTypeHard,
+	"BadEmailAddress":     receiver.BounceTypeHard,
+	"ManuallyDeactivated": receiver.BounceTypeHard,
+	"SoftBounce":          receiver.BounceTypeSoft,
+	"DnsError":            receiver.BounceTypeSoft,
+	"Blocked":             receiver.BounceTypeSoft,
+	"SpamNotification":    receiver.BounceTypeSoft,
+	"ContentRelated":      receiver.BounceTypeSoft,
+	"Transient":           receiver.BounceTypeTransient,
+}
+
+// postmarkPayload is a Postmark bounce or spam complaint webhook request.
+type postmarkPayload struct {
+	RecordType  string            `json:"RecordType"`
+	ID          int64             `json:"ID"`
+	Type        string            `json:"Type"`
+	Email       string            `json:"Email"`
+	Subject     string            `json:"Subject"`
+	Description string            `json:"Description"`
+	Details     string            `json:"Details"`
+	Metadata    map[string]string `json:"Metadata"`
+}
+
+// postmarkProvider receives Postmark webhooks. Postmark does not sign its requests,
+// so they are authenticated with basic auth credentials embedded in the webhook URL.
+type postmarkProvider struct {
+	username string
+	password string
+}
+
+func (p *postmarkProvider) parse(r *http.Request, body []byte) ([]notification, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(p.username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(p.password)) != 1 {
+		return nil, errUnauthorized
+	}
+
+	var payload postmarkPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid postmark payload: %w", err)
+	}
+	event := postmarkToEvent(&payload)
+	if event == nil {
+		return nil, nil
+	}
+	return []notification{{ID: payload.RecordType + ":" + strconv.FormatInt(payload.ID, 10), Event: event}}, nil
+}
+
+// postmarkToEvent converts a Postmark record into an event, or returns nil for records
+// other than bounces and spam complaints.
+func postmarkToEvent(payload *postmarkPayload) *receiver.Event {
+	deliveryID := payload.Metadata[postmarkMetadataKey]
+	switch payload.RecordType {
+	case "Bounce":
+		bounceType, ok := postmarkBounceTypes[payload.Type]
+		if !ok {
+			return nil
+		}
+		rcpt := receiver.RecipientStatus{
+			Recipient:      payload.Email,
+			Action:         "failed",
+			DiagnosticCode: payload.Details,
+			BounceType:     bounceType,
+		}
+		if rcpt.DiagnosticCode == "" {
+			rcpt.DiagnosticCode = payload.Description
+		}
+		if bounceType == receiver.BounceTypeTransient {
+			rcpt.Action = "delayed"
+		}
+		return bounceEvent(deliveryID, "", payload.Subject, []receiver.RecipientStatus{rcpt})
+	case "SpamComplaint":
+		return complaintEvent(deliveryID, "", payload.Subject, payload.Email, "", "")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultReplayWindow is how long event IDs are remembered without a tolerance.
+const defaultReplayWindow = time.Hour
+
+// replayCache remembers the IDs of received events for a window, so events that are
+// replayed or redelivered within it are dropped. Older requests are rejected by their
+// timestamp instead.
+type replayCache struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newReplayCache(window time.Duration) *replayCache {
+	if window <= 0 {
+		window = defaultReplayWindow
+	}
+	return &replayCache{
+		window: window,
+		seen:   map[string]time.Time{},
+	}
+}
+
+// add records id and reports whether it was not seen within the window.
+func (c *replayCache) add(id string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastPrune) > c.window {
+		for k, t := range c.seen {
+			if now.Sub(t) > c.window {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPrune = now
+	}
+	if t, ok := c.seen[id]; ok && now.Sub(t) <= c.window {
+		return false
+	}
+	c.seen[id] = now
+	return true
+}
+
+// remove forgets id, e.g. when its event could not be handed over and will be retried.
+func (c *replayCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, id)
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/headmail/headmail/internal/mail/bounce"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+const (
+	sendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	sendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// sendGridEvent is one event of a SendGrid event webhook request.
+type sendGridEvent struct {
+	ID     string `json:"sg_event_id"`
+	Event  string `json:"event"`
+	Email  string `json:"email"`
+	SMTPID string `json:"smtp-id"`
+	// Type is "bounce" or "blocked" for bounce events.
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	// Response is the reason of deferred events.
+	Response string `json:"response"`
+}
+
+// sendGridProvider receives SendGrid signed event webhooks, which are signed with
+// ECDSA over the timestamp header and the body.
+type sendGridProvider struct {
+	receiver  *Receiver
+	publicKey *ecdsa.PublicKey
+}
+
+func newSendGridProvider(r *Receiver, publicKey string) (*sendGridProvider, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sendgrid public key: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sendgrid public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid sendgrid public key: not an ECDSA key")
+	}
+	return &sendGridProvider{receiver: r, publicKey: ecKey}, nil
+}
+
+func (p *sendGridProvider) parse(r *http.Request, body []byte) ([]notification, error) {
+	timestamp := r.Header.Get(sendGridTimestampHeader)
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(sendGridSignatureHeader))
+	if err != nil || timestamp == "" {
+		return nil, errUnauthorized
+	}
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	if !ecdsa.VerifyASN1(p.publicKey, digest[:], signature) {
+		return nil, errUnauthorized
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errUnauthorized
+	}
+	if err := p.receiver.checkTimestamp(time.Unix(seconds, 0)); err != nil {
+		return nil, err
+	}
+
+	var events []sendGridEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("invalid sendgrid events: %w", err)
+	}
+	var notifications []notification
+	for _, e := range events {
+		if event := sendGridToEvent(&e); event != nil {
+			notifications = append(notifications, notification{ID: e.ID, Event: event})
+		}
+	}
+	return notifications, nil
+}
+
+// sendGridToEvent converts a SendGrid event into an event, or returns nil for events
+// other than bounces, deferrals and spam reports.
+func sendGridToEvent(e *sendGridEvent) *receiver.Event {
+	deliveryID := deliveryIDFromMessageID(e.SMTPID)
+	rcpt := receiver.RecipientStatus{
+		Recipient:      e.Email,
+		Status:         e.Status,
+		DiagnosticCode: e.Reason,
+	}
+	switch e.Event {
+	case "bounce", "blocked":
+		rcpt.Action = "failed"
+		rcpt.BounceType = bounce.Classify(rcpt.Action, rcpt.Status, rcpt.DiagnosticCode)
+		if (e.Event == "blocked" || e.Type == "blocked") && rcpt.BounceType == receiver.BounceTypeHard {
+			// blocked messages were rejected for the sender, not the recipient
+			rcpt.BounceType = receiver.BounceTypeSoft
+		}
+	case "deferred":
+		rcpt.Action = "delayed"
+		rcpt.DiagnosticCode = e.Response
+		rcpt.BounceType = receiver.BounceTypeTransient
+	case "spamreport":
+		return complaintEvent(deliveryID, e.SMTPID, "", e.Email, "", "")
+	default:
+		return nil
+	}
+	return bounceEvent(deliveryID, e.SMTPID, "", []receiver.RecipientStatus{rcpt})
+}
@@ -0,0 +1,339 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// snsHost matches the hosts SNS serves signing certificates and subscription URLs from.
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsMessage is an SNS HTTP(S) delivery.
+type snsMessage struct {
+	Type             string  `json:"Type"`
+	MessageID        string  `json:"MessageId"`
+	Token            string  `json:"Token"`
+	TopicArn         string  `json:"TopicArn"`
+	Subject          *string `json:"Subject"`
+	Message          string  `json:"Message"`
+	Timestamp        string  `json:"Timestamp"`
+	SignatureVersion string  `json:"SignatureVersion"`
+	Signature        string  `json:"Signature"`
+	SigningCertURL   string  `json:"SigningCertURL"`
+	SubscribeURL     string  `json:"SubscribeURL"`
+}
+
+// signedString returns the string SNS signs for the message type.
+func (m *snsMessage) signedString() string {
+	var b strings.Builder
+	field := func(name, value string) {
+		b.WriteString(name + "\n" + value + "\n")
+	}
+	field("Message", m.Message)
+	field("MessageId", m.MessageID)
+	if m.Type == "Notification" {
+		if m.Subject != nil {
+			field("Subject", *m.Subject)
+		}
+	} else {
+		field("SubscribeURL", m.SubscribeURL)
+	}
+	field("Timestamp", m.Timestamp)
+	if m.Type != "Notification" {
+		field("Token", m.Token)
+	}
+	field("TopicArn", m.TopicArn)
+	field("Type", m.Type)
+	return b.String()
+}
+
+// sesNotification is an SES bounce or complaint notification, or an SES event
+// published to SNS by a configuration set.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Bounce           *struct {
+		BounceType        string `json:"bounceType"`
+		BounceSubType     string `json:"bounceSubType"`
+		ReportingMTA      string `json:"reportingMTA"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			Action         string `json:"action"`
+			Status         string `json:"status"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint *struct {
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+		UserAgent             string `json:"userAgent"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Mail struct {
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		CommonHeaders struct {
+			MessageID string `json:"messageId"`
+			Subject   string `json:"subject"`
+		} `json:"commonHeaders"`
+	} `json:"mail"`
+}
+
+// sesProvider receives SES notifications delivered by SNS. Messages are verified with
+// the SNS signing certificate and accepted only from the configured topics;
+// subscriptions to them are confirmed automatically.
+type sesProvider struct {
+	receiver *Receiver
+	topics   map[string]bool
+
+	// client and host fetch certificates and confirm subscriptions; tests replace them.
+	client *http.Client
+	host   *regexp.Regexp
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newSESProvider(r *Receiver, topicARNs []string) *sesProvider {
+	topics := make(map[string]bool, len(topicARNs))
+	for _, arn := range topicARNs {
+		topics[arn] = true
+	}
+	return &sesProvider{
+		receiver: r,
+		topics:   topics,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		host:     snsHost,
+		certs:    map[string]*x509.Certificate{},
+	}
+}
+
+func (p *sesProvider) parse(r *http.Request, body []byte) ([]notification, error) {
+	var m snsMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("invalid sns message: %w", err)
+	}
+	if !p.topics[m.TopicArn] {
+		return nil, fmt.Errorf("%w: unknown topic '%s'", errUnauthorized, m.TopicArn)
+	}
+	if err := p.verify(r.Context(), &m); err != nil {
+		return nil, err
+	}
+	timestamp, err := time.Parse(time.RFC3339, m.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sns timestamp: %w", err)
+	}
+	if err := p.receiver.checkTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+
+	switch m.Type {
+	case "SubscriptionConfirmation":
+		return nil, p.confirm(r.Context(), &m)
+	case "Notification":
+		event, err := sesEvent(m.Message)
+		if err != nil || event == nil {
+			return nil, err
+		}
+		return []notification{{ID: m.MessageID, Event: event}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// verify checks the signature of the message with the certificate it refers to.
+func (p *sesProvider) verify(ctx context.Context, m *snsMessage) error {
+	var hash crypto.Hash
+	switch m.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("%w: unsupported signature version '%s'", errUnauthorized, m.SignatureVersion)
+	}
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return errUnauthorized
+	}
+	cert, err := p.certificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: unsupported signing certificate", errUnauthorized)
+	}
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(m.signedString()))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(m.signedString()))
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// certificate returns the signing certificate at rawURL, which must be a .pem file
+// served by SNS. Certificates are cached by URL without query, so messages cannot
+// grow the cache by varying it.
+func (p *sesProvider) certificate(ctx context.Context, rawURL string) (*x509.Certificate, error) {
+	if err := p.checkURL(rawURL); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(rawURL)
+	if !strings.HasSuffix(u.Path, ".pem") {
+		return nil, fmt.Errorf("%w: untrusted url '%s'", errUnauthorized, rawURL)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	certURL := u.String()
+	p.mu.Lock()
+	cert, ok := p.certs[certURL]
+	p.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	data, err := p.get(ctx, certURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: invalid signing certificate", errUnauthorized)
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signing certificate", errUnauthorized)
+	}
+	p.mu.Lock()
+	p.certs[certURL] = cert
+	p.mu.Unlock()
+	return cert, nil
+}
+
+// confirm confirms the subscription of a configured topic to this endpoint.
+func (p *sesProvider) confirm(ctx context.Context, m *snsMessage) error {
+	if err := p.checkURL(m.SubscribeURL); err != nil {
+		return err
+	}
+	if _, err := p.get(ctx, m.SubscribeURL); err != nil {
+		return fmt.Errorf("confirm subscription: %w", err)
+	}
+	log.Printf("webhook ses: confirmed subscription to %s", m.TopicArn)
+	return nil
+}
+
+func (p *sesProvider) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || !p.host.MatchString(u.Hostname()) {
+		return fmt.Errorf("%w: untrusted url '%s'", errUnauthorized, rawURL)
+	}
+	return nil
+}
+
+func (p *sesProvider) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+}
+
+// sesEvent converts an SES notification into an event, or returns nil for
+// notifications other than bounces and complaints.
+func sesEvent(message string) (*receiver.Event, error) {
+	var n sesNotification
+	if err := json.Unmarshal([]byte(message), &n); err != nil {
+		return nil, fmt.Errorf("invalid ses notification: %w", err)
+	}
+	deliveryID := ""
+	for _, h := range n.Mail.Headers {
+		if strings.EqualFold(h.Name, mailer.HeadmailDeliveryHeaderName) {
+			deliveryID = h.Value
+		}
+	}
+	messageID := n.Mail.CommonHeaders.MessageID
+	if deliveryID == "" {
+		deliveryID = deliveryIDFromMessageID(messageID)
+	}
+	subject := n.Mail.CommonHeaders.Subject
+
+	kind := n.NotificationType
+	if kind == "" {
+		kind = n.EventType
+	}
+	switch {
+	case kind == "Bounce" && n.Bounce != nil:
+		// SES stops retrying before it reports transient bounces, so they are failures
+		bounceType := receiver.BounceTypeSoft
+		if n.Bounce.BounceType == "Permanent" {
+			bounceType = receiver.BounceTypeHard
+		}
+		remoteMTA := n.Bounce.ReportingMTA
+		if _, host, ok := strings.Cut(remoteMTA, ";"); ok {
+			remoteMTA = strings.TrimSpace(host)
+		}
+		var recipients []receiver.RecipientStatus
+		for _, rcpt := range n.Bounce.BouncedRecipients {
+			action := rcpt.Action
+			if action == "" {
+				action = "failed"
+			}
+			recipients = append(recipients, receiver.RecipientStatus{
+				Recipient:      rcpt.EmailAddress,
+				Action:         action,
+				Status:         rcpt.Status,
+				DiagnosticCode: rcpt.DiagnosticCode,
+				RemoteMTA:      remoteMTA,
+				BounceType:     bounceType,
+			})
+		}
+		return bounceEvent(deliveryID, messageID, subject, recipients), nil
+	case kind == "Complaint" && n.Complaint != nil:
+		recipient := ""
+		if len(n.Complaint.ComplainedRecipients) > 0 {
+			recipient = n.Complaint.ComplainedRecipients[0].EmailAddress
+		}
+		return complaintEvent(deliveryID, messageID, subject, recipient, n.Complaint.ComplaintFeedbackType, n.Complaint.UserAgent), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package webhook receives bounce and complaint notifications of email service
+// providers (Amazon SES via SNS, SendGrid, Mailgun and Postmark) over HTTP.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/internal/mail/bounce"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/receiver"
+)
+
+// maxBodySize limits the size of webhook requests.
+const maxBodySize = 1 << 20
+
+var (
+	// errUnauthorized is returned for requests with missing or invalid signatures or credentials.
+	errUnauthorized = errors.New("invalid webhook signature")
+	// errExpired is returned for signed requests whose timestamp is outside the tolerance.
+	errExpired = errors.New("webhook timestamp outside tolerance")
+	// errStopped is returned for requests received while the receiver is not running.
+	errStopped = errors.New("webhook receiver is not running")
+)
+
+// notification is one event of a webhook request. ID identifies the event at the
+// provider and is used to drop replayed and redelivered events.
+type notification struct {
+	ID    string
+	Event *receiver.Event
+}
+
+// provider verifies a webhook request of one email service provider and normalizes
+// its payload into events.
+type provider interface {
+	parse(r *http.Request, body []byte) ([]notification, error)
+}
+
+// Receiver serves the webhook endpoints of the configured providers and emits their
+// bounce and complaint events. Requests are answered once their events have been
+// handed over, so providers retry events that could not be accepted.
+type Receiver struct {
+	tolerance time.Duration
+	providers map[string]provider
+	seen      *replayCache
+	now       func() time.Time
+
+	mu      sync.RWMutex
+	running bool
+	events  chan *receiver.Event
+	done    chan struct{}
+	stop    *sync.Once
+}
+
+// NewReceiver creates a Receiver for the providers configured in cfg.
+func NewReceiver(cfg *config.WebhookConfig) (*Receiver, error) {
+	r := &Receiver{
+		tolerance: cfg.Tolerance,
+		providers: map[string]provider{},
+		// an accepted timestamp may be up to the tolerance ahead of or behind now
+		seen: newReplayCache(2 * cfg.Tolerance),
+		now:  time.Now,
+	}
+	if len(cfg.SES.TopicARNs) > 0 {
+		r.providers["ses"] = newSESProvider(r, cfg.SES.TopicARNs)
+	}
+	if cfg.SendGrid.PublicKey != "" {
+		p, err := newSendGridProvider(r, cfg.SendGrid.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		r.providers["sendgrid"] = p
+	}
+	if cfg.Mailgun.SigningKey != "" {
+		r.providers["mailgun"] = &mailgunProvider{receiver: r, signingKey: []byte(cfg.Mailgun.SigningKey)}
+	}
+	if cfg.Postmark.Username != "" || cfg.Postmark.Password != "" {
+		r.providers["postmark"] = &postmarkProvider{username: cfg.Postmark.Username, password: cfg.Postmark.Password}
+	}
+	return r, nil
+}
+
+// Enabled reports whether any provider is configured.
+func (r *Receiver) Enabled() bool {
+	return len(r.providers) > 0
+}
+
+// RegisterRoutes registers the webhook endpoints on the provided router.
+func (r *Receiver) RegisterRoutes(router chi.Router) {
+	router.Post("/webhooks/{provider}", r.webhookHandler)
+}
+
+// Start starts accepting webhook requests.
+func (r *Receiver) Start(ctx context.Context) (chan *receiver.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return nil, errors.New("webhook receiver already started")
+	}
+	r.running = true
+	r.events = make(chan *receiver.Event, 1)
+	r.done = make(chan struct{})
+	r.stop = &sync.Once{}
+	done := r.done
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = r.Stop(context.Background())
+		case <-done:
+		}
+	}()
+	return r.events, nil
+}
+
+// Stop stops accepting webhook requests and closes the event channel.
+func (r *Receiver) Stop(ctx context.Context) error {
+	// wake up requests waiting to hand over events before taking the write lock
+	r.mu.RLock()
+	running, done, stop := r.running, r.done, r.stop
+	r.mu.RUnlock()
+	if !running {
+		return nil
+	}
+	stop.Do(func() { close(done) })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		r.running = false
+		close(r.events)
+	}
+	return nil
+}
+
+// @Summary Email service provider webhook
+// @Description Receives bounce and complaint notifications of Amazon SES (SNS), SendGrid, Mailgun or Postmark. Requests must carry the provider's signature or credentials; replayed events are ignored.
+// @Tags webhooks
+// @Param provider path string true "Provider" Enums(ses, sendgrid, mailgun, postmark)
+// @Accept json
+// @Success 200 "Accepted"
+// @Failure 400 {string} string "Invalid payload"
+// @Failure 401 {string} string "Invalid signature"
+// @Failure 404 {string} string "Provider not configured"
+// @Failure 503 {string} string "Receiver not running"
+// @Router /webhooks/{provider} [post]
+func (r *Receiver) webhookHandler(w http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "provider")
+	p, ok := r.providers[name]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	notifications, err := p.parse(req, body)
+	if err != nil {
+		log.Printf("webhook %s: %v", name, err)
+		http.Error(w, err.Error(), webhookErrorStatus(err))
+		return
+	}
+	for _, n := range notifications {
+		if n.ID != "" && !r.seen.add(name+":"+n.ID, r.now()) {
+			continue
+		}
+		if err := r.emit(req.Context(), n.Event); err != nil {
+			if n.ID != "" {
+				r.seen.remove(name + ":" + n.ID)
+			}
+			http.Error(w, err.Error(), webhookErrorStatus(err))
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// emit hands the event over to the consumer of the event channel.
+func (r *Receiver) emit(ctx context.Context, event *receiver.Event) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.running {
+		return errStopped
+	}
+	select {
+	case r.events <- event:
+		return nil
+	case <-r.done:
+		return errStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkTimestamp rejects signed requests outside the tolerance, so captured requests
+// cannot be replayed once their IDs have been forgotten.
+func (r *Receiver) checkTimestamp(t time.Time) error {
+	if r.tolerance <= 0 {
+		return nil
+	}
+	if d := r.now().Sub(t); d > r.tolerance || d < -r.tolerance {
+		return errExpired
+	}
+	return nil
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errUnauthorized), errors.Is(err, errExpired):
+		return http.StatusUnauthorized
+	case errors.Is(err, errStopped):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// deliveryIDFromMessageID returns the delivery ID of a Message-ID generated by the
+// message builder (<delivery id>@<sender domain>), or "" when it has no local part.
+func deliveryIDFromMessageID(messageID string) string {
+	messageID = strings.Trim(strings.TrimSpace(messageID), "<>")
+	at := strings.LastIndexByte(messageID, '@')
+	if at <= 0 {
+		return ""
+	}
+	return messageID[:at]
+}
+
+// bounceEvent builds a bounce event from the recipient statuses reported by a provider.
+func bounceEvent(deliveryID, messageID, subject string, recipients []receiver.RecipientStatus) *receiver.Event {
+	report := &bounce.Report{
+		DeliveryID: deliveryID,
+		MessageID:  strings.Trim(messageID, "<>"),
+		Subject:    subject,
+		Recipients: recipients,
+	}
+	return report.Event()
+}
+
+// complaintEvent builds a complaint event. Providers that do not report a feedback type
+// only report abuse complaints.
+func complaintEvent(deliveryID, messageID, subject, recipient, feedbackType, userAgent string) *receiver.Event {
+	if feedbackType == "" {
+		feedbackType = "abuse"
+	}
+	report := &bounce.Report{
+		DeliveryID: deliveryID,
+		MessageID:  strings.Trim(messageID, "<>"),
+		Subject:    subject,
+		Feedback: &bounce.Feedback{
+			FeedbackType:   strings.ToLower(feedbackType),
+			UserAgent:      userAgent,
+			OriginalRcptTo: recipient,
+		},
+	}
+	return report.Event()
+}
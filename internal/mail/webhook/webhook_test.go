@@ -0,0 +1,433 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves a started Receiver and collects the events it emits.
+type testServer struct {
+	*Receiver
+	url    string
+	events chan *receiver.Event
+}
+
+func newTestServer(t *testing.T, cfg config.WebhookConfig) *testServer {
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = time.Hour
+	}
+	r, err := NewReceiver(&cfg)
+	require.NoError(t, err)
+	router := chi.NewRouter()
+	r.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	events, err := r.Start(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Stop(context.Background()) })
+	collected := make(chan *receiver.Event, 100)
+	go func() {
+		for event := range events {
+			collected <- event
+		}
+	}()
+	return &testServer{Receiver: r, url: srv.URL, events: collected}
+}
+
+func (s *testServer) post(t *testing.T, provider string, body []byte, header http.Header) int {
+	req, err := http.NewRequest(http.MethodPost, s.url+"/webhooks/"+provider, bytes.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testServer) next(t *testing.T) *receiver.Event {
+	select {
+	case event := <-s.events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReceiver_SES(t *testing.T) {
+	const topic = "arn:aws:sns:us-east-1:123456789012:bounces"
+	cfg := config.WebhookConfig{}
+	cfg.SES.TopicARNs = []string{topic}
+	srv := newTestServer(t, cfg)
+
+	// a local SNS serves the signing certificate and subscription confirmations
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	var confirmed atomic.Int32
+	sns := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cert.pem":
+			_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		case "/confirm":
+			confirmed.Add(1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer sns.Close()
+	ses := srv.providers["ses"].(*sesProvider)
+	ses.client = sns.Client()
+	ses.host = regexp.MustCompile(`^127\.0\.0\.1$`)
+
+	sign := func(m *snsMessage) []byte {
+		m.TopicArn = topic
+		m.SignatureVersion = "2"
+		m.SigningCertURL = sns.URL + "/cert.pem"
+		if m.Timestamp == "" {
+			m.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		}
+		digest := sha256.Sum256([]byte(m.signedString()))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		m.Signature = base64.StdEncoding.EncodeToString(signature)
+		return mustJSON(t, m)
+	}
+	notify := func(id string, n any) []byte {
+		return sign(&snsMessage{Type: "Notification", MessageID: id, Message: string(mustJSON(t, n))})
+	}
+
+	// subscriptions to configured topics are confirmed
+	assert.Equal(t, http.StatusOK, srv.post(t, "ses", sign(&snsMessage{
+		Type:         "SubscriptionConfirmation",
+		MessageID:    "m-sub",
+		Token:        "token",
+		SubscribeURL: sns.URL + "/confirm",
+	}), nil))
+	assert.EqualValues(t, 1, confirmed.Load())
+
+	bounce := map[string]any{
+		"notificationType": "Bounce",
+		"bounce": map[string]any{
+			"bounceType":    "Permanent",
+			"bounceSubType": "General",
+			"reportingMTA":  "dsn; a1-2.smtp-out.amazonses.com",
+			"bouncedRecipients": []map[string]any{{
+				"emailAddress":   "nobody@example.com",
+				"action":         "failed",
+				"status":         "5.1.1",
+				"diagnosticCode": "smtp; 550 5.1.1 user unknown",
+			}},
+		},
+		"mail": map[string]any{
+			"headers": []map[string]any{{"name": "X-Headmail-Delivery", "value": "d-ses"}},
+			"commonHeaders": map[string]any{
+				"messageId": "<0100018c@email.amazonses.com>",
+				"subject":   "Newsletter",
+			},
+		},
+	}
+	body := notify("m-1", bounce)
+	require.Equal(t, http.StatusOK, srv.post(t, "ses", body, nil))
+	assert.Equal(t, &receiver.Event{
+		Type:              receiver.EventTypeBounce,
+		DeliveryID:        "d-ses",
+		MessageID:         "0100018c@email.amazonses.com",
+		Subject:           "Newsletter",
+		BouncedRecipients: []string{"nobody@example.com"},
+		Reason:            "hard bounce (5.1.1): smtp; 550 5.1.1 user unknown",
+		Permanent:         true,
+		BounceType:        receiver.BounceTypeHard,
+		Status:            "5.1.1",
+		DiagnosticCode:    "smtp; 550 5.1.1 user unknown",
+		RemoteMTA:         "a1-2.smtp-out.amazonses.com",
+		Recipients: []receiver.RecipientStatus{{
+			Recipient:      "nobody@example.com",
+			Action:         "failed",
+			Status:         "5.1.1",
+			DiagnosticCode: "smtp; 550 5.1.1 user unknown",
+			RemoteMTA:      "a1-2.smtp-out.amazonses.com",
+			BounceType:     receiver.BounceTypeHard,
+		}},
+	}, srv.next(t))
+
+	// a replayed notification is acknowledged but not emitted again
+	assert.Equal(t, http.StatusOK, srv.post(t, "ses", body, nil))
+
+	// the delivery is found by its Message-ID when SES does not include the headers
+	assert.Equal(t, http.StatusOK, srv.post(t, "ses", notify("m-2", map[string]any{
+		"notificationType": "Complaint",
+		"complaint": map[string]any{
+			"complaintFeedbackType": "abuse",
+			"complainedRecipients":  []map[string]any{{"emailAddress": "judy@example.net"}},
+		},
+		"mail": map[string]any{
+			"commonHeaders": map[string]any{"messageId": "<d-ses-2@news.example.com>"},
+		},
+	}), nil))
+	complaint := srv.next(t)
+	assert.Equal(t, receiver.EventTypeComplaint, complaint.Type)
+	assert.Equal(t, "d-ses-2", complaint.DeliveryID)
+	assert.Equal(t, []string{"judy@example.net"}, complaint.BouncedRecipients)
+
+	// forged, tampered, stale and foreign topic messages are rejected
+	var tampered snsMessage
+	require.NoError(t, json.Unmarshal(notify("m-3", bounce), &tampered))
+	tampered.Message = `{"notificationType":"Complaint"}`
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "ses", mustJSON(t, tampered), nil))
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "ses", sign(&snsMessage{
+		Type:      "Notification",
+		MessageID: "m-4",
+		Message:   string(mustJSON(t, bounce)),
+		Timestamp: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+	}), nil))
+	foreign := snsMessage{Type: "Notification", MessageID: "m-5", Message: "{}"}
+	sign(&foreign)
+	foreign.TopicArn = "arn:aws:sns:us-east-1:999999999999:other"
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "ses", mustJSON(t, foreign), nil))
+
+	// certificates are cached by URL without query, and only .pem files are fetched
+	for i := 0; i < 3; i++ {
+		_, err := ses.certificate(context.Background(), sns.URL+"/cert.pem?v="+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	assert.Len(t, ses.certs, 1)
+	_, err = ses.certificate(context.Background(), sns.URL+"/confirm")
+	assert.ErrorIs(t, err, errUnauthorized)
+
+	ses.host = snsHost
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "ses", notify("m-6", bounce), nil), "certificates are only fetched from SNS")
+
+	select {
+	case event := <-srv.events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestReceiver_SendGrid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	cfg := config.WebhookConfig{}
+	cfg.SendGrid.PublicKey = base64.StdEncoding.EncodeToString(der)
+	srv := newTestServer(t, cfg)
+
+	sign := func(body []byte, timestamp time.Time) http.Header {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		digest := sha256.Sum256(append([]byte(ts), body...))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		return http.Header{
+			sendGridTimestampHeader: {ts},
+			sendGridSignatureHeader: {base64.StdEncoding.EncodeToString(signature)},
+		}
+	}
+
+	body := mustJSON(t, []map[string]any{
+		{"sg_event_id": "e-1", "event": "delivered", "email": "ok@example.com", "smtp-id": "<d-ok@news.example.com>"},
+		{"sg_event_id": "e-2", "event": "bounce", "type": "bounce", "email": "nobody@example.com", "smtp-id": "<d-sg@news.example.com>", "status": "5.1.1", "reason": "550 5.1.1 user unknown"},
+		{"sg_event_id": "e-3", "event": "deferred", "email": "slow@example.com", "smtp-id": "<d-slow@news.example.com>", "response": "421 4.7.0 try again later"},
+		{"sg_event_id": "e-4", "event": "spamreport", "email": "judy@example.net", "smtp-id": "<d-spam@news.example.com>"},
+	})
+	require.Equal(t, http.StatusOK, srv.post(t, "sendgrid", body, sign(body, time.Now())))
+
+	bounced := srv.next(t)
+	assert.Equal(t, "d-sg", bounced.DeliveryID)
+	assert.Equal(t, receiver.BounceTypeHard, bounced.BounceType)
+	assert.True(t, bounced.Permanent)
+	assert.Equal(t, "hard bounce (5.1.1): 550 5.1.1 user unknown", bounced.Reason)
+	deferred := srv.next(t)
+	assert.Equal(t, "d-slow", deferred.DeliveryID)
+	assert.Equal(t, receiver.BounceTypeTransient, deferred.BounceType)
+	complaint := srv.next(t)
+	assert.Equal(t, receiver.EventTypeComplaint, complaint.Type)
+	assert.Equal(t, "d-spam", complaint.DeliveryID)
+	assert.Equal(t, "abuse", complaint.FeedbackType)
+
+	// the same batch signed again is acknowledged without emitting its events again
+	assert.Equal(t, http.StatusOK, srv.post(t, "sendgrid", body, sign(body, time.Now())))
+
+	header := sign(body, time.Now())
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "sendgrid", append(body, ' '), header))
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "sendgrid", body, nil))
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "sendgrid", body, sign(body, time.Now().Add(-2*time.Hour))))
+
+	select {
+	case event := <-srv.events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestReceiver_Mailgun(t *testing.T) {
+	cfg := config.WebhookConfig{}
+	cfg.Mailgun.SigningKey = "mailgun-signing-key"
+	srv := newTestServer(t, cfg)
+
+	payload := func(token string, timestamp time.Time, data map[string]any) []byte {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.Mailgun.SigningKey))
+		mac.Write([]byte(ts + token))
+		return mustJSON(t, map[string]any{
+			"signature":  map[string]any{"timestamp": ts, "token": token, "signature": hex.EncodeToString(mac.Sum(nil))},
+			"event-data": data,
+		})
+	}
+	failed := func(id, severity string) map[string]any {
+		return map[string]any{
+			"id":        id,
+			"event":     "failed",
+			"severity":  severity,
+			"recipient": "full@example.com",
+			"delivery-status": map[string]any{
+				"code":    552,
+				"message": "5.2.2 mailbox full",
+				"mx-host": "mx.example.com",
+			},
+			"message": map[string]any{"headers": map[string]any{"message-id": "d-mg@news.example.com", "subject": "Newsletter"}},
+		}
+	}
+
+	body := payload("token-1", time.Now(), failed("ev-1", "permanent"))
+	require.Equal(t, http.StatusOK, srv.post(t, "mailgun", body, nil))
+	event := srv.next(t)
+	assert.Equal(t, "d-mg", event.DeliveryID)
+	assert.Equal(t, "d-mg@news.example.com", event.MessageID)
+	assert.Equal(t, receiver.BounceTypeSoft, event.BounceType, "a full mailbox is a soft bounce")
+	assert.Equal(t, "mx.example.com", event.RemoteMTA)
+	assert.Equal(t, "552 5.2.2 mailbox full", event.DiagnosticCode)
+
+	// replays are dropped, including a redelivery of the event with a fresh signature
+	assert.Equal(t, http.StatusOK, srv.post(t, "mailgun", body, nil))
+	assert.Equal(t, http.StatusOK, srv.post(t, "mailgun", payload("token-2", time.Now(), failed("ev-1", "permanent")), nil))
+
+	require.Equal(t, http.StatusOK, srv.post(t, "mailgun", payload("token-3", time.Now(), failed("ev-2", "temporary")), nil))
+	assert.Equal(t, receiver.BounceTypeTransient, srv.next(t).BounceType)
+
+	require.Equal(t, http.StatusOK, srv.post(t, "mailgun", payload("token-4", time.Now(), map[string]any{
+		"id":        "ev-3",
+		"event":     "complained",
+		"recipient": "judy@example.net",
+		"message":   map[string]any{"headers": map[string]any{"message-id": "d-mg-spam@news.example.com"}},
+	}), nil))
+	complaint := srv.next(t)
+	assert.Equal(t, receiver.EventTypeComplaint, complaint.Type)
+	assert.Equal(t, "d-mg-spam", complaint.DeliveryID)
+
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "mailgun", payload("token-5", time.Now().Add(-2*time.Hour), failed("ev-4", "permanent")), nil))
+	forged := bytes.Replace(payload("token-6", time.Now(), failed("ev-5", "permanent")), []byte(`"signature":"`), []byte(`"signature":"00`), 1)
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "mailgun", forged, nil))
+
+	select {
+	case event := <-srv.events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestReceiver_Postmark(t *testing.T) {
+	cfg := config.WebhookConfig{}
+	cfg.Postmark.Username = "postmark"
+	cfg.Postmark.Password = "secret"
+	srv := newTestServer(t, cfg)
+
+	auth := func(username, password string) http.Header {
+		req, _ := http.NewRequest(http.MethodPost, "/", nil)
+		req.SetBasicAuth(username, password)
+		return req.Header
+	}
+	body := mustJSON(t, map[string]any{
+		"RecordType": "Bounce",
+		"ID":         4323372036854775807,
+		"Type":       "HardBounce",
+		"Email":      "nobody@example.com",
+		"Subject":    "Newsletter",
+		"Details":    "smtp;550 5.1.1 The email account that you tried to reach does not exist.",
+		"Metadata":   map[string]any{postmarkMetadataKey: "d-pm"},
+	})
+	require.Equal(t, http.StatusOK, srv.post(t, "postmark", body, auth("postmark", "secret")))
+	event := srv.next(t)
+	assert.Equal(t, "d-pm", event.DeliveryID)
+	assert.Equal(t, "Newsletter", event.Subject)
+	assert.Equal(t, receiver.BounceTypeHard, event.BounceType)
+	assert.Equal(t, []string{"nobody@example.com"}, event.BouncedRecipients)
+
+	assert.Equal(t, http.StatusOK, srv.post(t, "postmark", body, auth("postmark", "secret")))
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "postmark", body, auth("postmark", "wrong")))
+	assert.Equal(t, http.StatusUnauthorized, srv.post(t, "postmark", body, nil))
+
+	// records that are not failures are ignored
+	assert.Equal(t, http.StatusOK, srv.post(t, "postmark", mustJSON(t, map[string]any{
+		"RecordType": "Bounce",
+		"ID":         1,
+		"Type":       "AutoResponder",
+		"Email":      "away@example.com",
+	}), auth("postmark", "secret")))
+
+	require.Equal(t, http.StatusOK, srv.post(t, "postmark", mustJSON(t, map[string]any{
+		"RecordType": "SpamComplaint",
+		"ID":         2,
+		"Email":      "judy@example.net",
+		"Metadata":   map[string]any{postmarkMetadataKey: "d-pm-spam"},
+	}), auth("postmark", "secret")))
+	complaint := srv.next(t)
+	assert.Equal(t, receiver.EventTypeComplaint, complaint.Type)
+	assert.Equal(t, "d-pm-spam", complaint.DeliveryID)
+
+	assert.Equal(t, http.StatusNotFound, srv.post(t, "sendgrid", body, nil), "unconfigured providers are not served")
+
+	require.NoError(t, srv.Stop(context.Background()))
+	assert.Equal(t, http.StatusServiceUnavailable, srv.post(t, "postmark", mustJSON(t, map[string]any{
+		"RecordType": "SpamComplaint",
+		"ID":         3,
+		"Email":      "judy@example.net",
+	}), auth("postmark", "secret")))
+}
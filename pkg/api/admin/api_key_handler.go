@@ -0,0 +1,179 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/service"
+)
+
+// APIKeyHandler handles HTTP requests for stored admin API keys.
+type APIKeyHandler struct {
+	service service.APIKeyServiceProvider
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(service service.APIKeyServiceProvider) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the API key routes to the router.
+func (h *APIKeyHandler) RegisterRoutes(r chi.Router) {
+	read, write := auth.RequireScope(auth.ScopeAPIKeysRead), auth.RequireScope(auth.ScopeAPIKeysWrite)
+	r.Route("/api-keys", func(r chi.Router) {
+		r.With(read).Get("/", h.listAPIKeys)
+		r.With(write).Post("/", h.createAPIKey)
+		r.Route("/{apiKeyID}", func(r chi.Router) {
+			r.With(read).Get("/", h.getAPIKey)
+			r.With(write).Delete("/", h.revokeAPIKey)
+			r.With(write).Post("/rotate", h.rotateAPIKey)
+		})
+	})
+}
+
+// @Summary List API keys
+// @Description List stored API keys, newest first, including revoked keys. Keys themselves are never returned.
+// @Tags api-keys
+// @Produce  json
+// @Param   page  query  int  false  "Page number"
+// @Param   limit  query  int  false  "Number of items per page"
+// @Success 200 {object} PaginatedListResponse[domain.APIKey]
+// @Router /api-keys [get]
+func (h *APIKeyHandler) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit == 0 {
+		limit = 20
+	}
+
+	pagination := repository.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	keys, total, err := h.service.ListAPIKeys(r.Context(), pagination)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &PaginatedListResponse[*domain.APIKey]{
+		Data: keys,
+		Pagination: PaginationResponse{
+			Page:  page,
+			Total: total,
+			Limit: limit,
+		},
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// @Summary Create an API key
+// @Description Creates a key restricted to the given scopes. The key is only returned in this response.
+// @Tags api-keys
+// @Accept  json
+// @Produce  json
+// @Param   apiKey  body  dto.CreateAPIKeyRequest  true  "API key to create"
+// @Success 201 {object} dto.APIKeySecretResponse
+// @Failure 403 {object} map[string]string "Scope not held by the caller"
+// @Router /api-keys [post]
+func (h *APIKeyHandler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := &domain.APIKey{
+		Name:   req.Name,
+		Scopes: req.Scopes,
+	}
+	secret, err := h.service.CreateAPIKey(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusCreated, &dto.APIKeySecretResponse{APIKey: key, Key: secret})
+}
+
+// @Summary Get an API key by ID
+// @Description Get an API key by ID, including its scopes and last use
+// @Tags api-keys
+// @Produce  json
+// @Param   apiKeyID  path  string  true  "API key ID"
+// @Success 200 {object} domain.APIKey
+// @Router /api-keys/{apiKeyID} [get]
+func (h *APIKeyHandler) getAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.service.GetAPIKey(r.Context(), chi.URLParam(r, "apiKeyID"))
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+	writeJson(w, http.StatusOK, key)
+}
+
+// @Summary Rotate an API key
+// @Description Replaces the key, keeping its name and scopes. The previous key is rejected immediately and the new key is only returned in this response. The caller must hold every scope of the key.
+// @Tags api-keys
+// @Produce  json
+// @Param   apiKeyID  path  string  true  "API key ID"
+// @Success 200 {object} dto.APIKeySecretResponse
+// @Failure 403 {object} map[string]string "Scope not held by the caller"
+// @Router /api-keys/{apiKeyID}/rotate [post]
+func (h *APIKeyHandler) rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, secret, err := h.service.RotateAPIKey(r.Context(), chi.URLParam(r, "apiKeyID"))
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+	writeJson(w, http.StatusOK, &dto.APIKeySecretResponse{APIKey: key, Key: secret})
+}
+
+// @Summary Revoke an API key
+// @Description Rejects the key from now on. The entry is kept to show its last use. The caller must hold every scope of the key.
+// @Tags api-keys
+// @Produce  json
+// @Param   apiKeyID  path  string  true  "API key ID"
+// @Success 200 {object} domain.APIKey
+// @Failure 403 {object} map[string]string "Scope not held by the caller"
+// @Router /api-keys/{apiKeyID} [delete]
+func (h *APIKeyHandler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.service.RevokeAPIKey(r.Context(), chi.URLParam(r, "apiKeyID"))
+	if err != nil {
+		http.Error(w, err.Error(), apiKeyErrorStatus(err))
+		return
+	}
+	writeJson(w, http.StatusOK, key)
+}
+
+func apiKeyErrorStatus(err error) int {
+	var notFound *repository.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAPIKeyRevoked):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrAPIKeyScopeNotHeld):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/service"
+)
+
+// AuditHandler handles HTTP requests for the audit log.
+type AuditHandler struct {
+	service service.AuditServiceProvider
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(service service.AuditServiceProvider) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the audit log routes to the router.
+func (h *AuditHandler) RegisterRoutes(r chi.Router) {
+	r.With(auth.RequireScope(auth.ScopeAuditRead)).Get("/audit", h.listAuditEntries)
+}
+
+// @Summary List audit log entries
+// @Description List recorded admin mutations, newest first. Entries of campaigns, lists, subscribers and templates include the changed fields.
+// @Tags audit
+// @Produce  json
+// @Param   principal  query  string  false  "Filter by caller, e.g. api_key:ci"
+// @Param   action  query  string  false  "Filter by action, e.g. campaign.update"
+// @Param   entity_type  query  string  false  "Filter by entity type, e.g. campaign"
+// @Param   entity_id  query  string  false  "Filter by entity ID"
+// @Param   since  query  int  false  "Only entries at or after this unix time"
+// @Param   until  query  int  false  "Only entries at or before this unix time"
+// @Param   page  query  int  false  "Page number"
+// @Param   limit  query  int  false  "Number of items per page"
+// @Success 200 {object} PaginatedListResponse[domain.AuditEntry]
+// @Router /audit [get]
+func (h *AuditHandler) listAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit == 0 {
+		limit = 20
+	}
+
+	filter := repository.AuditFilter{
+		Principal:  query.Get("principal"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+	}
+	for name, bound := range map[string]*int64{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			ts, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid "+name+": "+v, http.StatusBadRequest)
+				return
+			}
+			*bound = ts
+		}
+	}
+	pagination := repository.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	entries, total, err := h.service.ListAuditEntries(r.Context(), filter, pagination)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &PaginatedListResponse[*domain.AuditEntry]{
+		Data: entries,
+		Pagination: PaginationResponse{
+			Page:  page,
+			Total: total,
+			Limit: limit,
+		},
+	}
+	writeJson(w, http.StatusOK, resp)
+}
@@ -5,12 +5,14 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/headmail/headmail/pkg/domain"
@@ -36,17 +38,19 @@ func NewCampaignHandler(service service.CampaignServiceProvider) *CampaignHandle
 
 // RegisterRoutes registers the campaign routes to the router.
 func (h *CampaignHandler) RegisterRoutes(r chi.Router) {
-	r.Post("/campaigns", h.createCampaign)
+	read, write := auth.RequireScope(auth.ScopeCampaignsRead), auth.RequireScope(auth.ScopeCampaignsWrite)
+	r.With(write).Post("/campaigns", h.createCampaign)
 	// Allow creating a campaign with a pre-defined ID. Accepts optional ?upsert=true to update existing.
-	r.Post("/campaigns/{campaignID}", h.createCampaignWithID)
-	r.Get("/campaigns", h.listCampaigns)
-	r.Get("/campaigns/{campaignID}", h.getCampaign)
-	r.Put("/campaigns/{campaignID}", h.updateCampaign)
-	r.Delete("/campaigns/{campaignID}", h.deleteCampaign)
-	r.Patch("/campaigns/{campaignID}/status", h.updateCampaignStatus)
-	r.Post("/campaigns/{campaignID}/deliveries", h.createCampaignDeliveries)
-	r.Get("/campaigns/stats", h.getCampaignsStats)
-	r.Get("/campaigns/{campaignID}/stats", h.getCampaignStats)
+	r.With(write).Post("/campaigns/{campaignID}", h.createCampaignWithID)
+	r.With(read).Get("/campaigns", h.listCampaigns)
+	r.With(read).Get("/campaigns/{campaignID}", h.getCampaign)
+	r.With(write).Put("/campaigns/{campaignID}", h.updateCampaign)
+	r.With(write).Delete("/campaigns/{campaignID}", h.deleteCampaign)
+	r.With(write).Patch("/campaigns/{campaignID}/status", h.updateCampaignStatus)
+	r.With(write).Post("/campaigns/{campaignID}/deliveries", h.createCampaignDeliveries)
+	r.With(read).Get("/campaigns/stats", h.getCampaignsStats)
+	r.With(read).Get("/campaigns/{campaignID}/stats", h.getCampaignStats)
+	r.With(read).Get("/campaigns/{campaignID}/links", h.listCampaignLinks)
 }
 
 // @Summary Create a new campaign
@@ -89,7 +93,7 @@ func (h *CampaignHandler) createCampaign(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -153,7 +157,7 @@ func (h *CampaignHandler) createCampaignWithID(w http.ResponseWriter, r *http.Re
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -217,7 +221,7 @@ func (h *CampaignHandler) updateCampaign(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.service.UpdateCampaign(r.Context(), campaign); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -331,7 +335,7 @@ func (h *CampaignHandler) updateCampaignStatus(w http.ResponseWriter, r *http.Re
 	campaign.Status = req.Status
 	campaign.ScheduledAt = req.ScheduledAt
 	if err := h.service.UpdateCampaign(r.Context(), campaign); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -357,7 +361,7 @@ func (h *CampaignHandler) createCampaignDeliveries(w http.ResponseWriter, r *htt
 
 	count, err := h.service.CreateDeliveries(r.Context(), campaignID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -458,3 +462,46 @@ func (h *CampaignHandler) getCampaignStats(w http.ResponseWriter, r *http.Reques
 	}
 	writeJson(w, http.StatusOK, stats)
 }
+
+// @Summary List campaign links
+// @Description Returns the tracked links of a campaign with their click counts, most clicked first.
+// @Tags campaigns
+// @Produce  json
+// @Param   campaignID  path  string  true  "Campaign ID"
+// @Param   page  query  int  false  "Page number"
+// @Param   limit  query  int  false  "Number of items per page"
+// @Success 200 {object} PaginatedListResponse[domain.Link]
+// @Failure 404 {object} map[string]string "Campaign not found"
+// @Router /campaigns/{campaignID}/links [get]
+func (h *CampaignHandler) listCampaignLinks(w http.ResponseWriter, r *http.Request) {
+	campaignID := chi.URLParam(r, "campaignID")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit == 0 {
+		limit = 20
+	}
+
+	links, total, err := h.service.ListCampaignLinks(r.Context(), campaignID, repository.Pagination{Page: page, Limit: limit})
+	if err != nil {
+		status := http.StatusInternalServerError
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	resp := &PaginatedListResponse[*domain.Link]{
+		Data: links,
+		Pagination: PaginationResponse{
+			Page:  page,
+			Total: total,
+			Limit: limit,
+		},
+	}
+	writeJson(w, http.StatusOK, resp)
+}
@@ -5,13 +5,17 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
 	"github.com/headmail/headmail/pkg/repository"
 	"github.com/headmail/headmail/pkg/service"
 )
@@ -29,14 +33,16 @@ func NewDeliveryHandler(service service.DeliveryServiceProvider, templateService
 
 // RegisterRoutes registers the delivery routes to the router.
 func (h *DeliveryHandler) RegisterRoutes(r chi.Router) {
-	r.Get("/campaigns/{campaignID}/deliveries", h.listCampaignDeliveries)
-	r.Get("/campaigns/{campaignID}/deliveries/{deliveryID}", h.getDelivery)
-	r.Post("/tx", h.createTransactionalDelivery)
-	r.Get("/tx/{deliveryID}", h.getDelivery)
+	read, write := auth.RequireScope(auth.ScopeDeliveriesRead), auth.RequireScope(auth.ScopeDeliveriesWrite)
+	r.With(read).Get("/campaigns/{campaignID}/deliveries", h.listCampaignDeliveries)
+	r.With(read).Get("/campaigns/{campaignID}/deliveries/{deliveryID}", h.getDelivery)
+	r.With(auth.RequireScope(auth.ScopeTxSend)).Post("/tx", h.createTransactionalDelivery)
+	// senders of transactional mail may follow up on what they sent
+	r.With(auth.RequireScope(auth.ScopeTxSend, auth.ScopeDeliveriesRead)).Get("/tx/{deliveryID}", h.getTransactionalDelivery)
 
 	// Immediate send / retry endpoints for a specific delivery (synchronous)
-	r.Post("/deliveries/{deliveryID}/send-now", h.sendNow)
-	r.Post("/deliveries/{deliveryID}/retry", h.retry)
+	r.With(write).Post("/deliveries/{deliveryID}/send-now", h.sendNow)
+	r.With(write).Post("/deliveries/{deliveryID}/retry", h.retry)
 }
 
 // @Summary List deliveries for a campaign
@@ -92,7 +98,6 @@ func (h *DeliveryHandler) listCampaignDeliveries(w http.ResponseWriter, r *http.
 // @Param   deliveryID  path  string  true  "Delivery ID"
 // @Success 200 {object} domain.Delivery
 // @Router /campaigns/{campaignID}/deliveries/{deliveryID} [get]
-// @Router /tx/{deliveryID} [get]
 func (h *DeliveryHandler) getDelivery(w http.ResponseWriter, r *http.Request) {
 	deliveryID := chi.URLParam(r, "deliveryID")
 
@@ -105,6 +110,31 @@ func (h *DeliveryHandler) getDelivery(w http.ResponseWriter, r *http.Request) {
 	writeJson(w, http.StatusOK, delivery)
 }
 
+// @Summary Get a transactional delivery by ID
+// @Description Get a delivery by ID. Callers with only the tx:send scope may read transactional deliveries; campaign deliveries require deliveries:read.
+// @Tags deliveries
+// @Produce  json
+// @Param   deliveryID  path  string  true  "Delivery ID"
+// @Success 200 {object} domain.Delivery
+// @Failure 403 {object} map[string]string
+// @Router /tx/{deliveryID} [get]
+func (h *DeliveryHandler) getTransactionalDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	delivery, err := h.service.GetDelivery(r.Context(), deliveryID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// campaign deliveries carry subscriber data that tx:send does not grant
+	if p := auth.PrincipalFromContext(r.Context()); p != nil && !p.HasScope(auth.ScopeDeliveriesRead) && delivery.CampaignID != nil {
+		http.Error(w, "missing scope "+auth.ScopeDeliveriesRead, http.StatusForbidden)
+		return
+	}
+
+	writeJson(w, http.StatusOK, delivery)
+}
+
 // @Summary Create a new transactional delivery
 // @Description Create a new transactional delivery
 // @Tags deliveries
@@ -153,6 +183,12 @@ func (h *DeliveryHandler) createTransactionalDelivery(w http.ResponseWriter, r *
 		Headers:     req.Headers,
 		Tags:        req.Tags,
 	}
+	if req.FromName != nil {
+		delivery.FromName = *req.FromName
+	}
+	if req.FromEmail != nil {
+		delivery.FromEmail = *req.FromEmail
+	}
 
 	// Keep template id reference in data for auditing/rendering if provided
 	if req.TemplateID != nil && *req.TemplateID != "" {
@@ -163,7 +199,7 @@ func (h *DeliveryHandler) createTransactionalDelivery(w http.ResponseWriter, r *
 	}
 
 	if err := h.service.CreateDelivery(r.Context(), delivery, templateMJML); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), messageErrorStatus(err))
 		return
 	}
 
@@ -178,6 +214,7 @@ func (h *DeliveryHandler) createTransactionalDelivery(w http.ResponseWriter, r *
 // @Param   deliveryID  path  string  true  "Delivery ID"
 // @Success 200 {object} domain.Delivery
 // @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string "Rate limited"
 // @Failure 500 {object} map[string]string
 // @Router /deliveries/{deliveryID}/send-now [post]
 func (h *DeliveryHandler) sendNow(w http.ResponseWriter, r *http.Request) {
@@ -189,7 +226,7 @@ func (h *DeliveryHandler) sendNow(w http.ResponseWriter, r *http.Request) {
 
 	delivery, err := h.service.SendNow(r.Context(), deliveryID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeSendError(w, err)
 		return
 	}
 
@@ -204,6 +241,7 @@ func (h *DeliveryHandler) sendNow(w http.ResponseWriter, r *http.Request) {
 // @Param   deliveryID  path  string  true  "Delivery ID"
 // @Success 200 {object} domain.Delivery
 // @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string "Rate limited"
 // @Failure 500 {object} map[string]string
 // @Router /deliveries/{deliveryID}/retry [post]
 func (h *DeliveryHandler) retry(w http.ResponseWriter, r *http.Request) {
@@ -215,9 +253,21 @@ func (h *DeliveryHandler) retry(w http.ResponseWriter, r *http.Request) {
 
 	delivery, err := h.service.Retry(r.Context(), deliveryID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeSendError(w, err)
 		return
 	}
 
 	writeJson(w, http.StatusOK, delivery)
 }
+
+// writeSendError writes the error of a synchronous send. Sends held back by a rate
+// limit get 429 with a Retry-After header.
+func writeSendError(w http.ResponseWriter, err error) {
+	var limited *mailer.RateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.Delay.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,20 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package dto
+
+import "github.com/headmail/headmail/pkg/domain"
+
+// CreateAPIKeyRequest is the request for creating an API key.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes are the granted scopes, e.g. tx:send. The caller must hold each of them.
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeySecretResponse is a created or rotated API key together with the key itself,
+// which is not shown again.
+type APIKeySecretResponse struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
@@ -8,6 +8,8 @@ type CreateListRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags"`
+	// ConfirmationTemplateID enables public double opt-in subscriptions to the list.
+	ConfirmationTemplateID *string `json:"confirmation_template_id,omitempty"`
 }
 
 // UpdateListRequest is the request for updating a list.
@@ -0,0 +1,21 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package dto
+
+// ReplayQueueItemsRequest selects the dead queue items to replay.
+type ReplayQueueItemsRequest struct {
+	Type string `json:"type,omitempty"`
+	// CreatedBefore limits the replay to items created before this unix time.
+	CreatedBefore int64 `json:"created_before,omitempty"`
+}
+
+// ReplayQueueItemsResponse reports how many items were replayed.
+type ReplayQueueItemsResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+// PurgeQueueItemsResponse reports how many items were deleted.
+type PurgeQueueItemsResponse struct {
+	Purged int `json:"purged"`
+}
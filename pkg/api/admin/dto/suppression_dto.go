@@ -0,0 +1,33 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package dto
+
+import "github.com/headmail/headmail/pkg/domain"
+
+// CreateSuppressionRequest is the request for adding a suppression entry.
+type CreateSuppressionRequest struct {
+	// Type is email or domain. When empty it is derived from Value.
+	Type   domain.SuppressionType   `json:"type,omitempty"`
+	Value  string                   `json:"value"`
+	Reason domain.SuppressionReason `json:"reason,omitempty"`
+	Source string                   `json:"source,omitempty"`
+	// ExpiresAt is an optional unix time after which the entry no longer applies.
+	ExpiresAt *int64 `json:"expires_at,omitempty"`
+}
+
+// ImportSuppressionsRequest is the JSON request for importing suppression entries.
+type ImportSuppressionsRequest struct {
+	Suppressions []*CreateSuppressionRequest `json:"suppressions"`
+}
+
+// ImportSuppressionsResponse reports how many entries were imported.
+type ImportSuppressionsResponse struct {
+	Imported int `json:"imported"`
+}
+
+// CheckSuppressionResponse reports whether an address is suppressed and by which entry.
+type CheckSuppressionResponse struct {
+	Suppressed  bool                `json:"suppressed"`
+	Suppression *domain.Suppression `json:"suppression,omitempty"`
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/headmail/headmail/pkg/domain"
@@ -29,21 +30,22 @@ func NewListHandler(service service.ListServiceProvider) *ListHandler {
 
 // RegisterRoutes registers the list routes to the router.
 func (h *ListHandler) RegisterRoutes(r chi.Router) {
+	read, write := auth.RequireScope(auth.ScopeListsRead), auth.RequireScope(auth.ScopeListsWrite)
 	r.Route("/lists", func(r chi.Router) {
-		r.Post("/", h.createList)
-		r.Get("/", h.listLists)
+		r.With(write).Post("/", h.createList)
+		r.With(read).Get("/", h.listLists)
 
 		// Subscribers management under a list
 		r.Route("/{listID}/subscribers", func(r chi.Router) {
-			r.Get("/", h.listSubscribersOfList)
-			r.Patch("/", h.patchSubscribersOfList)
-			r.Put("/", h.replaceSubscribersOfList)
+			r.With(auth.RequireScope(auth.ScopeSubscribersRead)).Get("/", h.listSubscribersOfList)
+			r.With(auth.RequireScope(auth.ScopeSubscribersWrite)).Patch("/", h.patchSubscribersOfList)
+			r.With(auth.RequireScope(auth.ScopeSubscribersWrite)).Put("/", h.replaceSubscribersOfList)
 		})
 
 		r.Route("/{listID}", func(r chi.Router) {
-			r.Get("/", h.getList)
-			r.Put("/", h.updateList)
-			r.Delete("/", h.deleteList)
+			r.With(read).Get("/", h.getList)
+			r.With(write).Put("/", h.updateList)
+			r.With(write).Delete("/", h.deleteList)
 		})
 	})
 }
@@ -67,6 +69,8 @@ func (h *ListHandler) createList(w http.ResponseWriter, r *http.Request) {
 		Name:        req.Name,
 		Description: req.Description,
 		Tags:        req.Tags,
+
+		ConfirmationTemplateID: req.ConfirmationTemplateID,
 	}
 
 	if err := h.service.CreateList(r.Context(), list); err != nil {
@@ -126,6 +130,8 @@ func (h *ListHandler) updateList(w http.ResponseWriter, r *http.Request) {
 		Name:        req.Name,
 		Description: req.Description,
 		Tags:        req.Tags,
+
+		ConfirmationTemplateID: req.ConfirmationTemplateID,
 	}
 
 	if err := h.service.UpdateList(r.Context(), list); err != nil {
@@ -0,0 +1,211 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/service"
+)
+
+// QueueHandler handles HTTP requests for inspecting the work queue.
+type QueueHandler struct {
+	service service.QueueServiceProvider
+}
+
+// NewQueueHandler creates a new QueueHandler.
+func NewQueueHandler(service service.QueueServiceProvider) *QueueHandler {
+	return &QueueHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the queue routes to the router.
+func (h *QueueHandler) RegisterRoutes(r chi.Router) {
+	read, write := auth.RequireScope(auth.ScopeQueueRead), auth.RequireScope(auth.ScopeQueueWrite)
+	r.Route("/queue", func(r chi.Router) {
+		r.With(read).Get("/", h.listItems)
+		r.With(write).Delete("/", h.purgeItems)
+		r.With(write).Post("/replay", h.replayItems)
+		r.Route("/{itemID}", func(r chi.Router) {
+			r.With(read).Get("/", h.getItem)
+			r.With(write).Post("/replay", h.replayItem)
+		})
+	})
+}
+
+// @Summary List queue items
+// @Description List queue items, newest first, including payload and last error
+// @Tags queue
+// @Produce  json
+// @Param   type  query  string  false  "Filter by item type (e.g. delivery)"
+// @Param   status[]  query  []string  false  "Filter by status (pending, reserved, done, dead)"
+// @Param   page  query  int  false  "Page number"
+// @Param   limit  query  int  false  "Number of items per page"
+// @Success 200 {object} PaginatedListResponse[queue.QueueItem]
+// @Router /queue [get]
+func (h *QueueHandler) listItems(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit == 0 {
+		limit = 20
+	}
+
+	filter := queue.Filter{
+		Type: r.URL.Query().Get("type"),
+	}
+	for _, s := range r.URL.Query()["status[]"] {
+		filter.Status = append(filter.Status, queue.Status(s))
+	}
+	pagination := repository.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	items, total, err := h.service.ListItems(r.Context(), filter, pagination)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &PaginatedListResponse[*queue.QueueItem]{
+		Data: items,
+		Pagination: PaginationResponse{
+			Page:  page,
+			Total: total,
+			Limit: limit,
+		},
+	}
+
+	writeJson(w, http.StatusOK, resp)
+}
+
+// @Summary Get a queue item by ID
+// @Description Get a queue item by ID
+// @Tags queue
+// @Produce  json
+// @Param   itemID  path  string  true  "Queue item ID"
+// @Success 200 {object} queue.QueueItem
+// @Router /queue/{itemID} [get]
+func (h *QueueHandler) getItem(w http.ResponseWriter, r *http.Request) {
+	itemID := chi.URLParam(r, "itemID")
+
+	item, err := h.service.GetItem(r.Context(), itemID)
+	if err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusOK, item)
+}
+
+// @Summary Replay a dead queue item
+// @Description Return a dead-lettered item to the queue with its attempts reset
+// @Tags queue
+// @Produce  json
+// @Param   itemID  path  string  true  "Queue item ID"
+// @Success 200 {object} queue.QueueItem
+// @Router /queue/{itemID}/replay [post]
+func (h *QueueHandler) replayItem(w http.ResponseWriter, r *http.Request) {
+	itemID := chi.URLParam(r, "itemID")
+
+	if err := h.service.ReplayItem(r.Context(), itemID); err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err))
+		return
+	}
+
+	item, err := h.service.GetItem(r.Context(), itemID)
+	if err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusOK, item)
+}
+
+// @Summary Replay dead queue items
+// @Description Return all dead-lettered items matching the filter to the queue
+// @Tags queue
+// @Accept  json
+// @Produce  json
+// @Param   filter  body  dto.ReplayQueueItemsRequest  true  "Items to replay"
+// @Success 200 {object} dto.ReplayQueueItemsResponse
+// @Router /queue/replay [post]
+func (h *QueueHandler) replayItems(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReplayQueueItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.service.ReplayItems(r.Context(), queue.Filter{
+		Type:          req.Type,
+		CreatedBefore: req.CreatedBefore,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, http.StatusOK, &dto.ReplayQueueItemsResponse{Replayed: n})
+}
+
+// @Summary Purge finished queue items
+// @Description Delete done and dead items. Pending and reserved items are never purged.
+// @Tags queue
+// @Produce  json
+// @Param   type  query  string  false  "Only purge items of this type"
+// @Param   status[]  query  []string  false  "Statuses to purge (done, dead); both by default"
+// @Param   created_before  query  int  false  "Only purge items created before this unix time"
+// @Success 200 {object} dto.PurgeQueueItemsResponse
+// @Router /queue [delete]
+func (h *QueueHandler) purgeItems(w http.ResponseWriter, r *http.Request) {
+	filter := queue.Filter{
+		Type: r.URL.Query().Get("type"),
+	}
+	for _, s := range r.URL.Query()["status[]"] {
+		filter.Status = append(filter.Status, queue.Status(s))
+	}
+	if v := r.URL.Query().Get("created_before"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid created_before", http.StatusBadRequest)
+			return
+		}
+		filter.CreatedBefore = ts
+	}
+
+	n, err := h.service.PurgeItems(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusOK, &dto.PurgeQueueItemsResponse{Purged: n})
+}
+
+func queueErrorStatus(err error) int {
+	var notFound *repository.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.Is(err, queue.ErrNotReplayable):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNotPurgeable):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/headmail/headmail/pkg/domain"
@@ -29,12 +30,13 @@ func NewSubscriberHandler(service service.ListServiceProvider) *SubscriberHandle
 
 // RegisterRoutes registers the subscriber routes to the router.
 func (h *SubscriberHandler) RegisterRoutes(r chi.Router) {
-	r.Get("/subscribers", h.listSubscribers)
-	r.Post("/subscribers", h.addSubscribers)
+	read, write := auth.RequireScope(auth.ScopeSubscribersRead), auth.RequireScope(auth.ScopeSubscribersWrite)
+	r.With(read).Get("/subscribers", h.listSubscribers)
+	r.With(write).Post("/subscribers", h.addSubscribers)
 	r.Route("/subscribers/{subscriberID}", func(r chi.Router) {
-		r.Get("/", h.getSubscriber)
-		r.Put("/", h.updateSubscriber)
-		r.Delete("/", h.deleteSubscriber)
+		r.With(read).Get("/", h.getSubscriber)
+		r.With(write).Put("/", h.updateSubscriber)
+		r.With(write).Delete("/", h.deleteSubscriber)
 	})
 }
 
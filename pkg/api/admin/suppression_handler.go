@@ -0,0 +1,276 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/service"
+)
+
+// SuppressionHandler handles HTTP requests for the global suppression list.
+type SuppressionHandler struct {
+	service service.SuppressionServiceProvider
+}
+
+// NewSuppressionHandler creates a new SuppressionHandler.
+func NewSuppressionHandler(service service.SuppressionServiceProvider) *SuppressionHandler {
+	return &SuppressionHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the suppression routes to the router.
+func (h *SuppressionHandler) RegisterRoutes(r chi.Router) {
+	read, write := auth.RequireScope(auth.ScopeSuppressionsRead), auth.RequireScope(auth.ScopeSuppressionsWrite)
+	r.Route("/suppressions", func(r chi.Router) {
+		r.With(read).Get("/", h.listSuppressions)
+		r.With(write).Post("/", h.createSuppression)
+		r.With(write).Post("/import", h.importSuppressions)
+		r.With(read).Get("/check", h.checkSuppression)
+		r.Route("/{suppressionID}", func(r chi.Router) {
+			r.With(read).Get("/", h.getSuppression)
+			r.With(write).Delete("/", h.deleteSuppression)
+		})
+	})
+}
+
+// @Summary List suppression entries
+// @Description List suppression entries, newest first
+// @Tags suppressions
+// @Produce  json
+// @Param   type  query  string  false  "Filter by type (email, domain)"
+// @Param   reason  query  string  false  "Filter by reason (hard_bounce, complaint, manual)"
+// @Param   search  query  string  false  "Search term"
+// @Param   page  query  int  false  "Page number"
+// @Param   limit  query  int  false  "Number of items per page"
+// @Success 200 {object} PaginatedListResponse[domain.Suppression]
+// @Router /suppressions [get]
+func (h *SuppressionHandler) listSuppressions(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit == 0 {
+		limit = 20
+	}
+
+	filter := repository.SuppressionFilter{
+		Type:   domain.SuppressionType(r.URL.Query().Get("type")),
+		Reason: domain.SuppressionReason(r.URL.Query().Get("reason")),
+		Search: r.URL.Query().Get("search"),
+	}
+	pagination := repository.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	suppressions, total, err := h.service.ListSuppressions(r.Context(), filter, pagination)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &PaginatedListResponse[*domain.Suppression]{
+		Data: suppressions,
+		Pagination: PaginationResponse{
+			Page:  page,
+			Total: total,
+			Limit: limit,
+		},
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// @Summary Add a suppression entry
+// @Description Suppresses an email address or a whole domain. Adding an existing value updates its reason, source and expiry.
+// @Tags suppressions
+// @Accept  json
+// @Produce  json
+// @Param   suppression  body  dto.CreateSuppressionRequest  true  "Entry to add"
+// @Success 201 {object} domain.Suppression
+// @Router /suppressions [post]
+func (h *SuppressionHandler) createSuppression(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateSuppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suppression := toSuppression(&req)
+	if err := h.service.CreateSuppression(r.Context(), suppression); err != nil {
+		http.Error(w, err.Error(), suppressionErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusCreated, suppression)
+}
+
+// @Summary Import suppression entries
+// @Description Adds many entries at once, all or nothing. Accepts a JSON body, or a text/csv body with the columns value, reason and expires_at (unix time); only value is required and a header row starting with "value" is skipped.
+// @Tags suppressions
+// @Accept  json,text/csv
+// @Produce  json
+// @Param   request  body  dto.ImportSuppressionsRequest  true  "Entries to import"
+// @Success 200 {object} dto.ImportSuppressionsResponse
+// @Router /suppressions/import [post]
+func (h *SuppressionHandler) importSuppressions(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportSuppressionsRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		entries, err := readSuppressionsCSV(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Suppressions = entries
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suppressions := make([]*domain.Suppression, 0, len(req.Suppressions))
+	for _, entry := range req.Suppressions {
+		suppressions = append(suppressions, toSuppression(entry))
+	}
+	n, err := h.service.ImportSuppressions(r.Context(), suppressions)
+	if err != nil {
+		http.Error(w, err.Error(), suppressionErrorStatus(err))
+		return
+	}
+
+	writeJson(w, http.StatusOK, &dto.ImportSuppressionsResponse{Imported: n})
+}
+
+// @Summary Check an address
+// @Description Reports whether mail to the address is suppressed, by its own entry or its domain's.
+// @Tags suppressions
+// @Produce  json
+// @Param   email  query  string  true  "Email address"
+// @Success 200 {object} dto.CheckSuppressionResponse
+// @Router /suppressions/check [get]
+func (h *SuppressionHandler) checkSuppression(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	suppression, err := h.service.CheckSuppression(r.Context(), email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, http.StatusOK, &dto.CheckSuppressionResponse{
+		Suppressed:  suppression != nil,
+		Suppression: suppression,
+	})
+}
+
+// @Summary Get a suppression entry by ID
+// @Description Get a suppression entry by ID
+// @Tags suppressions
+// @Produce  json
+// @Param   suppressionID  path  string  true  "Suppression ID"
+// @Success 200 {object} domain.Suppression
+// @Router /suppressions/{suppressionID} [get]
+func (h *SuppressionHandler) getSuppression(w http.ResponseWriter, r *http.Request) {
+	suppression, err := h.service.GetSuppression(r.Context(), chi.URLParam(r, "suppressionID"))
+	if err != nil {
+		http.Error(w, err.Error(), suppressionErrorStatus(err))
+		return
+	}
+	writeJson(w, http.StatusOK, suppression)
+}
+
+// @Summary Delete a suppression entry
+// @Description Deletes an entry so the address or domain can be mailed again
+// @Tags suppressions
+// @Produce  json
+// @Param   suppressionID  path  string  true  "Suppression ID"
+// @Success 200 {object} DeleteResponse
+// @Router /suppressions/{suppressionID} [delete]
+func (h *SuppressionHandler) deleteSuppression(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteSuppression(r.Context(), chi.URLParam(r, "suppressionID")); err != nil {
+		http.Error(w, err.Error(), suppressionErrorStatus(err))
+		return
+	}
+
+	resp := DeleteResponse{
+		Deleted: true,
+		Message: "Suppression deleted successfully",
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+func toSuppression(req *dto.CreateSuppressionRequest) *domain.Suppression {
+	return &domain.Suppression{
+		Type:      req.Type,
+		Value:     req.Value,
+		Reason:    req.Reason,
+		Source:    req.Source,
+		ExpiresAt: req.ExpiresAt,
+	}
+}
+
+// readSuppressionsCSV reads rows of value[,reason[,expires_at]].
+func readSuppressionsCSV(body io.Reader) ([]*dto.CreateSuppressionRequest, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []*dto.CreateSuppressionRequest
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "value") {
+			continue
+		}
+
+		entry := &dto.CreateSuppressionRequest{Value: record[0]}
+		if len(record) > 1 {
+			entry.Reason = domain.SuppressionReason(record[1])
+		}
+		if len(record) > 2 && record[2] != "" {
+			ts, err := strconv.ParseInt(record[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid expires_at '%s'", line, record[2])
+			}
+			entry.ExpiresAt = &ts
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func suppressionErrorStatus(err error) int {
+	var notFound *repository.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidSuppression):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
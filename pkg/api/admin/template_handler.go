@@ -11,6 +11,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/headmail/headmail/pkg/api/admin/dto"
+	"github.com/headmail/headmail/pkg/audit"
+	"github.com/headmail/headmail/pkg/auth"
 	"github.com/headmail/headmail/pkg/domain"
 	"github.com/headmail/headmail/pkg/repository"
 	"github.com/headmail/headmail/pkg/service"
@@ -32,16 +34,17 @@ func NewTemplateHandler(service service.TemplateServiceProvider, deliveryService
 
 // RegisterRoutes registers the template routes to the router.
 func (h *TemplateHandler) RegisterRoutes(r chi.Router) {
+	read, write := auth.RequireScope(auth.ScopeTemplatesRead), auth.RequireScope(auth.ScopeTemplatesWrite)
 	r.Route("/templates", func(r chi.Router) {
 		// server-side preview endpoint used by the editor to render templates with sample data
-		r.Post("/preview", h.previewTemplate)
+		r.With(read, audit.Skip).Post("/preview", h.previewTemplate)
 
-		r.Post("/", h.createTemplate)
-		r.Get("/", h.listTemplates)
+		r.With(write).Post("/", h.createTemplate)
+		r.With(read).Get("/", h.listTemplates)
 		r.Route("/{templateID}", func(r chi.Router) {
-			r.Get("/", h.getTemplate)
-			r.Put("/", h.updateTemplate)
-			r.Delete("/", h.deleteTemplate)
+			r.With(read).Get("/", h.getTemplate)
+			r.With(write).Put("/", h.updateTemplate)
+			r.With(write).Delete("/", h.deleteTemplate)
 		})
 	})
 }
@@ -5,7 +5,10 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/headmail/headmail/pkg/mailer"
 )
 
 func writeJson(w http.ResponseWriter, statusCode int, resp interface{}) {
@@ -13,3 +16,12 @@ func writeJson(w http.ResponseWriter, statusCode int, resp interface{}) {
 	w.WriteHeader(statusCode)
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// messageErrorStatus maps errors from validating a message's sender and headers to
+// 400 Bad Request and everything else to 500.
+func messageErrorStatus(err error) int {
+	if errors.Is(err, mailer.ErrSenderNotAllowed) || errors.Is(err, mailer.ErrInvalidHeader) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package public
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Boostport/mjml-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/service"
+	"github.com/headmail/headmail/pkg/template"
+)
+
+// defaultPreferencesTemplate is used when no preference center template is configured.
+const defaultPreferencesTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Email preferences</title>
+</head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto; padding: 0 1em;">
+<h1>Email preferences</h1>
+<p>Choose which emails {{ .email }} receives.</p>
+{{- if .saved }}
+<p><strong>Your preferences have been saved.</strong></p>
+{{- end }}
+<form method="post">
+{{- range .lists }}
+<p>
+<label>
+<input type="checkbox" name="list" value="{{ .id }}"{{ if .subscribed }} checked{{ end }}{{ if not .editable }} disabled{{ end }}>
+<strong>{{ .name }}</strong>
+</label>
+{{- if .description }}<br>{{ .description }}{{ end }}
+</p>
+{{- else }}
+<p>You are not subscribed to any list.</p>
+{{- end }}
+<button type="submit" name="action" value="save">Save preferences</button>
+<button type="submit" name="action" value="unsubscribe_all">Unsubscribe from all</button>
+</form>
+</body>
+</html>
+`
+
+// PreferencesHandler serves the hosted subscription preference center.
+type PreferencesHandler struct {
+	cfg             *config.PreferencesConfig
+	service         service.SubscriptionServiceProvider
+	templateService *template.Service
+}
+
+// NewPreferencesHandler creates a new PreferencesHandler.
+func NewPreferencesHandler(cfg *config.PreferencesConfig, service service.SubscriptionServiceProvider, templateService *template.Service) *PreferencesHandler {
+	return &PreferencesHandler{
+		cfg:             cfg,
+		service:         service,
+		templateService: templateService,
+	}
+}
+
+// RegisterRoutes registers preference center routes on the provided router.
+func (h *PreferencesHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/p/{token}", h.getHandler)
+	r.Post("/p/{token}", h.updateHandler)
+}
+
+// @Summary Preference center
+// @Description Shows the lists the subscriber of the signed link belongs to.
+// @Tags preferences
+// @Param token path string true "Preference center token"
+// @Produce html
+// @Success 200 {string} string "Preference center page"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Subscriber not found"
+// @Router /p/{token} [get]
+func (h *PreferencesHandler) getHandler(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.service.GetPreferences(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	h.writePage(w, r, prefs, false)
+}
+
+// @Summary Update preferences
+// @Description Keeps the subscriber subscribed to the checked lists ("list" form values) and unsubscribes them from the others. action=unsubscribe_all unsubscribes from every list.
+// @Tags preferences
+// @Param token path string true "Preference center token"
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 200 {string} string "Preference center page"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Subscriber not found"
+// @Router /p/{token} [post]
+func (h *PreferencesHandler) updateHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	unsubscribeAll := r.PostFormValue("action") == "unsubscribe_all"
+
+	prefs, err := h.service.UpdatePreferences(r.Context(), chi.URLParam(r, "token"), r.PostForm["list"], unsubscribeAll)
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	h.writePage(w, r, prefs, true)
+}
+
+func (h *PreferencesHandler) writePage(w http.ResponseWriter, r *http.Request, prefs *service.Preferences, saved bool) {
+	lists := make([]map[string]interface{}, 0, len(prefs.Lists))
+	for _, p := range prefs.Lists {
+		lists = append(lists, map[string]interface{}{
+			"id":          p.List.ID,
+			"name":        p.List.Name,
+			"description": p.List.Description,
+			"status":      string(p.Status),
+			"subscribed":  p.Status == domain.SubscriberListStatusConfirmed,
+			"editable":    p.Editable(),
+		})
+	}
+	data := map[string]interface{}{
+		"name":  prefs.Subscriber.Name,
+		"email": prefs.Subscriber.Email,
+		"lists": lists,
+		"saved": saved,
+	}
+
+	page, err := h.templateService.RenderHTML(h.pageTemplate(), data)
+	if err == nil && strings.HasPrefix(strings.TrimSpace(page), "<mjml") {
+		page, err = mjml.ToHTML(r.Context(), page, mjml.WithValidationLevel(mjml.Skip))
+	}
+	if err != nil {
+		log.Printf("render preference center failed: %+v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(page))
+}
+
+// pageTemplate returns the configured template, read on every request so it can be
+// edited without a restart, or the built-in one.
+func (h *PreferencesHandler) pageTemplate() string {
+	if h.cfg.TemplatePath == "" {
+		return defaultPreferencesTemplate
+	}
+	data, err := os.ReadFile(h.cfg.TemplatePath)
+	if err != nil {
+		log.Printf("read preference center template %s failed: %v", h.cfg.TemplatePath, err)
+		return defaultPreferencesTemplate
+	}
+	return string(data)
+}
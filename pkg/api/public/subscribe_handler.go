@@ -0,0 +1,173 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/service"
+)
+
+var subscribePage = template.Must(template.New("subscribe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Subscription</title>
+</head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto; padding: 0 1em;">
+{{- if eq .State "pending" }}
+<h1>Check your inbox</h1>
+<p>We have sent a confirmation link to {{ .Email }}. Click it to complete your subscription.</p>
+{{- else if eq .State "confirm" }}
+<h1>Confirm subscription</h1>
+<p>Subscribe {{ .Email }} to {{ .List }}?</p>
+<form method="post">
+<button type="submit">Confirm subscription</button>
+</form>
+{{- else }}
+<h1>Subscription confirmed</h1>
+<p>{{ .Email }} is now subscribed to {{ .List }}.</p>
+{{- end }}
+</body>
+</html>
+`))
+
+// SubscribeRequest is the JSON request of a public subscription.
+type SubscribeRequest struct {
+	Email string   `json:"email"`
+	Name  string   `json:"name"`
+	Lists []string `json:"lists"`
+}
+
+// SubscribeHandler handles double opt-in subscriptions from public sign-up forms.
+type SubscribeHandler struct {
+	service service.SubscriptionServiceProvider
+}
+
+// NewSubscribeHandler creates a new SubscribeHandler.
+func NewSubscribeHandler(service service.SubscriptionServiceProvider) *SubscribeHandler {
+	return &SubscribeHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers subscription routes on the provided router.
+func (h *SubscribeHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/subscribe", h.subscribeHandler)
+	r.Get("/subscribe/confirm/{token}", h.confirmPageHandler)
+	r.Post("/subscribe/confirm/{token}", h.confirmHandler)
+}
+
+// @Summary Subscribe to lists
+// @Description Creates pending memberships and sends a confirmation mail for every list. Accepts a JSON body (answered with JSON) or a form with "email", "name" and one or more "list" values (answered with an HTML page). Only lists with a confirmation template accept subscriptions. The response does not reveal whether the address was already subscribed.
+// @Tags subscribe
+// @Accept json,x-www-form-urlencoded
+// @Produce json,html
+// @Param request body SubscribeRequest false "Subscription"
+// @Success 202 {object} map[string]string "Confirmation pending"
+// @Failure 400 {string} string "Invalid email or list"
+// @Router /subscribe [post]
+func (h *SubscribeHandler) subscribeHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isJSON := mediaType == "application/json"
+
+	var req SubscribeRequest
+	if isJSON {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		req = SubscribeRequest{
+			Email: r.PostFormValue("email"),
+			Name:  r.PostFormValue("name"),
+			Lists: r.PostForm["list"],
+		}
+	}
+
+	if err := h.service.Subscribe(r.Context(), req.Email, req.Name, req.Lists); err != nil {
+		if errors.Is(err, service.ErrInvalidSubscription) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("subscribe failed: %+v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "pending"})
+		return
+	}
+	writeSubscribePage(w, http.StatusAccepted, "pending", req.Email, "")
+}
+
+// @Summary Subscription confirmation page
+// @Description Shows a confirmation page for the link in a confirmation mail. It does not confirm, so link scanners that prefetch URLs cannot confirm subscriptions.
+// @Tags subscribe
+// @Param token path string true "Confirmation token"
+// @Produce html
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Subscription expired"
+// @Router /subscribe/confirm/{token} [get]
+func (h *SubscribeHandler) confirmPageHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := h.service.ResolveConfirmation(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	state := "confirm"
+	if c.Status == domain.SubscriberListStatusConfirmed {
+		state = "confirmed"
+	}
+	writeSubscribePage(w, http.StatusOK, state, c.Subscriber.Email, c.List.Name)
+}
+
+// @Summary Confirm subscription
+// @Description Confirms the pending subscription of a confirmation link.
+// @Tags subscribe
+// @Param token path string true "Confirmation token"
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 200 {string} string "Subscription confirmed"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Subscription expired"
+// @Router /subscribe/confirm/{token} [post]
+func (h *SubscribeHandler) confirmHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := h.service.ConfirmSubscription(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	writeSubscribePage(w, http.StatusOK, "confirmed", c.Subscriber.Email, c.List.Name)
+}
+
+func writeSubscribePage(w http.ResponseWriter, status int, state string, email string, list string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := subscribePage.Execute(w, struct {
+		State string
+		Email string
+		List  string
+	}{State: state, Email: email, List: list}); err != nil {
+		log.Printf("render subscribe page failed: %+v", err)
+	}
+}
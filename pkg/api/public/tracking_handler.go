@@ -5,6 +5,7 @@ package public
 
 import (
 	"encoding/base64"
+	"errors"
 	"log"
 	"net"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/repository"
 	"github.com/headmail/headmail/pkg/service"
 )
 
@@ -39,12 +41,14 @@ func NewTrackingHandler(cfg *config.TrackingConfig, service service.TrackingServ
 func (h *TrackingHandler) RegisterRoutes(r chi.Router) {
 	r.Get("/r/{deliveryID}/o", h.openHandler)
 	r.Get("/r/{deliveryID}/c", h.clickHandler)
+	r.Get("/r/{deliveryID}/l/{linkID}", h.linkHandler)
 }
 
 // @Summary Track open (1x1 pixel)
-// @Description Records an open event for a delivery and returns a 1x1 transparent PNG (or configured image).
+// @Description Records an open event for a delivery and returns a 1x1 transparent PNG (or configured image). Opens with an invalid signature are not recorded, but the image is still served.
 // @Tags tracking
 // @Param deliveryID path string true "Delivery ID"
+// @Param s query string true "Signature"
 // @Produce image/png
 // @Success 200 {file} binary image
 // @Failure 400 {object} map[string]string
@@ -56,12 +60,14 @@ func (h *TrackingHandler) openHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	ua := r.UserAgent()
-	ip := extractRemoteIP(r)
+	if h.service.VerifyOpen(r.Context(), deliveryID, r.URL.Query().Get("s")) {
+		ctx := r.Context()
+		ua := r.UserAgent()
+		ip := extractRemoteIP(r)
 
-	if err := h.service.LogOpenEvent(ctx, deliveryID, &ua, &ip); err != nil {
-		log.Printf("log open event failed: %+v", err)
+		if err := h.service.LogOpenEvent(ctx, deliveryID, &ua, &ip); err != nil {
+			log.Printf("log open event failed: %+v", err)
+		}
 	}
 
 	// Serve image
@@ -91,12 +97,14 @@ func (h *TrackingHandler) openHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Track click and redirect
-// @Description Records a click event and redirects to the original URL.
+// @Description Records a click event and redirects to the original URL. The signature must match the delivery and target, so the endpoint cannot redirect elsewhere.
 // @Tags tracking
 // @Param deliveryID path string true "Delivery ID"
 // @Param u query string true "URL encoded target"
+// @Param s query string true "Signature"
 // @Success 302 "Redirect"
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /r/{deliveryID}/c [get]
 func (h *TrackingHandler) clickHandler(w http.ResponseWriter, r *http.Request) {
 	deliveryID := chi.URLParam(r, "deliveryID")
@@ -109,12 +117,11 @@ func (h *TrackingHandler) clickHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing url param 'u'", http.StatusBadRequest)
 		return
 	}
-	decoded, err := url.QueryUnescape(u)
-	if err != nil {
-		http.Error(w, "invalid url", http.StatusBadRequest)
+	if !h.service.VerifyClick(r.Context(), deliveryID, u, r.URL.Query().Get("s")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
 		return
 	}
-	target, err := url.Parse(decoded)
+	target, err := url.Parse(u)
 	if err != nil || !isAllowedScheme(target.Scheme) {
 		http.Error(w, "invalid or unsupported url scheme", http.StatusBadRequest)
 		return
@@ -124,12 +131,51 @@ func (h *TrackingHandler) clickHandler(w http.ResponseWriter, r *http.Request) {
 	ua := r.UserAgent()
 	ip := extractRemoteIP(r)
 
-	if err := h.service.LogClickEvent(ctx, deliveryID, &ua, &ip, decoded); err != nil {
+	if err := h.service.LogClickEvent(ctx, deliveryID, &ua, &ip, u); err != nil {
 		log.Printf("log click event failed: %+v", err)
 	}
 
 	// Redirect
-	http.Redirect(w, r, decoded, http.StatusFound)
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// @Summary Track short link click and redirect
+// @Description Records a click on a tracked campaign link and redirects to its URL. The signature must match the delivery and link.
+// @Tags tracking
+// @Param deliveryID path string true "Delivery ID"
+// @Param linkID path string true "Link ID"
+// @Param s query string true "Signature"
+// @Success 302 "Redirect"
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /r/{deliveryID}/l/{linkID} [get]
+func (h *TrackingHandler) linkHandler(w http.ResponseWriter, r *http.Request) {
+	deliveryID := chi.URLParam(r, "deliveryID")
+	linkID := chi.URLParam(r, "linkID")
+	if !h.service.VerifyLink(deliveryID, linkID, r.URL.Query().Get("s")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	ctx := r.Context()
+	link, err := h.service.GetLink(ctx, linkID)
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			http.Error(w, "link not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ua := r.UserAgent()
+	ip := extractRemoteIP(r)
+	if err := h.service.LogLinkClick(ctx, deliveryID, link, &ua, &ip); err != nil {
+		log.Printf("log click event failed: %+v", err)
+	}
+
+	http.Redirect(w, r, link.URL, http.StatusFound)
 }
 
 func isAllowedScheme(s string) bool {
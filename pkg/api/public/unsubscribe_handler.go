@@ -0,0 +1,126 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package public
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/service"
+	"github.com/headmail/headmail/pkg/signing"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Unsubscribe</title>
+</head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto; padding: 0 1em;">
+{{- if .Done }}
+<h1>You have been unsubscribed</h1>
+<p>{{ .Email }} will no longer receive these emails.</p>
+{{- else }}
+<h1>Unsubscribe</h1>
+<p>Stop sending these emails to {{ .Email }}?</p>
+<form method="post">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<button type="submit">Unsubscribe</button>
+</form>
+{{- end }}
+</body>
+</html>
+`))
+
+// UnsubscribeHandler handles one-click unsubscribe requests from mail clients and recipients.
+type UnsubscribeHandler struct {
+	service service.SubscriptionServiceProvider
+}
+
+// NewUnsubscribeHandler creates a new UnsubscribeHandler.
+func NewUnsubscribeHandler(service service.SubscriptionServiceProvider) *UnsubscribeHandler {
+	return &UnsubscribeHandler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers unsubscribe routes on the provided router.
+func (h *UnsubscribeHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/u/{token}", h.confirmHandler)
+	r.Post("/u/{token}", h.unsubscribeHandler)
+}
+
+// @Summary Unsubscribe confirmation page
+// @Description Shows a confirmation page for the unsubscribe link in a delivery. It does not unsubscribe, so link scanners that prefetch URLs cannot unsubscribe recipients.
+// @Tags unsubscribe
+// @Param token path string true "Unsubscribe token"
+// @Produce html
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Delivery not found"
+// @Router /u/{token} [get]
+func (h *UnsubscribeHandler) confirmHandler(w http.ResponseWriter, r *http.Request) {
+	d, err := h.service.ResolveUnsubscribe(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	writeUnsubscribePage(w, d.Email, false)
+}
+
+// @Summary Unsubscribe
+// @Description One-click unsubscribe (RFC 8058). Removes the recipient from the list the delivery was sent to, or from all lists for deliveries not sent to a list.
+// @Tags unsubscribe
+// @Param token path string true "Unsubscribe token"
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 200 {string} string "Unsubscribed"
+// @Failure 400 {string} string "Invalid token"
+// @Failure 404 {string} string "Delivery not found"
+// @Router /u/{token} [post]
+func (h *UnsubscribeHandler) unsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := chi.URLParam(r, "token")
+	ua := r.UserAgent()
+	ip := extractRemoteIP(r)
+
+	d, err := h.service.Unsubscribe(ctx, token, &ua, &ip)
+	if err != nil {
+		writeLinkError(w, err)
+		return
+	}
+	writeUnsubscribePage(w, d.Email, true)
+}
+
+func writeUnsubscribePage(w http.ResponseWriter, email string, done bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := unsubscribePage.Execute(w, struct {
+		Email string
+		Done  bool
+	}{Email: email, Done: done}); err != nil {
+		log.Printf("render unsubscribe page failed: %+v", err)
+	}
+}
+
+// writeLinkError maps errors of signed recipient links to HTTP responses.
+func writeLinkError(w http.ResponseWriter, err error) {
+	var notFound *repository.ErrNotFound
+	switch {
+	case errors.Is(err, signing.ErrInvalidToken):
+		http.Error(w, "invalid link", http.StatusBadRequest)
+	case errors.As(err, &notFound):
+		http.Error(w, "link expired", http.StatusNotFound)
+	default:
+		log.Printf("handle signed link failed: %+v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
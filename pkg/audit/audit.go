@@ -0,0 +1,259 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package audit records mutating admin API calls in the audit log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// maxCreatedResponse limits the response body read for the ID of a created entity.
+const maxCreatedResponse = 1 << 20
+
+// resources maps the path segments of admin routes to entity types.
+var resources = map[string]string{
+	"campaigns":    "campaign",
+	"lists":        "list",
+	"subscribers":  "subscriber",
+	"templates":    "template",
+	"deliveries":   "delivery",
+	"tx":           "delivery",
+	"queue":        "queue_item",
+	"suppressions": "suppression",
+	"api-keys":     "api_key",
+}
+
+// methodActions are the actions of calls on an entity or collection itself.
+var methodActions = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// Recorder stores audit log entries.
+type Recorder interface {
+	RecordAudit(ctx context.Context, entry *domain.AuditEntry) error
+}
+
+// Loader returns the current state of an entity. A not found error or a nil result
+// means the entity does not exist.
+type Loader func(ctx context.Context, id string) (interface{}, error)
+
+type skipKey struct{}
+
+// Skip excludes calls that change nothing, such as previews, from the audit log. It
+// must run inside Middleware.
+func Skip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if skip, ok := r.Context().Value(skipKey{}).(*bool); ok {
+			*skip = true
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware records successful calls with mutating methods. routes resolves the
+// route of a call before its handler runs, which gives the action, entity type and
+// entity ID. For entity types with a loader the fields changed by the call are
+// recorded too.
+func Middleware(routes chi.Routes, recorder Recorder, loaders map[string]Loader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := methodActions[r.Method]; !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rctx := chi.NewRouteContext()
+			pattern := routes.Find(rctx, r.Method, r.URL.Path)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			entry := newEntry(r, pattern, rctx)
+
+			load := loaders[entry.EntityType]
+			var before interface{}
+			if load != nil && entry.EntityID != "" {
+				before = loadEntity(r.Context(), load, entry)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var created *limitedBuffer
+			if load != nil && entry.EntityID == "" {
+				created = &limitedBuffer{}
+				ww.Tee(created)
+			}
+			skip := false
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), skipKey{}, &skip)))
+			if skip || ww.Status() >= http.StatusBadRequest {
+				return
+			}
+
+			// record calls completed before the client went away
+			ctx := context.WithoutCancel(r.Context())
+			if created != nil {
+				entry.EntityID = createdID(created.data)
+			}
+			if load != nil {
+				var after interface{}
+				if entry.EntityID != "" {
+					after = loadEntity(ctx, load, entry)
+				}
+				changes, err := Diff(before, after)
+				if err != nil {
+					log.Printf("audit: diff %s %s failed: %v", entry.EntityType, entry.EntityID, err)
+				}
+				entry.Changes = changes
+			}
+			if p := auth.PrincipalFromContext(r.Context()); p != nil {
+				entry.Principal = p.String()
+			}
+			if err := recorder.RecordAudit(ctx, entry); err != nil {
+				log.Printf("audit: record %s %s failed: %v", entry.Action, entry.EntityID, err)
+			}
+		})
+	}
+}
+
+// newEntry derives action, entity type and ID of a call from its route pattern, e.g.
+// /api/campaigns/{campaignID}/status is the campaign.status action on the campaign
+// with the ID of the campaignID parameter.
+func newEntry(r *http.Request, pattern string, rctx *chi.Context) *domain.AuditEntry {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	i := 0
+	for j, s := range segments {
+		if _, ok := resources[s]; ok {
+			i = j
+			break
+		}
+	}
+	entityType, ok := resources[segments[i]]
+	if !ok {
+		entityType = segments[i]
+	}
+	entry := &domain.AuditEntry{
+		EntityType: entityType,
+		Action:     entityType + "." + methodActions[r.Method],
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+	rest := segments[i+1:]
+	if len(rest) > 0 {
+		if name, ok := param(rest[0]); ok {
+			entry.EntityID = rctx.URLParam(name)
+		}
+		if last := rest[len(rest)-1]; !isParam(last) {
+			entry.Action = entityType + "." + last
+		}
+	}
+	return entry
+}
+
+func param(segment string) (string, bool) {
+	if !isParam(segment) {
+		return "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+	// strip regexp constraints such as {id:[0-9]+}
+	name, _, _ = strings.Cut(name, ":")
+	return name, true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// loadEntity returns the state of the entity of entry, or nil when it does not exist
+// or cannot be loaded.
+func loadEntity(ctx context.Context, load Loader, entry *domain.AuditEntry) interface{} {
+	v, err := load(ctx, entry.EntityID)
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if !errors.As(err, &notFound) {
+			log.Printf("audit: load %s %s failed: %v", entry.EntityType, entry.EntityID, err)
+		}
+		return nil
+	}
+	return v
+}
+
+// createdID returns the ID of the entity in a JSON response body, or "".
+func createdID(body []byte) string {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(body, &created) != nil {
+		return ""
+	}
+	return created.ID
+}
+
+// Diff returns the top-level JSON fields of before and after whose values differ.
+// Either may be nil, so creations and deletions list every field.
+func Diff(before, after interface{}) (map[string]domain.AuditChange, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]domain.AuditChange)
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = domain.AuditChange{Before: v, After: w}
+		}
+	}
+	for k, w := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = domain.AuditChange{After: w}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
+
+// toFields returns v as a JSON object, or nil for nil values.
+func toFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// limitedBuffer keeps the first maxCreatedResponse bytes written to it.
+type limitedBuffer struct {
+	data []byte
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := maxCreatedResponse - len(b.data); n > 0 {
+		b.data = append(b.data, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
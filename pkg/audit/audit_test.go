@@ -0,0 +1,102 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder []*domain.AuditEntry
+
+func (r *recorder) RecordAudit(_ context.Context, entry *domain.AuditEntry) error {
+	*r = append(*r, entry)
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	lists := map[string]*domain.List{"list-1": {ID: "list-1", Name: "News"}}
+	load := func(_ context.Context, id string) (interface{}, error) {
+		if l, ok := lists[id]; ok {
+			copied := *l
+			return &copied, nil
+		}
+		return nil, &repository.ErrNotFound{Entity: "List", ID: id}
+	}
+	var entries recorder
+
+	root := chi.NewRouter()
+	root.Route("/api", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Method: auth.MethodJWT, Subject: "alice"})))
+			})
+		})
+		r.Use(Middleware(root, &entries, map[string]Loader{"list": load}))
+		r.Route("/lists", func(r chi.Router) {
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+				lists["list-2"] = &domain.List{ID: "list-2", Name: "Offers"}
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(lists["list-2"])
+			})
+			r.Get("/{listID}", func(w http.ResponseWriter, r *http.Request) {})
+			r.Put("/{listID}", func(w http.ResponseWriter, r *http.Request) {
+				lists[chi.URLParam(r, "listID")].Name = "Weekly news"
+			})
+			r.Delete("/{listID}", func(w http.ResponseWriter, r *http.Request) {
+				delete(lists, chi.URLParam(r, "listID"))
+			})
+			r.With(Skip).Post("/preview", func(w http.ResponseWriter, r *http.Request) {})
+		})
+		r.Post("/queue/{itemID}/replay", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "not found", http.StatusNotFound)
+		})
+		r.Post("/queue/replay", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	call := func(method, path string) {
+		root.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, strings.NewReader("{}")))
+	}
+
+	call(http.MethodPut, "/api/lists/list-1")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "jwt:alice", entries[0].Principal)
+	assert.Equal(t, "list.update", entries[0].Action)
+	assert.Equal(t, "list", entries[0].EntityType)
+	assert.Equal(t, "list-1", entries[0].EntityID)
+	assert.Equal(t, map[string]domain.AuditChange{"name": {Before: "News", After: "Weekly news"}}, entries[0].Changes)
+
+	call(http.MethodPost, "/api/lists")
+	require.Len(t, entries, 2)
+	assert.Equal(t, "list.create", entries[1].Action)
+	assert.Equal(t, "list-2", entries[1].EntityID, "the ID of created entities is read from the response")
+	assert.Equal(t, domain.AuditChange{Before: nil, After: "Offers"}, entries[1].Changes["name"])
+
+	call(http.MethodDelete, "/api/lists/list-2")
+	require.Len(t, entries, 3)
+	assert.Equal(t, "list.delete", entries[2].Action)
+	assert.Equal(t, domain.AuditChange{Before: "Offers", After: nil}, entries[2].Changes["name"])
+
+	call(http.MethodPost, "/api/queue/replay")
+	require.Len(t, entries, 4)
+	assert.Equal(t, "queue_item.replay", entries[3].Action)
+	assert.Empty(t, entries[3].EntityID)
+	assert.Nil(t, entries[3].Changes)
+
+	// reads, skipped calls and failed calls are not recorded
+	call(http.MethodGet, "/api/lists/list-1")
+	call(http.MethodPost, "/api/lists/preview")
+	call(http.MethodPost, "/api/queue/item-1/replay")
+	assert.Len(t, entries, 4)
+}
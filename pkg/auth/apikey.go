@@ -0,0 +1,57 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/headmail/headmail/pkg/config"
+)
+
+// APIKeyHeader is the header API keys can be sent in instead of a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+type apiKey struct {
+	name string
+	hash []byte
+}
+
+// APIKeyAuthenticator accepts the static API keys of the configuration, which only
+// holds their SHA-256 hashes.
+type APIKeyAuthenticator struct {
+	keys []apiKey
+}
+
+// NewAPIKeyAuthenticator creates an APIKeyAuthenticator for the configured keys.
+func NewAPIKeyAuthenticator(keys []config.APIKeyConfig) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{}
+	for _, k := range keys {
+		hash, err := hex.DecodeString(strings.TrimSpace(k.SHA256))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key '%s': sha256 must be a hex encoded SHA-256 hash", k.Name)
+		}
+		a.keys = append(a.keys, apiKey{name: k.Name, hash: hash})
+	}
+	return a, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := requestAPIKey(r)
+	if key == "" {
+		return nil, nil
+	}
+	hash := sha256.Sum256([]byte(key))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash) == 1 {
+			return &Principal{Method: MethodAPIKey, Subject: k.name}, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
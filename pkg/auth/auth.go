@@ -0,0 +1,165 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package auth authenticates requests to the admin API.
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Authentication methods, as selected in Server.Admin.Auth.Methods.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodMTLS   = "mtls"
+)
+
+// Scopes of stored API keys. Each admin API route requires one of them.
+const (
+	ScopeListsRead         = "lists:read"
+	ScopeListsWrite        = "lists:write"
+	ScopeSubscribersRead   = "subscribers:read"
+	ScopeSubscribersWrite  = "subscribers:write"
+	ScopeCampaignsRead     = "campaigns:read"
+	ScopeCampaignsWrite    = "campaigns:write"
+	ScopeDeliveriesRead    = "deliveries:read"
+	ScopeDeliveriesWrite   = "deliveries:write"
+	ScopeTxSend            = "tx:send"
+	ScopeTemplatesRead     = "templates:read"
+	ScopeTemplatesWrite    = "templates:write"
+	ScopeQueueRead         = "queue:read"
+	ScopeQueueWrite        = "queue:write"
+	ScopeSuppressionsRead  = "suppressions:read"
+	ScopeSuppressionsWrite = "suppressions:write"
+	ScopeAPIKeysRead       = "api_keys:read"
+	ScopeAPIKeysWrite      = "api_keys:write"
+	ScopeAuditRead         = "audit:read"
+)
+
+// Scopes lists all scopes.
+var Scopes = []string{
+	ScopeListsRead, ScopeListsWrite,
+	ScopeSubscribersRead, ScopeSubscribersWrite,
+	ScopeCampaignsRead, ScopeCampaignsWrite,
+	ScopeDeliveriesRead, ScopeDeliveriesWrite,
+	ScopeTxSend,
+	ScopeTemplatesRead, ScopeTemplatesWrite,
+	ScopeQueueRead, ScopeQueueWrite,
+	ScopeSuppressionsRead, ScopeSuppressionsWrite,
+	ScopeAPIKeysRead, ScopeAPIKeysWrite,
+	ScopeAuditRead,
+}
+
+var (
+	// ErrUnauthenticated is returned when a request carries no credentials.
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrInvalidCredentials is returned when credentials are present but not valid.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the verified caller of a request.
+type Principal struct {
+	// Method is the method that authenticated the request.
+	Method string
+	// Subject identifies the caller: the API key name, the token subject or the
+	// certificate common name.
+	Subject string
+	// Scopes restricts the caller to the listed scopes. Nil grants all scopes, as for
+	// configured API keys, tokens and certificates.
+	Scopes []string
+}
+
+// String returns the principal as method:subject, e.g. for logs.
+func (p *Principal) String() string {
+	return p.Method + ":" + p.Subject
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator verifies the credentials of one method.
+type Authenticator interface {
+	// Authenticate returns the principal of the request. It returns nil and no error
+	// when the request carries no credentials of its method.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of an authenticated request, or nil.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Middleware rejects requests none of the authenticators accepts and stores the
+// principal of accepted requests in their context.
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := ErrUnauthenticated
+			for _, a := range authenticators {
+				p, aerr := a.Authenticate(r)
+				if aerr != nil {
+					err = aerr
+					continue
+				}
+				if p != nil {
+					next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+					return
+				}
+			}
+			if !errors.Is(err, ErrUnauthenticated) {
+				log.Printf("auth: rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="headmail"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		})
+	}
+}
+
+// RequireScope rejects requests whose principal was granted none of the scopes with
+// 403. Requests without a principal pass, as the admin API is open when no
+// authentication is configured.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := PrincipalFromContext(r.Context())
+			if p == nil || slices.ContainsFunc(scopes, p.HasScope) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "missing scope "+strings.Join(scopes, " or "), http.StatusForbidden)
+		})
+	}
+}
+
+// requestAPIKey returns the key of an X-API-Key header or bearer token, or "".
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	return bearerToken(r)
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
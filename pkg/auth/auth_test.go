@@ -0,0 +1,311 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// whoami responds with the principal of the request.
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(PrincipalFromContext(r.Context()).String()))
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestMiddleware_APIKey(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret-key"))
+	a, err := NewAPIKeyAuthenticator([]config.APIKeyConfig{{Name: "ci", SHA256: hex.EncodeToString(hash[:])}})
+	require.NoError(t, err)
+	h := Middleware(a)(whoami)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/lists", nil)
+	rec := serve(h, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer secret-key")
+	rec = serve(h, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "api_key:ci", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/lists", nil)
+	req.Header.Set(APIKeyHeader, "secret-key")
+	assert.Equal(t, http.StatusOK, serve(h, req).Code)
+	req.Header.Set(APIKeyHeader, "wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, serve(h, req).Code)
+
+	_, err = NewAPIKeyAuthenticator([]config.APIKeyConfig{{Name: "plain", SHA256: "secret-key"}})
+	assert.Error(t, err, "keys must be configured as hashes")
+}
+
+// keyVerifier verifies the stored keys of the map.
+type keyVerifier map[string]*domain.APIKey
+
+func (v keyVerifier) VerifyAPIKey(_ context.Context, key string) (*domain.APIKey, error) {
+	if k, ok := v[key]; ok {
+		return k, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+func TestRequireScope(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret-key"))
+	static, err := NewAPIKeyAuthenticator([]config.APIKeyConfig{{Name: "ci", SHA256: hex.EncodeToString(hash[:])}})
+	require.NoError(t, err)
+	stored := NewStoredKeyAuthenticator(keyVerifier{
+		"hm_tx":   {Name: "mailer", Scopes: []string{ScopeTxSend}},
+		"hm_none": {Name: "none"},
+	})
+	h := Middleware(static, stored)(RequireScope(ScopeTxSend, ScopeDeliveriesRead)(whoami))
+
+	for key, status := range map[string]int{
+		"secret-key": http.StatusOK, // configured keys have all scopes
+		"hm_tx":      http.StatusOK,
+		"hm_none":    http.StatusForbidden,
+		"hm_unknown": http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/tx", nil)
+		req.Header.Set(APIKeyHeader, key)
+		assert.Equal(t, status, serve(h, req).Code, key)
+	}
+
+	h = Middleware(static, stored)(RequireScope(ScopeListsWrite)(whoami))
+	req := httptest.NewRequest(http.MethodDelete, "/api/lists/1", nil)
+	req.Header.Set("Authorization", "Bearer hm_tx")
+	assert.Equal(t, http.StatusForbidden, serve(h, req).Code)
+
+	// without authentication the admin API is open
+	open := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	assert.Equal(t, http.StatusNoContent, serve(RequireScope(ScopeListsWrite)(open), req).Code)
+}
+
+func TestMiddleware_JWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	rsaJWK := map[string]any{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())}
+	ecJWK := map[string]any{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))}
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []any{rsaJWK}
+		if rotated.Load() {
+			keys = append(keys, ecJWK)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer jwks.Close()
+
+	a, err := NewJWTAuthenticator(config.JWTAuthConfig{
+		JWKSURL:  jwks.URL,
+		Issuer:   "https://idp.example.com",
+		Audience: "headmail",
+	})
+	require.NoError(t, err)
+	h := Middleware(a)(whoami)
+
+	sign := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) *http.Request {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/lists", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		return req
+	}
+	claims := func(mutate func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss": "https://idp.example.com",
+			"aud": "headmail",
+			"sub": "alice",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+
+	rec := serve(h, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims(nil)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "jwt:alice", rec.Body.String())
+
+	for name, c := range map[string]jwt.MapClaims{
+		"expired":      claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }),
+		"no expiry":    claims(func(c jwt.MapClaims) { delete(c, "exp") }),
+		"other issuer": claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+		"other aud":    claims(func(c jwt.MapClaims) { c["aud"] = "other" }),
+		"no subject":   claims(func(c jwt.MapClaims) { delete(c, "sub") }),
+	} {
+		assert.Equal(t, http.StatusUnauthorized, serve(h, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, c)).Code, name)
+	}
+
+	// symmetric tokens keyed with public material are rejected
+	hmacReq := sign(jwt.SigningMethodHS256, "rsa-1", []byte(rsaJWK["n"].(string)), claims(nil))
+	assert.Equal(t, http.StatusUnauthorized, serve(h, hmacReq).Code)
+
+	// a key added to the set is picked up once the set is reloaded
+	rotated.Store(true)
+	a.mu.Lock()
+	a.loadedAt = time.Now().Add(-time.Hour)
+	a.attemptedAt = a.loadedAt
+	a.mu.Unlock()
+	before := fetches.Load()
+	rec = serve(h, sign(jwt.SigningMethodES256, "ec-1", ecKey, claims(func(c jwt.MapClaims) { c["sub"] = "bob" })))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "jwt:bob", rec.Body.String())
+	assert.Equal(t, before+1, fetches.Load())
+
+	// unknown keys do not reload the set more than once a minute
+	assert.Equal(t, http.StatusUnauthorized, serve(h, sign(jwt.SigningMethodES256, "ec-2", ecKey, claims(nil))).Code)
+	assert.Equal(t, before+1, fetches.Load())
+}
+
+func TestJWTAuthenticator_ThrottlesFailedReloads(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	ecJWK := map[string]any{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))}
+
+	var down atomic.Bool
+	var fetches atomic.Int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if down.Load() {
+			time.Sleep(50 * time.Millisecond)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{ecJWK}})
+	}))
+	defer jwks.Close()
+
+	a, err := NewJWTAuthenticator(config.JWTAuthConfig{JWKSURL: jwks.URL, RefreshInterval: time.Second})
+	require.NoError(t, err)
+	h := Middleware(a)(whoami)
+	request := func(kid string) *http.Request {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(ecKey)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/lists", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		return req
+	}
+
+	// the key set is stale and its URL fails; concurrent requests share one attempt
+	down.Store(true)
+	a.mu.Lock()
+	a.loadedAt = time.Now().Add(-time.Hour)
+	a.attemptedAt = a.loadedAt
+	a.mu.Unlock()
+	before := fetches.Load()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, serve(h, request("ec-1")).Code)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, before+1, fetches.Load())
+
+	// the failed attempt throttles later reloads, also for unknown keys
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve(h, request("ec-1")).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(h, request("unknown")).Code)
+	}
+	assert.Equal(t, before+1, fetches.Load())
+}
+
+func TestMiddleware_MTLS(t *testing.T) {
+	newCert := func(cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		if parent == nil {
+			template.IsCA = true
+			template.BasicConstraintsValid = true
+			template.KeyUsage = x509.KeyUsageCertSign
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	ca, caKey, _ := newCert("Test CA", nil, nil)
+	_, _, ops := newCert("ops", ca, caKey)
+	_, _, other := newCert("intruder", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	srv := httptest.NewUnstartedServer(Middleware(NewCertificateAuthenticator(config.MTLSAuthConfig{AllowedSubjects: []string{"ops"}}))(whoami))
+	srv.TLS = &tls.Config{ClientCAs: pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(cert *tls.Certificate) (int, string) {
+		client := srv.Client()
+		transport := client.Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		client.Transport = transport
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body [64]byte
+		n, _ := resp.Body.Read(body[:])
+		return resp.StatusCode, string(body[:n])
+	}
+	status, body := get(&ops)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "mtls:ops", body)
+	status, _ = get(&other)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = get(nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
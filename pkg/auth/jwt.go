@@ -0,0 +1,307 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/headmail/headmail/pkg/config"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwtLeeway tolerates clock skew when checking exp and nbf.
+	jwtLeeway = 30 * time.Second
+	// jwksMinRefresh is the minimum time between attempts to reload the key set, so
+	// tokens with unknown key IDs or an unreachable key set URL do not cause a fetch
+	// per request.
+	jwksMinRefresh = time.Minute
+	// maxJWKSSize limits the size of a key set.
+	maxJWKSSize = 1 << 20
+)
+
+// jwtMethods are the accepted signature algorithms; symmetric algorithms are excluded
+// because the key set is public.
+var jwtMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// jwk is a public JSON Web Key (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWTAuthenticator accepts bearer JWTs, e.g. OIDC access tokens, signed with a key of
+// a JWKS loaded from a file or URL. The key set is reloaded periodically and when a
+// token names an unknown key, so keys can be rotated.
+type JWTAuthenticator struct {
+	cfg    config.JWTAuthConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	mu          sync.RWMutex
+	keys        map[string]any
+	loadedAt    time.Time
+	attemptedAt time.Time
+	reloads     singleflight.Group
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator and loads its key set.
+func NewJWTAuthenticator(cfg config.JWTAuthConfig) (*JWTAuthenticator, error) {
+	if (cfg.JWKSURL == "") == (cfg.JWKSFile == "") {
+		return nil, errors.New("jwt auth requires either jwks_url or jwks_file")
+	}
+	if cfg.SubjectClaim == "" {
+		cfg.SubjectClaim = "sub"
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a := &JWTAuthenticator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		parser: jwt.NewParser(opts...),
+	}
+	if err := a.refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	raw := bearerToken(r)
+	if raw == "" || strings.Count(raw, ".") != 2 {
+		// not a JWT, e.g. an API key
+		return nil, nil
+	}
+	a.mu.RLock()
+	stale := a.cfg.RefreshInterval > 0 && time.Since(a.loadedAt) > a.cfg.RefreshInterval
+	a.mu.RUnlock()
+	if stale {
+		a.reload(r.Context())
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		return a.key(r.Context(), t)
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	subject, _ := claims[a.cfg.SubjectClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no %s claim", ErrInvalidCredentials, a.cfg.SubjectClaim)
+	}
+	return &Principal{Method: MethodJWT, Subject: subject}, nil
+}
+
+// key returns the verification key of the token.
+func (a *JWTAuthenticator) key(ctx context.Context, t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	a.mu.RLock()
+	key, ok := a.lookup(kid)
+	a.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if kid != "" {
+		a.reload(ctx)
+		a.mu.RLock()
+		key, ok = a.lookup(kid)
+		a.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key '%s'", kid)
+}
+
+// lookup returns the key with the ID, or the only key for tokens without a key ID.
+// The caller holds a.mu.
+func (a *JWTAuthenticator) lookup(kid string) (any, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			return k, true
+		}
+	}
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+// reload refreshes the key set unless a refresh was attempted within jwksMinRefresh,
+// whether it succeeded or not. Concurrent callers wait for a single refresh.
+func (a *JWTAuthenticator) reload(ctx context.Context) {
+	a.reloads.Do("jwks", func() (any, error) {
+		a.mu.RLock()
+		recent := time.Since(a.attemptedAt) < jwksMinRefresh
+		a.mu.RUnlock()
+		if recent {
+			return nil, nil
+		}
+		// the refresh is shared, so it must not end with the request that started it
+		if err := a.refresh(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("auth: reload jwks failed: %v", err)
+		}
+		return nil, nil
+	})
+}
+
+// refresh reloads the key set.
+func (a *JWTAuthenticator) refresh(ctx context.Context) error {
+	a.mu.Lock()
+	a.attemptedAt = time.Now()
+	a.mu.Unlock()
+	data, err := a.load(ctx)
+	if err != nil {
+		return fmt.Errorf("load jwks: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.keys = keys
+	a.loadedAt = time.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *JWTAuthenticator) load(ctx context.Context) ([]byte, error) {
+	if a.cfg.JWKSFile != "" {
+		return os.ReadFile(a.cfg.JWKSFile)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// parseJWKS returns the signature keys of a JWKS by key ID. Keys of unsupported types
+// are skipped.
+func parseJWKS(data []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk '%s': %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no signature keys")
+	}
+	return keys, nil
+}
+
+// publicKey returns the key, or nil for unsupported key types.
+func (k *jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/headmail/headmail/pkg/config"
+)
+
+// CertificateAuthenticator accepts client certificates verified during the TLS
+// handshake. The server must request them with a client CA pool.
+type CertificateAuthenticator struct {
+	allowed map[string]bool
+}
+
+// NewCertificateAuthenticator creates a CertificateAuthenticator. Without allowed
+// subjects any verified certificate is accepted.
+func NewCertificateAuthenticator(cfg config.MTLSAuthConfig) *CertificateAuthenticator {
+	a := &CertificateAuthenticator{}
+	if len(cfg.AllowedSubjects) > 0 {
+		a.allowed = make(map[string]bool, len(cfg.AllowedSubjects))
+		for _, s := range cfg.AllowedSubjects {
+			a.allowed[s] = true
+		}
+	}
+	return a
+}
+
+// Authenticate implements Authenticator.
+func (a *CertificateAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if subject == "" || (a.allowed != nil && !a.allowed[subject]) {
+		return nil, fmt.Errorf("%w: certificate subject '%s' is not allowed", ErrInvalidCredentials, subject)
+	}
+	return &Principal{Method: MethodMTLS, Subject: subject}, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/headmail/headmail/pkg/domain"
+)
+
+// APIKeyVerifier verifies API keys stored in the database.
+type APIKeyVerifier interface {
+	// VerifyAPIKey returns the stored key, or an error wrapping ErrInvalidCredentials
+	// when key is unknown or revoked.
+	VerifyAPIKey(ctx context.Context, key string) (*domain.APIKey, error)
+}
+
+// StoredKeyAuthenticator accepts API keys created through the admin API. Their
+// principals are restricted to the scopes of the key.
+type StoredKeyAuthenticator struct {
+	verifier APIKeyVerifier
+}
+
+// NewStoredKeyAuthenticator creates a StoredKeyAuthenticator.
+func NewStoredKeyAuthenticator(verifier APIKeyVerifier) *StoredKeyAuthenticator {
+	return &StoredKeyAuthenticator{verifier: verifier}
+}
+
+// Authenticate implements Authenticator.
+func (a *StoredKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := requestAPIKey(r)
+	if !strings.HasPrefix(key, domain.APIKeyPrefix) {
+		return nil, nil
+	}
+	stored, err := a.verifier.VerifyAPIKey(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	scopes := stored.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &Principal{Method: MethodAPIKey, Subject: stored.Name, Scopes: scopes}, nil
+}
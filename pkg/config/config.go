@@ -6,6 +6,7 @@ package config
 import (
 	"log"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/parsers/toml"
@@ -17,11 +18,18 @@ import (
 
 // Config holds the application configuration.
 type Config struct {
-	Server   ServerConfig   `koanf:"server"`
-	SMTP     SMTPConfig     `koanf:"smtp"`
-	IMAP     IMAPConfig     `koanf:"imap"`
-	Tracking TrackingConfig `koanf:"tracking"`
-	Database DatabaseConfig `koanf:"database"`
+	Server       ServerConfig       `koanf:"server"`
+	SMTP         SMTPConfig         `koanf:"smtp"`
+	IMAP         IMAPConfig         `koanf:"imap"`
+	Tracking     TrackingConfig     `koanf:"tracking"`
+	Preferences  PreferencesConfig  `koanf:"preferences"`
+	Subscription SubscriptionConfig `koanf:"subscription"`
+	Bounce       BounceConfig       `koanf:"bounce"`
+	Webhooks     WebhookConfig      `koanf:"webhooks"`
+	Database     DatabaseConfig     `koanf:"database"`
+	Queue        QueueConfig        `koanf:"queue"`
+	DKIM         DKIMConfig         `koanf:"dkim"`
+	Security     SecurityConfig     `koanf:"security"`
 }
 
 // ServerConfig holds server-related configuration.
@@ -33,9 +41,61 @@ type ServerConfig struct {
 	Admin struct {
 		Addr int `koanf:"addr"`
 		Port int `koanf:"port"`
+		// TLS serves the admin API over HTTPS, which client certificate authentication requires.
+		TLS AdminTLSConfig `koanf:"tls"`
+		// Auth authenticates requests to the admin API.
+		Auth AdminAuthConfig `koanf:"auth"`
 	} `koanf:"admin"`
 }
 
+// AdminTLSConfig holds the certificate of the admin server.
+type AdminTLSConfig struct {
+	CertFile string `koanf:"cert_file"`
+	KeyFile  string `koanf:"key_file"`
+	// ClientCAFile holds the PEM encoded CAs client certificates are verified with.
+	ClientCAFile string `koanf:"client_ca_file"`
+}
+
+// AdminAuthConfig selects how admin API requests are authenticated.
+type AdminAuthConfig struct {
+	// Methods lists the enabled schemes: api_key, jwt and mtls. A request is accepted
+	// when any of them authenticates it. When empty the admin API is open to anyone
+	// who can reach it.
+	Methods []string `koanf:"methods"`
+	// APIKeys are static keys with all scopes, sent as "Authorization: Bearer <key>"
+	// or X-API-Key. The api_key method also accepts scoped keys stored in the database.
+	APIKeys []APIKeyConfig `koanf:"api_keys"`
+	JWT     JWTAuthConfig  `koanf:"jwt"`
+	MTLS    MTLSAuthConfig `koanf:"mtls"`
+}
+
+// APIKeyConfig is a static admin API key. Only the hex encoded SHA-256 hash of the
+// key is configured, e.g. `printf %s "$KEY" | sha256sum`.
+type APIKeyConfig struct {
+	Name   string `koanf:"name"`
+	SHA256 string `koanf:"sha256"`
+}
+
+// JWTAuthConfig verifies bearer JWTs, e.g. OIDC access tokens, with the keys of a JWKS.
+type JWTAuthConfig struct {
+	// JWKSURL or JWKSFile is the key set tokens are signed with.
+	JWKSURL  string `koanf:"jwks_url"`
+	JWKSFile string `koanf:"jwks_file"`
+	// RefreshInterval is how often the key set is reloaded.
+	RefreshInterval time.Duration `koanf:"refresh_interval"`
+	// Issuer and Audience, if set, must match the iss and aud claims.
+	Issuer   string `koanf:"issuer"`
+	Audience string `koanf:"audience"`
+	// SubjectClaim is the claim that identifies the caller.
+	SubjectClaim string `koanf:"subject_claim"`
+}
+
+// MTLSAuthConfig authenticates clients by certificates issued by Server.Admin.TLS.ClientCAFile.
+type MTLSAuthConfig struct {
+	// AllowedSubjects, if set, lists the accepted certificate common names.
+	AllowedSubjects []string `koanf:"allowed_subjects"`
+}
+
 // SMTPConfig holds SMTP-related configuration.
 type SMTPConfig struct {
 	Host     string `koanf:"host"`
@@ -46,11 +106,52 @@ type SMTPConfig struct {
 		Name  string `koanf:"name"`
 		Email string `koanf:"email"`
 	} `koanf:"from"`
-	Send struct {
+	// AllowedSenderDomains lists the domains campaigns and deliveries may send from.
+	// When empty only the domain of From.Email is allowed.
+	AllowedSenderDomains []string `koanf:"allowed_sender_domains"`
+	// ReturnPath is an optional bounce mailbox, e.g. bounce@bounces.example.com. When set,
+	// each delivery is sent with the envelope sender bounce+<delivery id>.<tag>@bounces.example.com
+	// so bounces can be correlated even when MTAs strip the returned message.
+	ReturnPath string `koanf:"return_path"`
+	Send       struct {
 		BatchSize int `koanf:"batch_size"`
-		Throttle  int `koanf:"throttle"`
-		Attempts  int `koanf:"attempts"`
+		Throttle  int `koanf:"throttle"` // maximum emails per second, 0 for unlimited
+		// DomainThrottle limits sends per recipient domain on top of Throttle.
+		DomainThrottle []DomainThrottleConfig `koanf:"domain_throttle"`
 	} `koanf:"send"`
+	// Receive is the embedded SMTP server that accepts bounces and feedback reports.
+	Receive SMTPReceiveConfig `koanf:"receive"`
+}
+
+// SMTPReceiveConfig configures the embedded SMTP server that receives bounces and
+// feedback loop reports directly instead of polling a mailbox over IMAP.
+type SMTPReceiveConfig struct {
+	// Addr is the address to listen on, e.g. ":25". The server is disabled when empty.
+	Addr string `koanf:"addr"`
+	// Hostname is announced in the greeting. Defaults to the first accepted domain.
+	Hostname string `koanf:"hostname"`
+	// Domains lists the recipient domains mail is accepted for. Defaults to the
+	// domain of ReturnPath.
+	Domains []string `koanf:"domains"`
+	// MaxMessageBytes rejects larger messages.
+	MaxMessageBytes int64 `koanf:"max_message_bytes"`
+	// MaxRecipients limits the recipients of one message.
+	MaxRecipients int `koanf:"max_recipients"`
+	// MaxConnections limits concurrent connections; further clients wait to be accepted.
+	MaxConnections int `koanf:"max_connections"`
+	// Timeout closes connections idle for longer.
+	Timeout time.Duration `koanf:"timeout"`
+	// TLSCertFile and TLSKeyFile enable STARTTLS.
+	TLSCertFile string `koanf:"tls_cert_file"`
+	TLSKeyFile  string `koanf:"tls_key_file"`
+}
+
+// DomainThrottleConfig limits sends to one recipient domain to Limit emails per Interval.
+type DomainThrottleConfig struct {
+	Domain   string        `koanf:"domain"`
+	Limit    int           `koanf:"limit"`
+	Interval time.Duration `koanf:"interval"`
+	Burst    int           `koanf:"burst"`
 }
 
 type IMAPConfig struct {
@@ -62,11 +163,104 @@ type IMAPConfig struct {
 	Mailbox  string `koanf:"mailbox"` // e.g. "INBOX"
 }
 
+// DKIMConfig holds DKIM signing configuration. Messages are signed with the key
+// of the sender's domain; mail from domains without a key is sent unsigned.
+type DKIMConfig struct {
+	// Headers lists the header fields to sign. Defaults to the RFC 6376 recommended set.
+	Headers []string `koanf:"headers"`
+	// HeaderCanonicalization and BodyCanonicalization are "relaxed" (default) or "simple".
+	HeaderCanonicalization string          `koanf:"header_canonicalization"`
+	BodyCanonicalization   string          `koanf:"body_canonicalization"`
+	Keys                   []DKIMKeyConfig `koanf:"keys"`
+}
+
+// DKIMKeyConfig is the DKIM key of one sending domain. The PEM encoded private key
+// is given inline or as a file path.
+type DKIMKeyConfig struct {
+	Domain         string `koanf:"domain"`
+	Selector       string `koanf:"selector"`
+	PrivateKey     string `koanf:"private_key"`
+	PrivateKeyFile string `koanf:"private_key_file"`
+}
+
+// SecurityConfig holds secrets used to sign links in outgoing mail.
+type SecurityConfig struct {
+	// SigningKeys sign links such as unsubscribe and tracking URLs. The first key signs
+	// new links; keep retired keys listed after it so links in mail already sent keep
+	// working. Required when server.public.url is set; otherwise a random key is
+	// generated at startup.
+	SigningKeys []SigningKeyConfig `koanf:"signing_keys"`
+}
+
+// SigningKeyConfig is one HMAC signing key.
+type SigningKeyConfig struct {
+	ID     string `koanf:"id"`
+	Secret string `koanf:"secret"`
+}
+
 // TrackingConfig holds tracking-related configuration.
 type TrackingConfig struct {
 	// ImagePath is an optional path or URL to a tracking image to return for opens.
 	// If empty, a built-in 1x1 transparent PNG will be returned.
 	ImagePath string `koanf:"image_path"`
+	// UnsignedLinksBefore accepts open and click tracking URLs without a signature for
+	// deliveries created before this time, e.g. the upgrade that started signing them.
+	// Unsigned clicks only redirect to targets the delivery's mail linked to. It is a
+	// temporary migration aid: remove it once mail sent before has aged out. If zero,
+	// unsigned URLs are rejected.
+	UnsignedLinksBefore time.Time `koanf:"unsigned_links_before"`
+}
+
+// PreferencesConfig holds configuration of the hosted preference center.
+type PreferencesConfig struct {
+	// TemplatePath is an optional path to a Go template for the preference center page.
+	// Templates starting with <mjml> are converted to HTML. If empty, a built-in page is used.
+	TemplatePath string `koanf:"template_path"`
+}
+
+// SubscriptionConfig holds configuration of public double opt-in subscriptions.
+type SubscriptionConfig struct {
+	// ConfirmationExpiry is how long a pending subscription waits for confirmation
+	// before it is deleted.
+	ConfirmationExpiry time.Duration `koanf:"confirmation_expiry"`
+}
+
+// BounceConfig holds the policies that change subscriber list status on bounces and complaints.
+type BounceConfig struct {
+	// HardBounce marks a subscriber bounced on all lists at the first permanent bounce.
+	HardBounce bool `koanf:"hard_bounce"`
+	// SoftBounceLimit marks a subscriber bounced once this many deliveries bounced
+	// within SoftBounceWindow. Zero disables the soft bounce policy.
+	SoftBounceLimit  int           `koanf:"soft_bounce_limit"`
+	SoftBounceWindow time.Duration `koanf:"soft_bounce_window"`
+	// Complaint marks a subscriber complained on all lists at the first spam complaint.
+	Complaint bool `koanf:"complaint"`
+}
+
+// WebhookConfig holds the event webhooks of email service providers, received by the
+// public server at /webhooks/<provider>. A provider is enabled by configuring its credentials.
+type WebhookConfig struct {
+	// Tolerance rejects signed events whose timestamp is further than this from now.
+	// Event IDs are remembered for at least as long, so replayed events are dropped.
+	Tolerance time.Duration `koanf:"tolerance"`
+	SES       struct {
+		// TopicARNs lists the SNS topics SES publishes bounces and complaints to.
+		// Notifications and subscription confirmations of other topics are rejected.
+		TopicARNs []string `koanf:"topic_arns"`
+	} `koanf:"ses"`
+	SendGrid struct {
+		// PublicKey is the base64 encoded verification key of the signed event webhook.
+		PublicKey string `koanf:"public_key"`
+	} `koanf:"sendgrid"`
+	Mailgun struct {
+		// SigningKey is the HTTP webhook signing key of the Mailgun account.
+		SigningKey string `koanf:"signing_key"`
+	} `koanf:"mailgun"`
+	Postmark struct {
+		// Username and Password are the basic auth credentials set in the webhook URL.
+		Username string `koanf:"username"`
+		Password string `koanf:"password"`
+	} `koanf:"postmark"`
 }
 
 // DatabaseConfig holds database-related configuration.
@@ -75,6 +269,28 @@ type DatabaseConfig struct {
 	URL  string `koanf:"url"`
 }
 
+// QueueConfig holds queue-related configuration.
+type QueueConfig struct {
+	// Lease is how long a claimed item may stay reserved before it is handed out again.
+	// It must be longer than the slowest handler run.
+	Lease time.Duration `koanf:"lease"`
+	// ReapInterval is how often expired leases are released.
+	ReapInterval time.Duration `koanf:"reap_interval"`
+	// Workers is the number of queue items processed in parallel.
+	Workers int `koanf:"workers"`
+	// PollInterval is how long workers wait before polling an empty queue again.
+	PollInterval time.Duration `koanf:"poll_interval"`
+	// Concurrency caps how many items of a type (e.g. "delivery") run at the same time.
+	Concurrency map[string]int `koanf:"concurrency"`
+	Retry       struct {
+		MaxAttempts     int           `koanf:"max_attempts"`
+		InitialInterval time.Duration `koanf:"initial_interval"`
+		MaxInterval     time.Duration `koanf:"max_interval"`
+		Multiplier      float64       `koanf:"multiplier"`
+		Jitter          float64       `koanf:"jitter"` // fraction of the delay, e.g. 0.2 for ±20%
+	} `koanf:"retry"`
+}
+
 // Option defines a function that configures a koanf instance.
 type Option func(k *koanf.Koanf) error
 
@@ -120,7 +336,36 @@ func WithEnv(prefix string) Option {
 }
 
 var envMappings = map[string]string{
-	"SMTP_SEND_BATCH_SIZE": "smtp.send.batch_size",
+	"SMTP_SEND_BATCH_SIZE":             "smtp.send.batch_size",
+	"SMTP_ALLOWED_SENDER_DOMAINS":      "smtp.allowed_sender_domains",
+	"SMTP_RETURN_PATH":                 "smtp.return_path",
+	"SMTP_RECEIVE_MAX_MESSAGE_BYTES":   "smtp.receive.max_message_bytes",
+	"SMTP_RECEIVE_MAX_RECIPIENTS":      "smtp.receive.max_recipients",
+	"SMTP_RECEIVE_MAX_CONNECTIONS":     "smtp.receive.max_connections",
+	"SMTP_RECEIVE_TLS_CERT_FILE":       "smtp.receive.tls_cert_file",
+	"SMTP_RECEIVE_TLS_KEY_FILE":        "smtp.receive.tls_key_file",
+	"QUEUE_REAP_INTERVAL":              "queue.reap_interval",
+	"QUEUE_POLL_INTERVAL":              "queue.poll_interval",
+	"QUEUE_RETRY_MAX_ATTEMPTS":         "queue.retry.max_attempts",
+	"QUEUE_RETRY_INITIAL_INTERVAL":     "queue.retry.initial_interval",
+	"QUEUE_RETRY_MAX_INTERVAL":         "queue.retry.max_interval",
+	"SUBSCRIPTION_CONFIRMATION_EXPIRY": "subscription.confirmation_expiry",
+	"TRACKING_UNSIGNED_LINKS_BEFORE":   "tracking.unsigned_links_before",
+	"BOUNCE_HARD_BOUNCE":               "bounce.hard_bounce",
+	"BOUNCE_SOFT_BOUNCE_LIMIT":         "bounce.soft_bounce_limit",
+	"BOUNCE_SOFT_BOUNCE_WINDOW":        "bounce.soft_bounce_window",
+	"WEBHOOKS_SES_TOPIC_ARNS":          "webhooks.ses.topic_arns",
+	"WEBHOOKS_SENDGRID_PUBLIC_KEY":     "webhooks.sendgrid.public_key",
+	"WEBHOOKS_MAILGUN_SIGNING_KEY":     "webhooks.mailgun.signing_key",
+
+	"SERVER_ADMIN_TLS_CERT_FILE":              "server.admin.tls.cert_file",
+	"SERVER_ADMIN_TLS_KEY_FILE":               "server.admin.tls.key_file",
+	"SERVER_ADMIN_TLS_CLIENT_CA_FILE":         "server.admin.tls.client_ca_file",
+	"SERVER_ADMIN_AUTH_JWT_JWKS_URL":          "server.admin.auth.jwt.jwks_url",
+	"SERVER_ADMIN_AUTH_JWT_JWKS_FILE":         "server.admin.auth.jwt.jwks_file",
+	"SERVER_ADMIN_AUTH_JWT_REFRESH_INTERVAL":  "server.admin.auth.jwt.refresh_interval",
+	"SERVER_ADMIN_AUTH_JWT_SUBJECT_CLAIM":     "server.admin.auth.jwt.subject_claim",
+	"SERVER_ADMIN_AUTH_MTLS_ALLOWED_SUBJECTS": "server.admin.auth.mtls.allowed_subjects",
 }
 
 // Load loads the configuration using the provided options.
@@ -130,8 +375,29 @@ func Load(opts ...Option) (*Config, error) {
 	// Set default values
 	k.Set("server.public.port", 8080)
 	k.Set("server.admin.port", 8081)
+	k.Set("server.admin.auth.jwt.refresh_interval", "1h")
+	k.Set("server.admin.auth.jwt.subject_claim", "sub")
 	k.Set("database.type", "sqlite")
 	k.Set("database.url", "file:data.db?cache=shared&mode=rwc")
+	k.Set("smtp.receive.max_message_bytes", 10<<20)
+	k.Set("smtp.receive.max_recipients", 50)
+	k.Set("smtp.receive.max_connections", 100)
+	k.Set("smtp.receive.timeout", "5m")
+	k.Set("queue.lease", "5m")
+	k.Set("queue.reap_interval", "30s")
+	k.Set("queue.workers", 4)
+	k.Set("queue.poll_interval", "1s")
+	k.Set("queue.retry.max_attempts", 5)
+	k.Set("queue.retry.initial_interval", "30s")
+	k.Set("queue.retry.max_interval", "1h")
+	k.Set("queue.retry.multiplier", 2.0)
+	k.Set("queue.retry.jitter", 0.2)
+	k.Set("subscription.confirmation_expiry", "72h")
+	k.Set("bounce.hard_bounce", true)
+	k.Set("bounce.soft_bounce_limit", 3)
+	k.Set("bounce.soft_bounce_window", "168h")
+	k.Set("bounce.complaint", true)
+	k.Set("webhooks.tolerance", "1h")
 
 	// Apply all options
 	for _, opt := range opts {
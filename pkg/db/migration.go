@@ -0,0 +1,60 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// MigrationStatus describes a single schema migration.
+type MigrationStatus struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt *int64 `json:"applied_at,omitempty"`
+	// Unknown is set for migrations recorded in the database that this binary does not ship,
+	// which usually means the database was migrated by a newer release.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Migrator is implemented by databases whose schema is managed by versioned migrations.
+type Migrator interface {
+	// MigrateUp applies up to steps pending migrations in version order, or all of them when steps <= 0.
+	// It returns the number of migrations applied.
+	MigrateUp(ctx context.Context, steps int) (int, error)
+	// MigrateDown reverts up to steps applied migrations in reverse version order.
+	// It returns the number of migrations reverted.
+	MigrateDown(ctx context.Context, steps int) (int, error)
+	// MigrationStatus lists the known and applied migrations in version order.
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// ErrSchemaBehind is returned when the database has pending migrations.
+type ErrSchemaBehind struct {
+	Pending []MigrationStatus
+}
+
+func (e *ErrSchemaBehind) Error() string {
+	return fmt.Sprintf("database schema is behind: %d pending migration(s), starting with %04d_%s; run 'headmail migrate up'",
+		len(e.Pending), e.Pending[0].Version, e.Pending[0].Name)
+}
+
+// CheckSchema returns *ErrSchemaBehind when m has pending migrations.
+func CheckSchema(ctx context.Context, m Migrator) error {
+	statuses, err := m.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []MigrationStatus
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s)
+		}
+	}
+	if len(pending) > 0 {
+		return &ErrSchemaBehind{Pending: pending}
+	}
+	return nil
+}
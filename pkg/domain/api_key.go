@@ -0,0 +1,22 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package domain
+
+// APIKeyPrefix starts every key created through the admin API, which tells them apart
+// from configured keys and bearer tokens.
+const APIKeyPrefix = "hm_"
+
+// APIKey is an admin API key stored in the database. Only the SHA-256 hash of the key
+// is stored; the key itself is shown once when it is created or rotated.
+type APIKey struct {
+	ID         string   `json:"id"`                     // UUID
+	Name       string   `json:"name"`                   // Name of the key, e.g. the service using it
+	Prefix     string   `json:"prefix"`                 // First characters of the key, to recognize it
+	Hash       string   `json:"-"`                      // Hex encoded SHA-256 hash of the key
+	Scopes     []string `json:"scopes"`                 // Granted scopes, e.g. tx:send, campaigns:write
+	LastUsedAt *int64   `json:"last_used_at,omitempty"` // Unix timestamp of the last authenticated request
+	RevokedAt  *int64   `json:"revoked_at,omitempty"`   // Unix timestamp from which the key is rejected
+	CreatedAt  int64    `json:"created_at"`             // Unix timestamp in seconds
+	UpdatedAt  int64    `json:"updated_at"`             // Unix timestamp in seconds
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package domain
+
+// AuditEntry records a mutating admin API call.
+type AuditEntry struct {
+	ID         string                 `json:"id"`                  // UUID
+	Principal  string                 `json:"principal"`           // method:subject of the caller, empty while the admin API is open
+	Action     string                 `json:"action"`              // e.g. campaign.update, campaign.status, list.delete
+	EntityType string                 `json:"entity_type"`         // e.g. campaign, list, subscriber, template
+	EntityID   string                 `json:"entity_id,omitempty"` // ID of the changed entity, when known
+	Method     string                 `json:"method"`              // HTTP method of the call
+	Path       string                 `json:"path"`                // Request path of the call
+	Changes    map[string]AuditChange `json:"changes,omitempty"`   // Changed fields of the entity by JSON name
+	CreatedAt  int64                  `json:"created_at"`          // Unix timestamp in seconds
+}
+
+// AuditChange is the value of a field before and after a call. Before is null for
+// created and After for deleted entities.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
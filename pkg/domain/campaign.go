@@ -43,4 +43,5 @@ type Campaign struct {
 	OpenCount      int `json:"open_count"`
 	ClickCount     int `json:"click_count"`
 	BounceCount    int `json:"bounce_count"`
+	ComplaintCount int `json:"complaint_count"`
 }
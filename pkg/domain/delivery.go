@@ -20,16 +20,21 @@ const (
 	DeliveryStatusDelivered DeliveryStatus = "delivered"
 	DeliveryStatusFailed    DeliveryStatus = "failed"
 	DeliveryStatusBounced   DeliveryStatus = "bounced"
+	// DeliveryStatusSuppressed is a delivery that was not sent because its recipient is suppressed.
+	DeliveryStatusSuppressed DeliveryStatus = "suppressed"
 )
 
 // Delivery represents a single email delivery.
 type Delivery struct {
 	ID         string                 `json:"id"`                    // UUID
 	CampaignID *string                `json:"campaign_id,omitempty"` // Campaign ID (nullable for transactional)
+	ListID     *string                `json:"list_id,omitempty"`     // List the recipient was taken from (unsubscribe scope)
 	Type       DeliveryType           `json:"type"`                  // campaign, transactional
-	Status     DeliveryStatus         `json:"status"`                // scheduled, sending, sent, delivered, failed, bounced
+	Status     DeliveryStatus         `json:"status"`                // scheduled, sending, sent, delivered, failed, bounced, suppressed
 	Name       string                 `json:"name"`                  // Recipient's name
 	Email      string                 `json:"email"`                 // Recipient's email
+	FromName   string                 `json:"from_name,omitempty"`   // Sender's name (defaults to smtp.from)
+	FromEmail  string                 `json:"from_email,omitempty"`  // Sender's email (defaults to smtp.from)
 	Subject    string                 `json:"subject"`               // Actual sent subject
 	BodyHTML   string                 `json:"body_html"`             // HTML body
 	BodyText   string                 `json:"body_text"`             // Text body
@@ -48,7 +53,8 @@ type Delivery struct {
 	FailureReason *string `json:"failure_reason,omitempty"` // Reason for failure
 
 	// Statistics
-	OpenCount   int `json:"open_count"`   // Number of opens
-	ClickCount  int `json:"click_count"`  // Number of clicks
-	BounceCount int `json:"bounce_count"` // Number of bounces
+	OpenCount      int `json:"open_count"`      // Number of opens
+	ClickCount     int `json:"click_count"`     // Number of clicks
+	BounceCount    int `json:"bounce_count"`    // Number of bounces
+	ComplaintCount int `json:"complaint_count"` // Number of spam complaints
 }
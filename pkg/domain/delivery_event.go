@@ -14,6 +14,11 @@ const (
 	EventTypeBounced      EventType = "bounced"
 	EventTypeComplained   EventType = "complained"
 	EventTypeUnsubscribed EventType = "unsubscribed"
+	// EventTypeDeferred records a transient bounce: a delay the sending server still retries.
+	EventTypeDeferred EventType = "deferred"
+	// EventTypeSubscriberStatusChanged records that a bounce or complaint policy changed
+	// the list memberships of the delivery's subscriber.
+	EventTypeSubscriberStatusChanged EventType = "subscriber_status_changed"
 )
 
 // DeliveryEvent represents an event related to a delivery.
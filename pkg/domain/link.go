@@ -0,0 +1,14 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package domain
+
+// Link is a tracked link target of a campaign. Campaign mail links to the click
+// tracker by the short ID of the link instead of embedding its URL.
+type Link struct {
+	ID         string `json:"id"`          // Short ID derived from campaign ID and URL
+	CampaignID string `json:"campaign_id"` // Campaign of the link
+	URL        string `json:"url"`         // Target URL
+	ClickCount int    `json:"click_count"` // Number of recorded clicks
+	CreatedAt  int64  `json:"created_at"`  // Unix timestamp in seconds
+}
@@ -14,12 +14,38 @@ const (
 type SubscriberListStatus string
 
 const (
+	// SubscriberListStatusPending is a double opt-in subscription awaiting confirmation.
+	SubscriberListStatusPending      SubscriberListStatus = "pending"
 	SubscriberListStatusConfirmed    SubscriberListStatus = "confirmed"
 	SubscriberListStatusUnsubscribed SubscriberListStatus = "unsubscribed"
 	SubscriberListStatusBounced      SubscriberListStatus = "bounced"
 	SubscriberListStatusComplained   SubscriberListStatus = "complained"
 )
 
+// SubscriberListStatuses lists all membership statuses in order of precedence.
+var SubscriberListStatuses = []SubscriberListStatus{
+	SubscriberListStatusPending,
+	SubscriberListStatusConfirmed,
+	SubscriberListStatusUnsubscribed,
+	SubscriberListStatusBounced,
+	SubscriberListStatusComplained,
+}
+
+// Precedence ranks the statuses that end a membership: a complaint outranks a bounce,
+// which outranks an unsubscribe. Pending and confirmed memberships rank lowest.
+func (s SubscriberListStatus) Precedence() int {
+	switch s {
+	case SubscriberListStatusUnsubscribed:
+		return 1
+	case SubscriberListStatusBounced:
+		return 2
+	case SubscriberListStatusComplained:
+		return 3
+	default:
+		return 0
+	}
+}
+
 // Subscriber represents a unique subscriber
 type Subscriber struct {
 	ID        string           `json:"id"`         // UUID
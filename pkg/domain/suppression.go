@@ -0,0 +1,42 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package domain
+
+// SuppressionType is what a suppression entry matches.
+type SuppressionType string
+
+const (
+	SuppressionTypeEmail  SuppressionType = "email"
+	SuppressionTypeDomain SuppressionType = "domain"
+)
+
+// SuppressionReason is why an address or domain must not be mailed.
+type SuppressionReason string
+
+const (
+	SuppressionReasonHardBounce SuppressionReason = "hard_bounce"
+	SuppressionReasonComplaint  SuppressionReason = "complaint"
+	SuppressionReasonManual     SuppressionReason = "manual"
+)
+
+// Sources of suppression entries.
+const (
+	SuppressionSourceAdmin     = "admin"
+	SuppressionSourceImport    = "import"
+	SuppressionSourceBounce    = "bounce"
+	SuppressionSourceComplaint = "complaint"
+)
+
+// Suppression is an entry of the global suppression list. No mail is sent to a
+// suppressed address or to any address of a suppressed domain.
+type Suppression struct {
+	ID        string            `json:"id"`                   // UUID
+	Type      SuppressionType   `json:"type"`                 // email, domain
+	Value     string            `json:"value"`                // Lower-cased email address or domain
+	Reason    SuppressionReason `json:"reason"`               // hard_bounce, complaint, manual
+	Source    string            `json:"source"`               // Where the entry came from, e.g. admin, import, bounce
+	ExpiresAt *int64            `json:"expires_at,omitempty"` // Unix timestamp after which the entry no longer applies
+	CreatedAt int64             `json:"created_at"`           // Unix timestamp in seconds
+	UpdatedAt int64             `json:"updated_at"`           // Unix timestamp in seconds
+}
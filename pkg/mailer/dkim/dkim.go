@@ -0,0 +1,166 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package dkim signs outgoing messages with DKIM (RFC 6376).
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	msgdkim "github.com/emersion/go-msgauth/dkim"
+)
+
+// DefaultHeaders are signed when no header set is configured (RFC 6376 section 5.4.1).
+var DefaultHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	"List-Id", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// Key is the signing key of one sending domain.
+type Key struct {
+	// Domain is the signing domain (d=). It also signs mail from its subdomains.
+	Domain string
+	// Selector is the DNS selector (s=) under which the public key is published.
+	Selector string
+	Signer   crypto.Signer
+}
+
+// Options configures how messages are signed.
+type Options struct {
+	// Headers lists the header fields to sign. Defaults to DefaultHeaders.
+	Headers []string
+	// HeaderCanonicalization and BodyCanonicalization are "simple" or "relaxed".
+	// Both default to "relaxed".
+	HeaderCanonicalization string
+	BodyCanonicalization   string
+}
+
+// Signer signs messages with the key of the sender's domain.
+type Signer struct {
+	keys    map[string]Key
+	headers []string
+	hdrCan  msgdkim.Canonicalization
+	bodyCan msgdkim.Canonicalization
+}
+
+// NewSigner creates a Signer for the given keys.
+func NewSigner(keys []Key, opts Options) (*Signer, error) {
+	s := &Signer{
+		keys:    make(map[string]Key, len(keys)),
+		headers: opts.Headers,
+		hdrCan:  msgdkim.CanonicalizationRelaxed,
+		bodyCan: msgdkim.CanonicalizationRelaxed,
+	}
+	if len(s.headers) == 0 {
+		s.headers = DefaultHeaders
+	}
+	hasFrom := false
+	for _, h := range s.headers {
+		hasFrom = hasFrom || strings.EqualFold(h, "From")
+	}
+	if !hasFrom {
+		return nil, errors.New("dkim: the signed headers must include From")
+	}
+
+	var err error
+	if s.hdrCan, err = parseCanonicalization(opts.HeaderCanonicalization); err != nil {
+		return nil, err
+	}
+	if s.bodyCan, err = parseCanonicalization(opts.BodyCanonicalization); err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		if k.Domain == "" || k.Selector == "" || k.Signer == nil {
+			return nil, fmt.Errorf("dkim: key for '%s' needs a domain, selector and private key", k.Domain)
+		}
+		s.keys[strings.ToLower(k.Domain)] = k
+	}
+	return s, nil
+}
+
+// Sign prepends a DKIM-Signature to msg using the key for the sender's domain,
+// or the closest parent domain with a key. Messages from domains without a key
+// are returned unchanged.
+func (s *Signer) Sign(sender string, msg []byte) ([]byte, error) {
+	key, ok := s.keyFor(sender)
+	if !ok {
+		return msg, nil
+	}
+
+	var signed bytes.Buffer
+	err := msgdkim.Sign(&signed, bytes.NewReader(msg), &msgdkim.SignOptions{
+		Domain:                 key.Domain,
+		Selector:               key.Selector,
+		Signer:                 key.Signer,
+		HeaderCanonicalization: s.hdrCan,
+		BodyCanonicalization:   s.bodyCan,
+		HeaderKeys:             s.headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signed.Bytes(), nil
+}
+
+func (s *Signer) keyFor(sender string) (Key, bool) {
+	at := strings.LastIndexByte(sender, '@')
+	if at < 0 {
+		return Key{}, false
+	}
+	domain := strings.ToLower(sender[at+1:])
+	for domain != "" {
+		if key, ok := s.keys[domain]; ok {
+			return key, true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return Key{}, false
+}
+
+func parseCanonicalization(v string) (msgdkim.Canonicalization, error) {
+	switch strings.ToLower(v) {
+	case "", "relaxed":
+		return msgdkim.CanonicalizationRelaxed, nil
+	case "simple":
+		return msgdkim.CanonicalizationSimple, nil
+	default:
+		return "", fmt.Errorf("dkim: unknown canonicalization '%s'", v)
+	}
+}
+
+// ParsePrivateKey parses a PEM encoded RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("dkim: no PEM block found in private key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("dkim: unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("dkim: unsupported PEM block '%s'", block.Type)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	msgdkim "github.com/emersion/go-msgauth/dkim"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dnsRecords maps "<selector>._domainkey.<domain>" to the published TXT record.
+type dnsRecords map[string]string
+
+func (r dnsRecords) publish(t *testing.T, key Key) {
+	var keyType string
+	var pub []byte
+	switch p := key.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		keyType = "rsa"
+		var err error
+		pub, err = x509.MarshalPKIXPublicKey(p)
+		require.NoError(t, err)
+	case ed25519.PublicKey:
+		keyType = "ed25519"
+		pub = p
+	}
+	r[key.Selector+"._domainkey."+key.Domain] = fmt.Sprintf("v=DKIM1; k=%s; p=%s", keyType, base64.StdEncoding.EncodeToString(pub))
+}
+
+func (r dnsRecords) verify(t *testing.T, msg []byte) []*msgdkim.Verification {
+	verifications, err := msgdkim.VerifyWithOptions(bytes.NewReader(msg), &msgdkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			if txt, ok := r[domain]; ok {
+				return []string{txt}, nil
+			}
+			return nil, fmt.Errorf("no TXT record for %s", domain)
+		},
+	})
+	require.NoError(t, err)
+	return verifications
+}
+
+func newRSAKey(t *testing.T, domain, selector string) Key {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return Key{Domain: domain, Selector: selector, Signer: priv}
+}
+
+func newEd25519Key(t *testing.T, domain, selector string) Key {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return Key{Domain: domain, Selector: selector, Signer: priv}
+}
+
+func buildMessage(t *testing.T, signer message.Signer, d *domain.Delivery) []byte {
+	b := message.NewBuilder("No Reply", "no-reply@example.com")
+	b.Signer = signer
+	msg, err := b.Build(d)
+	require.NoError(t, err)
+	return msg
+}
+
+func newDelivery() *domain.Delivery {
+	return &domain.Delivery{
+		ID:       "d1",
+		Name:     "Bob",
+		Email:    "bob@example.org",
+		Subject:  "Grüße",
+		BodyText: "hello",
+		BodyHTML: "<p>hello</p>",
+		Headers:  map[string]string{"List-Id": "News <news.example.com>"},
+	}
+}
+
+func TestSigner_Verifies(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		key  Key
+		opts Options
+	}{
+		{name: "rsa relaxed", key: newRSAKey(t, "example.com", "s1")},
+		{name: "rsa simple", key: newRSAKey(t, "example.com", "s1"), opts: Options{HeaderCanonicalization: "simple", BodyCanonicalization: "simple"}},
+		{name: "ed25519", key: newEd25519Key(t, "example.com", "ed")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner([]Key{tt.key}, tt.opts)
+			require.NoError(t, err)
+			dns := dnsRecords{}
+			dns.publish(t, tt.key)
+
+			msg := buildMessage(t, signer, newDelivery())
+
+			verifications := dns.verify(t, msg)
+			require.Len(t, verifications, 1)
+			assert.NoError(t, verifications[0].Err)
+			assert.Equal(t, "example.com", verifications[0].Domain)
+			assert.Contains(t, verifications[0].HeaderKeys, "From")
+			assert.Contains(t, verifications[0].HeaderKeys, "List-Id")
+		})
+	}
+}
+
+func TestSigner_TamperedMessageFails(t *testing.T) {
+	key := newRSAKey(t, "example.com", "s1")
+	signer, err := NewSigner([]Key{key}, Options{})
+	require.NoError(t, err)
+	dns := dnsRecords{}
+	dns.publish(t, key)
+
+	msg := buildMessage(t, signer, newDelivery())
+	tampered := bytes.Replace(msg, []byte("Subject: "), []byte("Subject: RE: "), 1)
+
+	verifications := dns.verify(t, tampered)
+	require.Len(t, verifications, 1)
+	assert.Error(t, verifications[0].Err)
+}
+
+func TestSigner_KeyPerSendingDomain(t *testing.T) {
+	example := newRSAKey(t, "example.com", "s1")
+	other := newEd25519Key(t, "other.org", "s2")
+	signer, err := NewSigner([]Key{example, other}, Options{})
+	require.NoError(t, err)
+	dns := dnsRecords{}
+	dns.publish(t, example)
+	dns.publish(t, other)
+
+	// subdomains are signed with the parent domain's key
+	d := newDelivery()
+	d.FromEmail = "news@mail.other.org"
+	verifications := dns.verify(t, buildMessage(t, signer, d))
+	require.Len(t, verifications, 1)
+	assert.NoError(t, verifications[0].Err)
+	assert.Equal(t, "other.org", verifications[0].Domain)
+
+	// domains without a key are sent unsigned
+	d = newDelivery()
+	d.FromEmail = "news@unknown.net"
+	assert.Empty(t, dns.verify(t, buildMessage(t, signer, d)))
+}
+
+func TestNewSigner_RequiresFrom(t *testing.T) {
+	_, err := NewSigner(nil, Options{Headers: []string{"Subject"}})
+	assert.Error(t, err)
+
+	_, err = NewSigner(nil, Options{BodyCanonicalization: "nofws"})
+	assert.Error(t, err)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, data := range map[string][]byte{"pkcs1": pkcs1, "pkcs8": pkcs8} {
+		signer, err := ParsePrivateKey(data)
+		require.NoError(t, err, name)
+		assert.Implements(t, (*crypto.Signer)(nil), signer)
+	}
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
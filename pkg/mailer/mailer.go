@@ -13,7 +13,8 @@ import (
 // Mailer is an abstraction for sending emails.
 type Mailer interface {
 	// Send delivers the given delivery. Implementations are responsible for
-	// constructing message headers/body and performing the send.
+	// constructing message headers/body and performing the send. If d.MessageID
+	// is empty, Send sets it to the Message-ID used so the caller can persist it.
 	Send(ctx context.Context, d *domain.Delivery) error
 }
 
@@ -0,0 +1,191 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package message renders deliveries into RFC 5322 / MIME messages that can be
+// shared by all mailer implementations.
+package message
+
+import (
+	"bytes"
+	"io"
+	netmail "net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+)
+
+// Signer signs a rendered message before it is handed to the transport, e.g. with DKIM.
+type Signer interface {
+	Sign(sender string, msg []byte) ([]byte, error)
+}
+
+// Builder renders deliveries into messages.
+type Builder struct {
+	// From is used when the delivery does not set its own sender.
+	From mail.Address
+	// Now returns the time for the Date header. Defaults to time.Now.
+	Now func() time.Time
+	// Signer, if set, signs every built message.
+	Signer Signer
+	// DeliveryHeaders are additional headers set to the delivery ID, e.g. metadata
+	// headers an email service provider returns in its webhooks.
+	DeliveryHeaders []string
+}
+
+// NewBuilder creates a Builder with the given default sender.
+func NewBuilder(fromName, fromEmail string) *Builder {
+	return &Builder{
+		From: mail.Address{Name: fromName, Address: fromEmail},
+	}
+}
+
+// Sender returns the From address of d, falling back to the builder's default.
+func (b *Builder) Sender(d *domain.Delivery) *mail.Address {
+	from := &mail.Address{Name: b.From.Name, Address: b.From.Address}
+	if d.FromEmail != "" {
+		from = &mail.Address{Name: d.FromName, Address: d.FromEmail}
+	} else if d.FromName != "" {
+		from.Name = d.FromName
+	}
+	return from
+}
+
+// MessageID returns the Message-ID of d without angle brackets. A delivery keeps
+// its stored ID; otherwise one is derived from the delivery ID and the sender
+// domain so that every attempt of the same delivery uses the same ID.
+func (b *Builder) MessageID(d *domain.Delivery) string {
+	if d.MessageID != nil && *d.MessageID != "" {
+		return *d.MessageID
+	}
+	host := "localhost"
+	from := b.Sender(d).Address
+	if at := strings.LastIndexByte(from, '@'); at >= 0 && at < len(from)-1 {
+		host = from[at+1:]
+	}
+	return d.ID + "@" + host
+}
+
+// Build renders d into a message. Text bodies are quoted-printable encoded and
+// non-ASCII header text is RFC 2047 encoded. The message is signed by the
+// builder's Signer, if any. If d has no Message-ID yet, the generated one is
+// stored in d.MessageID so the caller can persist it.
+func (b *Builder) Build(d *domain.Delivery) ([]byte, error) {
+	if err := mailer.ValidateHeaders(d.Headers); err != nil {
+		return nil, err
+	}
+
+	var h mail.Header
+	if err := setCustomHeaders(&h, d.Headers); err != nil {
+		return nil, err
+	}
+
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	messageID := b.MessageID(d)
+
+	from := b.Sender(d)
+	h.SetAddressList("From", []*mail.Address{from})
+	h.SetAddressList("To", []*mail.Address{{Name: d.Name, Address: d.Email}})
+	h.SetSubject(d.Subject)
+	h.SetDate(now())
+	h.SetMessageID(messageID)
+	h.Set(mailer.HeadmailDeliveryHeaderName, d.ID)
+	for _, name := range b.DeliveryHeaders {
+		h.Set(name, d.ID)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBody(&buf, h, d); err != nil {
+		return nil, err
+	}
+	msg := buf.Bytes()
+
+	if b.Signer != nil {
+		signed, err := b.Signer.Sign(from.Address, msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = signed
+	}
+
+	d.MessageID = &messageID
+	return msg, nil
+}
+
+// setCustomHeaders adds the delivery's custom headers in a stable order.
+func setCustomHeaders(h *mail.Header, headers map[string]string) error {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		value := headers[name]
+		if mailer.IsAddressHeader(key) {
+			addrs, err := netmail.ParseAddressList(value)
+			if err != nil {
+				return err
+			}
+			list := make([]*mail.Address, len(addrs))
+			for i, addr := range addrs {
+				list[i] = (*mail.Address)(addr)
+			}
+			h.SetAddressList(key, list)
+		} else {
+			h.SetText(key, value)
+		}
+	}
+	return nil
+}
+
+func writeBody(w io.Writer, h mail.Header, d *domain.Delivery) error {
+	if d.BodyText != "" && d.BodyHTML != "" {
+		mw, err := mail.CreateInlineWriter(w, h)
+		if err != nil {
+			return err
+		}
+		if err := writePart(mw, "text/plain", d.BodyText); err != nil {
+			return err
+		}
+		if err := writePart(mw, "text/html", d.BodyHTML); err != nil {
+			return err
+		}
+		return mw.Close()
+	}
+
+	contentType, body := "text/plain", d.BodyText
+	if d.BodyHTML != "" {
+		contentType, body = "text/html", d.BodyHTML
+	}
+	h.SetContentType(contentType, map[string]string{"charset": "utf-8"})
+	bw, err := mail.CreateSingleInlineWriter(w, h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(bw, body); err != nil {
+		return err
+	}
+	return bw.Close()
+}
+
+func writePart(mw *mail.InlineWriter, contentType, body string) error {
+	var ph mail.InlineHeader
+	ph.SetContentType(contentType, map[string]string{"charset": "utf-8"})
+	pw, err := mw.CreatePart(ph)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(pw, body); err != nil {
+		return err
+	}
+	return pw.Close()
+}
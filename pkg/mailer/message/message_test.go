@@ -0,0 +1,164 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package message
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBuilder() *Builder {
+	b := NewBuilder("No Reply", "no-reply@example.com")
+	b.Now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return b
+}
+
+func readMessage(t *testing.T, raw []byte) *mail.Reader {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	return mr
+}
+
+func TestBuild_Headers(t *testing.T) {
+	d := &domain.Delivery{
+		ID:        "d1",
+		Name:      "Bob",
+		Email:     "bob@example.org",
+		FromName:  "Café News",
+		FromEmail: "news@news.example.com",
+		Subject:   "Bonjour à tous",
+		BodyText:  "hi",
+		Headers: map[string]string{
+			"reply-to":   "Équipe <team@example.com>",
+			"List-Id":    "News <news.example.com>",
+			"Precedence": "bulk",
+		},
+	}
+	b := newTestBuilder()
+	b.DeliveryHeaders = []string{"X-PM-Metadata-headmail-delivery"}
+	raw, err := b.Build(d)
+	require.NoError(t, err)
+
+	// non-ASCII header text is encoded on the wire
+	assert.NotContains(t, string(raw), "Café")
+	assert.NotContains(t, string(raw), "à")
+
+	h := readMessage(t, raw).Header
+	from, err := h.AddressList("From")
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "Café News", Address: "news@news.example.com"}}, from)
+	to, err := h.AddressList("To")
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "Bob", Address: "bob@example.org"}}, to)
+	replyTo, err := h.AddressList("Reply-To")
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "Équipe", Address: "team@example.com"}}, replyTo)
+
+	subject, err := h.Subject()
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour à tous", subject)
+	date, err := h.Date()
+	require.NoError(t, err)
+	assert.True(t, date.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.Equal(t, "News <news.example.com>", h.Get("List-Id"))
+	assert.Equal(t, "bulk", h.Get("Precedence"))
+	assert.Equal(t, "d1", h.Get(mailer.HeadmailDeliveryHeaderName))
+	assert.Equal(t, "d1", h.Get("X-PM-Metadata-headmail-delivery"))
+}
+
+func TestBuild_MessageID(t *testing.T) {
+	b := newTestBuilder()
+	d := &domain.Delivery{ID: "d1", Email: "bob@example.org", BodyText: "hi"}
+
+	raw, err := b.Build(d)
+	require.NoError(t, err)
+	require.NotNil(t, d.MessageID)
+	assert.Equal(t, "d1@example.com", *d.MessageID)
+	id, err := readMessage(t, raw).Header.MessageID()
+	require.NoError(t, err)
+	assert.Equal(t, "d1@example.com", id)
+
+	// a stored Message-ID is reused on retries
+	stored := "original@example.net"
+	d.MessageID = &stored
+	raw, err = b.Build(d)
+	require.NoError(t, err)
+	id, err = readMessage(t, raw).Header.MessageID()
+	require.NoError(t, err)
+	assert.Equal(t, "original@example.net", id)
+}
+
+func TestBuild_MultipartAlternative(t *testing.T) {
+	longLine := strings.Repeat("Grüße ", 40)
+	d := &domain.Delivery{
+		ID:       "d1",
+		Email:    "bob@example.org",
+		Subject:  "Hello",
+		BodyText: longLine,
+		BodyHTML: "<p>" + longLine + "</p>",
+	}
+	raw, err := newTestBuilder().Build(d)
+	require.NoError(t, err)
+
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		assert.LessOrEqual(t, len(line), 78, "line too long: %q", line)
+	}
+
+	mr := readMessage(t, raw)
+	mediaType, _, err := mr.Header.ContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var types []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		h, ok := p.Header.(*mail.InlineHeader)
+		require.True(t, ok)
+		ct, params, err := h.ContentType()
+		require.NoError(t, err)
+		assert.Equal(t, "utf-8", params["charset"])
+		assert.Equal(t, "quoted-printable", h.Get("Content-Transfer-Encoding"))
+		types = append(types, ct)
+
+		body, err := io.ReadAll(p.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), longLine)
+	}
+	assert.Equal(t, []string{"text/plain", "text/html"}, types)
+	assert.NotContains(t, string(raw), "BOUNDARY_d1")
+}
+
+func TestBuild_SinglePart(t *testing.T) {
+	raw, err := newTestBuilder().Build(&domain.Delivery{ID: "d1", Email: "bob@example.org", BodyHTML: "<p>hi</p>"})
+	require.NoError(t, err)
+
+	mr := readMessage(t, raw)
+	mediaType, params, err := mr.Header.ContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html", mediaType)
+	assert.Equal(t, "utf-8", params["charset"])
+	assert.Equal(t, "1.0", mr.Header.Get("Mime-Version"))
+}
+
+func TestBuild_RejectsHeaderInjection(t *testing.T) {
+	_, err := newTestBuilder().Build(&domain.Delivery{
+		ID:      "d1",
+		Email:   "bob@example.org",
+		Headers: map[string]string{"X-Tag": "a\r\nBcc: eve@example.net"},
+	})
+	assert.ErrorIs(t, err, mailer.ErrInvalidHeader)
+}
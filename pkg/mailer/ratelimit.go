@@ -0,0 +1,126 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"golang.org/x/time/rate"
+)
+
+// DomainLimit limits sends to recipients of a single domain to Limit messages per Interval.
+type DomainLimit struct {
+	Domain   string
+	Limit    int
+	Interval time.Duration
+	// Burst is the number of messages that may be sent back to back. Defaults to 1.
+	Burst int
+}
+
+// maxGlobalWait is the longest delay of the global limit Send waits for. Longer delays
+// are returned as a RateLimitError.
+const maxGlobalWait = time.Second
+
+// RateLimitError is returned by RateLimitedMailer.Send when a send would exceed a rate
+// limit. Nothing was sent; the send may be retried after Delay.
+type RateLimitError struct {
+	// Domain is the recipient domain whose limit was reached, or "" for the global limit.
+	Domain string
+	Delay  time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Domain == "" {
+		return fmt.Sprintf("rate limit: retry in %s", e.Delay)
+	}
+	return fmt.Sprintf("rate limit of %s: retry in %s", e.Domain, e.Delay)
+}
+
+// RateLimitedMailer wraps a Mailer and keeps sends within a global rate and optional
+// per recipient domain rates. Sends are not held back while they wait for a domain
+// limit: they fail with a RateLimitError instead, so callers can reschedule them. A
+// single instance should be shared by all workers of the process.
+type RateLimitedMailer struct {
+	next    Mailer
+	global  *rate.Limiter
+	domains map[string]*rate.Limiter
+}
+
+// NewRateLimitedMailer wraps next. perSecond is the global limit in messages per second;
+// zero or less disables the global limit.
+func NewRateLimitedMailer(next Mailer, perSecond int, domains []DomainLimit) *RateLimitedMailer {
+	m := &RateLimitedMailer{
+		next:    next,
+		domains: make(map[string]*rate.Limiter),
+	}
+	if perSecond > 0 {
+		m.global = rate.NewLimiter(rate.Limit(perSecond), 1)
+	}
+	for _, d := range domains {
+		if d.Limit <= 0 || d.Interval <= 0 {
+			continue
+		}
+		burst := d.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		limit := rate.Limit(float64(d.Limit) / d.Interval.Seconds())
+		m.domains[strings.ToLower(d.Domain)] = rate.NewLimiter(limit, burst)
+	}
+	return m
+}
+
+// Send reserves a token of the recipient domain's limiter and of the global one, and
+// sends. It returns a RateLimitError without sending if the domain limit is reached or
+// the global limit needs a wait longer than maxGlobalWait. Shorter global waits are
+// waited out; Send returns an error without sending if ctx is done meanwhile.
+func (m *RateLimitedMailer) Send(ctx context.Context, d *domain.Delivery) error {
+	now := time.Now()
+	var reserved *rate.Reservation
+	domainName := recipientDomain(d.Email)
+	if limiter, ok := m.domains[domainName]; ok {
+		reserved = limiter.ReserveN(now, 1)
+		if delay := reserved.DelayFrom(now); delay > 0 {
+			reserved.CancelAt(now)
+			return &RateLimitError{Domain: domainName, Delay: delay}
+		}
+	}
+	if m.global != nil {
+		r := m.global.ReserveN(now, 1)
+		delay := r.DelayFrom(now)
+		if delay > maxGlobalWait {
+			r.CancelAt(now)
+			if reserved != nil {
+				reserved.CancelAt(now)
+			}
+			return &RateLimitError{Delay: delay}
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				r.Cancel()
+				if reserved != nil {
+					reserved.Cancel()
+				}
+				return fmt.Errorf("rate limit: %w", ctx.Err())
+			}
+		}
+	}
+	return m.next.Send(ctx, d)
+}
+
+func recipientDomain(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(email[at+1:], ">"))
+}
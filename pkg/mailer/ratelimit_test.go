@@ -0,0 +1,82 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+type countingMailer struct {
+	sent int32
+}
+
+func (m *countingMailer) Send(ctx context.Context, d *domain.Delivery) error {
+	atomic.AddInt32(&m.sent, 1)
+	return nil
+}
+
+func TestRateLimitedMailer_Global(t *testing.T) {
+	next := &countingMailer{}
+	m := NewRateLimitedMailer(next, 20, nil)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "a@example.com"}))
+	}
+	// the first send is immediate, the remaining four are spaced 50ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+	assert.Equal(t, int32(5), next.sent)
+}
+
+func TestRateLimitedMailer_Domain(t *testing.T) {
+	next := &countingMailer{}
+	m := NewRateLimitedMailer(next, 0, []DomainLimit{
+		{Domain: "Gmail.com", Limit: 1, Interval: time.Hour},
+	})
+
+	require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "a@gmail.com"}))
+
+	// other domains are not limited
+	for i := 0; i < 3; i++ {
+		require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "a@example.com"}))
+	}
+
+	// the second gmail.com send exceeds the domain limit and fails without waiting
+	start := time.Now()
+	err := m.Send(context.Background(), &domain.Delivery{Email: "b@GMAIL.COM"})
+	var limited *RateLimitError
+	require.ErrorAs(t, err, &limited)
+	assert.Equal(t, "gmail.com", limited.Domain)
+	assert.Greater(t, limited.Delay, 59*time.Minute)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, int32(4), next.sent)
+}
+
+func TestRateLimitedMailer_GlobalDelay(t *testing.T) {
+	next := &countingMailer{}
+	m := NewRateLimitedMailer(next, 1, []DomainLimit{{Domain: "gmail.com", Limit: 10, Interval: time.Second, Burst: 10}})
+
+	require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "a@gmail.com"}))
+	// the next global token is a second away, which is waited out
+	start := time.Now()
+	require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "b@gmail.com"}))
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	// longer global delays fail and give back the domain token
+	m = NewRateLimitedMailer(next, 0, nil)
+	m.global = rate.NewLimiter(rate.Every(time.Minute), 1)
+	require.NoError(t, m.Send(context.Background(), &domain.Delivery{Email: "a@example.com"}))
+	var limited *RateLimitError
+	require.ErrorAs(t, m.Send(context.Background(), &domain.Delivery{Email: "b@example.com"}), &limited)
+	assert.Empty(t, limited.Domain)
+	assert.Equal(t, int32(3), next.sent)
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+var (
+	// ErrSenderNotAllowed is returned for a From address outside the allowed sender domains.
+	ErrSenderNotAllowed = errors.New("sender address is not allowed")
+	// ErrInvalidHeader is returned for a custom header that is malformed or managed by the mailer.
+	ErrInvalidHeader = errors.New("invalid mail header")
+)
+
+// reservedHeaders are always set by the mailer and cannot be overridden by custom headers.
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"Sender":                    true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Return-Path":               true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	textproto.CanonicalMIMEHeaderKey(HeadmailDeliveryHeaderName): true,
+}
+
+// addressHeaders hold address lists and are validated as such.
+var addressHeaders = map[string]bool{
+	"Reply-To": true,
+}
+
+// SenderPolicy restricts which From addresses and custom headers a campaign or
+// delivery may use.
+type SenderPolicy struct {
+	// AllowedDomains lists the domains a From address may use. Empty allows any domain.
+	AllowedDomains []string
+}
+
+// Check validates a sender address and custom headers. An empty fromEmail means the
+// configured default sender is used and is always allowed.
+func (p SenderPolicy) Check(fromEmail string, headers map[string]string) error {
+	if fromEmail != "" {
+		addr, err := mail.ParseAddress(fromEmail)
+		if err != nil || addr.Address != fromEmail {
+			return fmt.Errorf("%w: invalid address '%s'", ErrSenderNotAllowed, fromEmail)
+		}
+		if !p.domainAllowed(recipientDomain(fromEmail)) {
+			return fmt.Errorf("%w: domain of '%s' is not in the allowed sender domains", ErrSenderNotAllowed, fromEmail)
+		}
+	}
+	return ValidateHeaders(headers)
+}
+
+func (p SenderPolicy) domainAllowed(domain string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedDomains {
+		if strings.EqualFold(allowed, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateHeaders checks that custom headers have valid names, single line values
+// and do not override headers set by the mailer.
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: bad header name '%s'", ErrInvalidHeader, name)
+		}
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if reservedHeaders[key] {
+			return fmt.Errorf("%w: '%s' is set by the mailer", ErrInvalidHeader, name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: value of '%s' contains a line break", ErrInvalidHeader, name)
+		}
+		if addressHeaders[key] {
+			if _, err := mail.ParseAddressList(value); err != nil {
+				return fmt.Errorf("%w: '%s': %v", ErrInvalidHeader, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IsAddressHeader reports whether the header holds an address list (e.g. Reply-To).
+func IsAddressHeader(name string) bool {
+	return addressHeaders[textproto.CanonicalMIMEHeaderKey(name)]
+}
+
+// validHeaderName reports whether name only contains printable ASCII other than ':' (RFC 5322 2.2).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderPolicy_Check(t *testing.T) {
+	p := SenderPolicy{AllowedDomains: []string{"example.com"}}
+
+	tests := []struct {
+		name      string
+		fromEmail string
+		headers   map[string]string
+		wantErr   error
+	}{
+		{name: "default sender", fromEmail: ""},
+		{name: "allowed domain", fromEmail: "news@Example.com"},
+		{name: "other domain", fromEmail: "ceo@bank.com", wantErr: ErrSenderNotAllowed},
+		{name: "display name smuggled in", fromEmail: "Bank <ceo@bank.com>", wantErr: ErrSenderNotAllowed},
+		{name: "custom headers", headers: map[string]string{
+			"Reply-To":   "Support <support@example.com>",
+			"List-Id":    "News <news.example.com>",
+			"Precedence": "bulk",
+		}},
+		{name: "reserved header", headers: map[string]string{"from": "x@bank.com"}, wantErr: ErrInvalidHeader},
+		{name: "header injection", headers: map[string]string{"X-Tag": "a\r\nBcc: x@bank.com"}, wantErr: ErrInvalidHeader},
+		{name: "bad header name", headers: map[string]string{"X Tag": "a"}, wantErr: ErrInvalidHeader},
+		{name: "bad reply-to", headers: map[string]string{"Reply-To": "not an address"}, wantErr: ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.fromEmail, tt.headers)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSenderPolicy_AnyDomain(t *testing.T) {
+	assert.NoError(t, SenderPolicy{}.Check("someone@anywhere.org", nil))
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/headmail/headmail/pkg/signing"
+)
+
+// returnPathPurpose binds VERP tags to return paths.
+const returnPathPurpose = "verp"
+
+const (
+	// maxLocalPartLength is the RFC 5321 limit of the local part of an address.
+	maxLocalPartLength = 64
+	// deliveryIDLength is the length of the UUIDs used as delivery IDs.
+	deliveryIDLength = 36
+)
+
+// ReturnPath builds per-delivery VERP envelope senders from a base address, e.g.
+// bounce@bounces.example.com becomes bounce+<delivery id>.<tag>@bounces.example.com.
+// Bounces are returned to that address, so they can be correlated with their delivery
+// even when the returned message is stripped. The tag authenticates the delivery ID so
+// forged bounces cannot be attributed to arbitrary deliveries.
+type ReturnPath struct {
+	local   string
+	domain  string
+	keyring *signing.Keyring
+}
+
+// NewReturnPath creates a ReturnPath from the base address, whose mailbox must receive
+// mail for its subaddresses (local+anything@domain). The local part must be short enough
+// for the VERP addresses built from it to stay within 64 characters.
+func NewReturnPath(address string, keyring *signing.Keyring) (*ReturnPath, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil || addr.Address != address {
+		return nil, fmt.Errorf("invalid return path '%s'", address)
+	}
+	at := strings.LastIndexByte(address, '@')
+	local := address[:at]
+	if strings.Contains(local, "+") {
+		return nil, fmt.Errorf("return path '%s' must not contain '+'", address)
+	}
+	p := &ReturnPath{
+		local:   strings.ToLower(local),
+		domain:  strings.ToLower(address[at+1:]),
+		keyring: keyring,
+	}
+	verpLocal := strings.LastIndexByte(p.Address(strings.Repeat("0", deliveryIDLength)), '@')
+	if verpLocal > maxLocalPartLength {
+		return nil, fmt.Errorf("local part of return path '%s' is longer than %d characters", address, maxLocalPartLength-(verpLocal-len(local)))
+	}
+	return p, nil
+}
+
+// Address returns the envelope sender for the delivery.
+func (p *ReturnPath) Address(deliveryID string) string {
+	return p.local + "+" + deliveryID + "." + p.keyring.Tag(returnPathPurpose, deliveryID) + "@" + p.domain
+}
+
+// DeliveryID returns the delivery ID encoded in address, or "" when address is not a
+// return path of p with a valid tag. Surrounding angle brackets are ignored.
+func (p *ReturnPath) DeliveryID(address string) string {
+	address = strings.ToLower(strings.Trim(strings.TrimSpace(address), "<>"))
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 || address[at+1:] != p.domain {
+		return ""
+	}
+	extension, ok := strings.CutPrefix(address[:at], p.local+"+")
+	if !ok {
+		return ""
+	}
+	dot := strings.LastIndexByte(extension, '.')
+	if dot < 0 {
+		return ""
+	}
+	deliveryID, tag := extension[:dot], extension[dot+1:]
+	if deliveryID == "" || !p.keyring.VerifyTag(returnPathPurpose, deliveryID, tag) {
+		return ""
+	}
+	return deliveryID
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReturnPath(t *testing.T) {
+	key, err := signing.GenerateKey()
+	require.NoError(t, err)
+	keyring, err := signing.NewKeyring(key)
+	require.NoError(t, err)
+	p, err := NewReturnPath("Bounce@Bounces.Example.com", keyring)
+	require.NoError(t, err)
+
+	const id = "0b6f3f8e-4c1d-4f0a-9a57-1f5b7c2d9e31"
+	addr := p.Address(id)
+	assert.True(t, strings.HasPrefix(addr, "bounce+"+id+"."))
+	assert.True(t, strings.HasSuffix(addr, "@bounces.example.com"))
+	assert.LessOrEqual(t, strings.IndexByte(addr, '@'), 64, "local part fits RFC 5321 limits")
+
+	assert.Equal(t, id, p.DeliveryID(addr))
+	assert.Equal(t, id, p.DeliveryID("<"+strings.ToUpper(addr)+">"), "MTAs may change case")
+
+	forged := strings.Replace(addr, id, "11111111-2222-3333-4444-555555555555", 1)
+	for _, invalid := range []string{
+		forged,
+		strings.Replace(addr, "@bounces.example.com", "@example.com", 1),
+		strings.Replace(addr, "bounce+", "other+", 1),
+		"bounce@bounces.example.com",
+		"bounce+" + id + "@bounces.example.com",
+	} {
+		assert.Empty(t, p.DeliveryID(invalid), invalid)
+	}
+
+	// bounce+<36>.<16> leaves 10 characters for the local part
+	_, err = NewReturnPath("abcdefghij@example.com", keyring)
+	assert.NoError(t, err)
+	for _, invalid := range []string{"not an address", "Bounces <bounce@example.com>", "bounce+x@example.com", "abcdefghijk@example.com"} {
+		_, err := NewReturnPath(invalid, keyring)
+		assert.Error(t, err, invalid)
+	}
+}
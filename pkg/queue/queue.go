@@ -6,6 +6,8 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 )
 
 type Status string
@@ -14,7 +16,8 @@ const (
 	StatusPending  Status = "pending"
 	StatusReserved Status = "reserved"
 	StatusDone     Status = "done"
-	StatusFailed   Status = "failed"
+	// StatusDead marks items that used up their attempts or failed permanently (dead-letter).
+	StatusDead Status = "dead"
 )
 
 // QueueItem represents a generic queue entry.
@@ -24,10 +27,40 @@ type QueueItem struct {
 	Type       string          `json:"type"`                  // arbitrary topic/type
 	Payload    json.RawMessage `json:"payload"`               // opaque payload (JSON)
 	UniqueKey  *string         `json:"unique_key,omitempty"`  // optional deduplication key
-	Status     Status          `json:"status"`                // pending/reserved/done/failed
+	Status     Status          `json:"status"`                // pending/reserved/done/dead
 	ReservedBy *string         `json:"reserved_by,omitempty"` // worker id that reserved the item
 	ReservedAt *int64          `json:"reserved_at,omitempty"`
-	CreatedAt  int64           `json:"created_at"`
+	// Attempts counts how many times the item has been claimed.
+	Attempts int `json:"attempts"`
+	// AvailableAt is the unix time from which the item may be claimed.
+	AvailableAt int64   `json:"available_at"`
+	LastError   *string `json:"last_error,omitempty"`
+	CreatedAt   int64   `json:"created_at"`
+}
+
+// ErrNotReplayable is returned when replaying an item that is not dead-lettered.
+var ErrNotReplayable = errors.New("only dead queue items can be replayed")
+
+// ErrLeaseLost is returned by Ack and Fail when the item is no longer reserved by the
+// worker, e.g. because its lease expired and another worker claimed it again.
+var ErrLeaseLost = errors.New("queue item lease lost")
+
+// Filter selects queue items for inspection, replay and purge.
+// Zero values match everything.
+type Filter struct {
+	Type   string   `json:"type,omitempty"`
+	Status []Status `json:"status,omitempty"`
+	// CreatedBefore matches items created before this unix time.
+	CreatedBefore int64 `json:"created_before,omitempty"`
+}
+
+// ClaimFilter restricts the types of the items Claim reserves. The zero value claims
+// items of any type.
+type ClaimFilter struct {
+	// Types, if not empty, claims only items of these types.
+	Types []string
+	// ExcludeTypes skips items of these types.
+	ExcludeTypes []string
 }
 
 // Queue is an abstract queue interface. All methods accept context so that
@@ -37,15 +70,78 @@ type Queue interface {
 	// deduplication via UniqueKey, duplicate inserts should be ignored.
 	Enqueue(ctx context.Context, item *QueueItem) error
 
-	// Claim atomically reserves up to `limit` pending items that are ready
-	// Claimed items should have Status changed to "reserved" and reserved_by set.
-	Claim(ctx context.Context, workerID string, limit int) ([]*QueueItem, error)
+	// Claim atomically reserves up to `limit` pending items whose AvailableAt has passed
+	// and whose type passes filter.
+	// Claimed items should have Status changed to "reserved", reserved_by set and Attempts incremented.
+	Claim(ctx context.Context, workerID string, limit int, filter ClaimFilter) ([]*QueueItem, error)
+
+	// Ack marks the queue item reserved by workerID as successfully processed (can delete
+	// or mark done). It returns ErrLeaseLost if the item is not reserved by workerID.
+	Ack(ctx context.Context, id string, workerID string) error
+
+	// Fail records reason as the last error of the item reserved by workerID. If retryAt
+	// is non-nil the item returns to pending and becomes available again at that unix
+	// time; otherwise it is moved to the dead-letter state. It returns ErrLeaseLost if
+	// the item is not reserved by workerID.
+	Fail(ctx context.Context, id string, workerID string, reason string, retryAt *int64) error
+
+	// Defer returns the item reserved by workerID to pending without using up an attempt,
+	// e.g. because it hit a rate limit, and makes it available again at availableAt. It
+	// returns ErrLeaseLost if the item is not reserved by workerID.
+	Defer(ctx context.Context, id string, workerID string, availableAt int64) error
+
+	// ReleaseExpired returns items reserved before reservedBefore (an expired lease,
+	// e.g. the worker crashed) to pending. Items that already reached maxAttempts are
+	// moved to the dead-letter state instead. It returns the number of items changed.
+	ReleaseExpired(ctx context.Context, reservedBefore int64, maxAttempts int) (int, error)
+
+	// List returns items matching filter, newest first, skipping offset items and
+	// returning at most limit, together with the total number of matches.
+	List(ctx context.Context, filter Filter, offset int, limit int) ([]*QueueItem, int, error)
+
+	// Get returns a single item by id.
+	Get(ctx context.Context, id string) (*QueueItem, error)
+
+	// Replay returns a dead item to pending with its attempts reset, making it
+	// available immediately. It returns ErrNotReplayable for items in any other state.
+	Replay(ctx context.Context, id string) error
 
-	// Ack marks the queue item as successfully processed (can delete or mark done).
-	Ack(ctx context.Context, id string) error
+	// ReplayMatching replays all dead items matching filter (filter.Status is ignored)
+	// and returns the number of items replayed.
+	ReplayMatching(ctx context.Context, filter Filter) (int, error)
+
+	// Purge deletes items matching filter and returns the number of items deleted.
+	Purge(ctx context.Context, filter Filter) (int, error)
+}
+
+// CommitError wraps the error of a handler that recorded the failure in its transaction.
+// The worker commits the transaction together with the retry or dead-letter of the item
+// instead of rolling it back.
+type CommitError struct {
+	Err error
+}
+
+func (e *CommitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// DeferError is returned by handlers that could not process an item yet, e.g. because
+// of a rate limit. The worker rolls back the transaction and defers the item by Delay.
+type DeferError struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *DeferError) Error() string {
+	return e.Err.Error()
+}
 
-	// Fail marks the queue item as failed for this attempt
-	Fail(ctx context.Context, id string, reason string) error
+func (e *DeferError) Unwrap() error {
+	return e.Err
 }
 
 type Handler = func(txCtx context.Context, workerID string, item *QueueItem) error
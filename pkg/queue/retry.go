@@ -0,0 +1,69 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package queue
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy computes exponential backoff delays for failed queue items.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts after which an item is dead-lettered.
+	MaxAttempts int
+	// InitialInterval is the delay after the first failed attempt.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay.
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after every further attempt.
+	Multiplier float64
+	// Jitter randomizes the delay by up to ±Jitter (a fraction, e.g. 0.2 for ±20%).
+	Jitter float64
+}
+
+// DefaultRetryPolicy is used when no policy is configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     5,
+	InitialInterval: 30 * time.Second,
+	MaxInterval:     time.Hour,
+	Multiplier:      2,
+	Jitter:          0.2,
+}
+
+// Backoff returns the delay before the next attempt after `attempts` failed attempts.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Exhausted reports whether an item that failed its attempts-th attempt has used up
+// its attempts and is dead-lettered.
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// NextRetry returns the unix time at which an item that failed its attempts-th
+// attempt should be retried, or nil when it has used up its attempts.
+func (p RetryPolicy) NextRetry(attempts int, now time.Time) *int64 {
+	if p.Exhausted(attempts) {
+		return nil
+	}
+	retryAt := now.Add(p.Backoff(attempts)).Unix()
+	return &retryAt
+}
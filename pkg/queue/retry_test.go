@@ -0,0 +1,52 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:     10,
+		InitialInterval: 10 * time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2,
+	}
+
+	assert.Equal(t, 10*time.Second, p.Backoff(1))
+	assert.Equal(t, 20*time.Second, p.Backoff(2))
+	assert.Equal(t, 40*time.Second, p.Backoff(3))
+	assert.Equal(t, time.Minute, p.Backoff(4), "capped by MaxInterval")
+	assert.Equal(t, time.Minute, p.Backoff(20))
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	p := RetryPolicy{InitialInterval: 100 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		assert.GreaterOrEqual(t, d, 80*time.Second)
+		assert.LessOrEqual(t, d, 120*time.Second)
+	}
+}
+
+func TestRetryPolicy_NextRetry(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Minute, Multiplier: 2}
+	now := time.Unix(1_700_000_000, 0)
+
+	retryAt := p.NextRetry(1, now)
+	require.NotNil(t, retryAt)
+	assert.Equal(t, now.Unix()+60, *retryAt)
+
+	retryAt = p.NextRetry(2, now)
+	require.NotNil(t, retryAt)
+	assert.Equal(t, now.Unix()+120, *retryAt)
+
+	assert.Nil(t, p.NextRetry(3, now), "attempts exhausted")
+}
@@ -5,12 +5,59 @@ package receiver
 
 import "context"
 
+// EventType distinguishes the kinds of inbound events.
+type EventType string
+
+const (
+	EventTypeBounce    EventType = "bounce"
+	EventTypeComplaint EventType = "complaint"
+)
+
+// BounceType classifies a bounce by whether later mail to the recipient can succeed.
+type BounceType string
+
+const (
+	// BounceTypeHard is a permanent failure, e.g. an unknown mailbox or domain.
+	BounceTypeHard BounceType = "hard"
+	// BounceTypeSoft is a failure that may clear up, e.g. a full mailbox or a policy rejection.
+	BounceTypeSoft BounceType = "soft"
+	// BounceTypeTransient is a temporary failure or delay the sending server still retries.
+	BounceTypeTransient BounceType = "transient"
+)
+
+// RecipientStatus is the delivery status of one recipient of a bounced message.
+type RecipientStatus struct {
+	Recipient string
+	// Action is the RFC 3464 action: failed, delayed, delivered, relayed or expanded.
+	Action string
+	// Status is the enhanced status code, e.g. 5.1.1.
+	Status         string
+	DiagnosticCode string
+	RemoteMTA      string
+	BounceType     BounceType
+}
+
 type Event struct {
+	// Type is the kind of event; empty means a bounce.
+	Type              EventType
 	DeliveryID        string
 	MessageID         string
 	Subject           string
 	BouncedRecipients []string
 	Reason            string
+	// Permanent is set for hard bounces (permanent failures).
+	Permanent bool
+
+	// BounceType, Status, DiagnosticCode and RemoteMTA describe the most severe
+	// recipient failure; Recipients holds the status of every recipient.
+	BounceType     BounceType
+	Status         string
+	DiagnosticCode string
+	RemoteMTA      string
+	Recipients     []RecipientStatus
+
+	// FeedbackType is the RFC 5965 feedback type of a complaint, e.g. abuse.
+	FeedbackType string
 }
 
 // Receiver defines an interface for inbound mail receivers (bounce processors, webhooks, etc).
@@ -22,6 +22,10 @@ type DB interface {
 	QueueRepository() queue.Queue
 	// EventRepository returns an implementation for storing delivery events (opens/clicks).
 	EventRepository() EventRepository
+	SuppressionRepository() SuppressionRepository
+	APIKeyRepository() APIKeyRepository
+	AuditRepository() AuditRepository
+	LinkRepository() LinkRepository
 }
 
 // Transactionable defines the interface for transaction management.
@@ -38,6 +42,7 @@ type ListRepository interface {
 	Update(ctx context.Context, list *domain.List) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, filter ListFilter, pagination Pagination) ([]*domain.List, int, error)
+	// GetSubscriberCount returns the number of confirmed members of the list.
 	GetSubscriberCount(ctx context.Context, listID string) (int, error)
 	GetSubscribers(ctx context.Context) (chan *domain.Subscriber, error)
 
@@ -61,6 +66,65 @@ type SubscriberRepository interface {
 	List(ctx context.Context, filter SubscriberFilter, pagination Pagination) ([]*domain.Subscriber, int, error)
 	ListStream(ctx context.Context, filter SubscriberFilter) (chan *domain.Subscriber, error)
 	BulkUpsert(ctx context.Context, subscribers []*domain.Subscriber) error
+
+	// SetListStatus changes the status of the subscriber's membership in listID, or in all
+	// of the subscriber's lists when listID is empty. Memberships that already have the
+	// status are left untouched, and so are those whose status has the same or a higher
+	// precedence, e.g. a bounce does not replace a complaint. Changes of a single list to
+	// a confirmed or pending status, e.g. by an administrator, are always applied.
+	// Returns the number of memberships changed.
+	SetListStatus(ctx context.Context, subscriberID string, listID string, status domain.SubscriberListStatus, at int64) (int, error)
+	// DeleteListMemberships deletes the memberships of all subscribers that have had the
+	// given status since before the given time. Returns the number of memberships deleted.
+	DeleteListMemberships(ctx context.Context, status domain.SubscriberListStatus, updatedBefore int64) (int, error)
+}
+
+// SuppressionRepository defines the interface for suppression list storage.
+type SuppressionRepository interface {
+	// Upsert creates the entry, or updates reason, source and expiry of the existing entry
+	// with the same type and value. ID and CreatedAt are set to those of the stored entry.
+	Upsert(ctx context.Context, suppression *domain.Suppression) error
+	GetByID(ctx context.Context, id string) (*domain.Suppression, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, filter SuppressionFilter, pagination Pagination) ([]*domain.Suppression, int, error)
+	// Match returns an entry that suppresses the given lower-cased email address or domain
+	// and has not expired at now, or ErrNotFound.
+	Match(ctx context.Context, email string, domainName string, now int64) (*domain.Suppression, error)
+}
+
+// APIKeyRepository defines the interface for stored admin API keys.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByID(ctx context.Context, id string) (*domain.APIKey, error)
+	// GetByHash returns the key with the given hex encoded SHA-256 hash, revoked or not.
+	GetByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	// Update stores name, prefix, hash, scopes and revocation of the key.
+	Update(ctx context.Context, key *domain.APIKey) error
+	List(ctx context.Context, pagination Pagination) ([]*domain.APIKey, int, error)
+	// TouchLastUsed sets the last use of the key to at.
+	TouchLastUsed(ctx context.Context, id string, at int64) error
+}
+
+// AuditRepository defines the interface for audit log storage.
+type AuditRepository interface {
+	Create(ctx context.Context, entry *domain.AuditEntry) error
+	// List returns entries matching the filter, newest first.
+	List(ctx context.Context, filter AuditFilter, pagination Pagination) ([]*domain.AuditEntry, int, error)
+}
+
+// LinkRepository defines the interface for tracked link storage.
+type LinkRepository interface {
+	// Upsert creates the link unless a link with its ID exists. URL, ClickCount and
+	// CreatedAt are set to those of the stored link.
+	Upsert(ctx context.Context, link *domain.Link) error
+	// UpsertLimited is like Upsert, but does not create the link when its campaign
+	// already has limit links, and then returns false. The links of a campaign are
+	// counted on the campaign, so concurrent calls do not exceed the limit.
+	UpsertLimited(ctx context.Context, link *domain.Link, limit int) (bool, error)
+	GetByID(ctx context.Context, id string) (*domain.Link, error)
+	// ListByCampaign returns the links of a campaign, most clicked first.
+	ListByCampaign(ctx context.Context, campaignID string, pagination Pagination) ([]*domain.Link, int, error)
+	IncrementClicks(ctx context.Context, id string) error
 }
 
 // CampaignRepository defines the interface for campaign storage.
@@ -77,7 +141,7 @@ type CampaignRepository interface {
 
 	// IncrementStats atomically increments per-campaign counters.
 	// Provide deltas for fields you want to change; pass 0 for no-op.
-	IncrementStats(ctx context.Context, id string, recipientDelta int, deliveredDelta int, failedDelta int, openDelta int, clickDelta int, bounceDelta int) error
+	IncrementStats(ctx context.Context, id string, recipientDelta int, deliveredDelta int, failedDelta int, openDelta int, clickDelta int, bounceDelta int, complaintDelta int) error
 }
 
 // DeliveryRepository defines the interface for delivery storage.
@@ -127,6 +191,10 @@ type EventRepository interface {
 
 	// CountByCampaignAndRangeByType returns aggregated event counts filtered by event_type grouped by campaign and bucket time.
 	CountByCampaignAndRangeByType(ctx context.Context, campaignIDs []string, eventType string, from int64, to int64, granularity string) (map[string]map[int64]int64, error)
+
+	// CountDeliveriesByRecipient returns the number of distinct deliveries to email that
+	// have at least one event of eventType created at or after since.
+	CountDeliveriesByRecipient(ctx context.Context, email string, eventType domain.EventType, since int64) (int, error)
 }
 
 // Filter Types
@@ -136,6 +204,22 @@ type ListFilter struct {
 	Tags   []string `json:"tags,omitempty"`
 }
 
+type SuppressionFilter struct {
+	Type   domain.SuppressionType   `json:"type,omitempty"`
+	Reason domain.SuppressionReason `json:"reason,omitempty"`
+	Search string                   `json:"search,omitempty"`
+}
+
+type AuditFilter struct {
+	Principal  string `json:"principal,omitempty"`
+	Action     string `json:"action,omitempty"`
+	EntityType string `json:"entity_type,omitempty"`
+	EntityID   string `json:"entity_id,omitempty"`
+	// Since and Until bound CreatedAt, inclusively; zero leaves them open.
+	Since int64 `json:"since,omitempty"`
+	Until int64 `json:"until,omitempty"`
+}
+
 type SubscriberFilter struct {
 	ListID     string                      `json:"list_id,omitempty"`
 	ListStatus domain.SubscriberListStatus `json:"list_status,omitempty"`
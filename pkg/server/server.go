@@ -5,18 +5,25 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	http_swagger "github.com/headmail/headmail/internal/http-swagger"
 	"github.com/headmail/headmail/internal/mail/imap"
 	"github.com/headmail/headmail/internal/mail/smtp"
+	"github.com/headmail/headmail/internal/mail/webhook"
 	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/mailer/dkim"
+	"github.com/headmail/headmail/pkg/mailer/message"
 	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/headmail/headmail/pkg/signing"
 	"github.com/headmail/headmail/pkg/template"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -24,22 +31,36 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/headmail/headmail/pkg/api/admin"
 	"github.com/headmail/headmail/pkg/api/public"
+	"github.com/headmail/headmail/pkg/audit"
+	"github.com/headmail/headmail/pkg/auth"
 	"github.com/headmail/headmail/pkg/config"
 	"github.com/headmail/headmail/pkg/db"
+	"github.com/headmail/headmail/pkg/queue"
 	"github.com/headmail/headmail/pkg/repository"
 	"github.com/headmail/headmail/pkg/service"
 
 	// Import providers to register them
+	_ "github.com/headmail/headmail/internal/db/mysql"
+	_ "github.com/headmail/headmail/internal/db/postgres"
 	_ "github.com/headmail/headmail/internal/db/sqlite"
 )
 
+// pendingExpiryInterval is how often unconfirmed subscriptions are looked for.
+const pendingExpiryInterval = 10 * time.Minute
+
 // Server holds the dependencies for an HTTP server.
 type Server struct {
 	cfg *config.Config
 	db  repository.DB
 
-	mailer   mailer.Mailer
-	receiver receiver.Receiver
+	mailer    mailer.Mailer
+	receivers []receiver.Receiver
+	// webhooks receives email service provider webhooks on the public server.
+	webhooks *webhook.Receiver
+
+	// adminAuth authenticates admin API requests; empty leaves the API open.
+	adminAuth []auth.Authenticator
+	adminTLS  *tls.Config
 
 	adminRouter  *chi.Mux
 	publicRouter *chi.Mux
@@ -49,12 +70,21 @@ type Server struct {
 	startTime time.Time
 	promReg   *prometheus.Registry
 
+	// cancel stops the background scheduler, reaper and worker pool.
+	cancel     context.CancelFunc
+	workerPool *WorkerPool
+
 	// Services
-	listService     service.ListServiceProvider
-	campaignService service.CampaignServiceProvider
-	deliveryService service.DeliveryServiceProvider
-	templateService service.TemplateServiceProvider
-	trackingService service.TrackingServiceProvider
+	listService         service.ListServiceProvider
+	campaignService     service.CampaignServiceProvider
+	deliveryService     service.DeliveryServiceProvider
+	templateService     service.TemplateServiceProvider
+	trackingService     service.TrackingServiceProvider
+	queueService        service.QueueServiceProvider
+	subscriptionService service.SubscriptionServiceProvider
+	suppressionService  service.SuppressionServiceProvider
+	apiKeyService       service.APIKeyServiceProvider
+	auditService        service.AuditServiceProvider
 }
 
 // Option defines a function that configures a Server.
@@ -73,9 +103,11 @@ func WithMailer(m mailer.Mailer) Option {
 	}
 }
 
+// WithReceiver adds an inbound receiver. Without receivers given as options, the
+// configured IMAP and SMTP receivers are used.
 func WithReceiver(r receiver.Receiver) Option {
 	return func(s *Server) {
-		s.receiver = r
+		s.receivers = append(s.receivers, r)
 	}
 }
 
@@ -105,11 +137,90 @@ func New(cfg *config.Config, opts ...Option) (*Server, error) {
 		srv.db = dbConn
 	}
 
+	// Never change the schema implicitly; migrations are applied with `headmail migrate up`.
+	if m, ok := srv.db.(db.Migrator); ok {
+		if err := db.CheckSchema(context.Background(), m); err != nil {
+			return nil, err
+		}
+	}
+
+	adminTLS, err := newAdminTLSConfig(cfg.Server.Admin.TLS)
+	if err != nil {
+		return nil, err
+	}
+	srv.adminTLS = adminTLS
+	srv.apiKeyService = service.NewAPIKeyService(srv.db)
+	srv.adminAuth, err = newAdminAuthenticators(cfg.Server.Admin.Auth, adminTLS, srv.apiKeyService)
+	if err != nil {
+		return nil, err
+	}
+	if len(srv.adminAuth) == 0 {
+		log.Printf("Warning: server.admin.auth.methods is not configured; the admin API is open to anyone who can reach it")
+	}
+
+	keyring, err := newKeyring(cfg.Security, cfg.Server.Public.URL)
+	if err != nil {
+		return nil, err
+	}
+	var returnPath *mailer.ReturnPath
+	if cfg.SMTP.ReturnPath != "" {
+		returnPath, err = mailer.NewReturnPath(cfg.SMTP.ReturnPath, keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if srv.mailer == nil && len(cfg.SMTP.Host) > 0 {
-		srv.mailer = smtp.NewMailer(cfg.SMTP)
+		builder := message.NewBuilder(cfg.SMTP.From.Name, cfg.SMTP.From.Email)
+		if len(cfg.DKIM.Keys) > 0 {
+			signer, err := newDKIMSigner(cfg.DKIM)
+			if err != nil {
+				return nil, err
+			}
+			builder.Signer = signer
+		}
+		if cfg.Webhooks.Postmark.Username != "" || cfg.Webhooks.Postmark.Password != "" {
+			builder.DeliveryHeaders = append(builder.DeliveryHeaders, webhook.PostmarkMetadataHeader)
+		}
+		smtpMailer := smtp.NewMailer(cfg.SMTP, builder)
+		smtpMailer.ReturnPath = returnPath
+		srv.mailer = smtpMailer
 	}
-	if srv.receiver == nil && len(cfg.IMAP.Host) > 0 {
-		srv.receiver = imap.NewReceiver(&cfg.IMAP)
+	if srv.mailer != nil && (cfg.SMTP.Send.Throttle > 0 || len(cfg.SMTP.Send.DomainThrottle) > 0) {
+		// one limiter shared by all workers of this process
+		domainLimits := make([]mailer.DomainLimit, 0, len(cfg.SMTP.Send.DomainThrottle))
+		for _, d := range cfg.SMTP.Send.DomainThrottle {
+			domainLimits = append(domainLimits, mailer.DomainLimit{
+				Domain:   d.Domain,
+				Limit:    d.Limit,
+				Interval: d.Interval,
+				Burst:    d.Burst,
+			})
+		}
+		srv.mailer = mailer.NewRateLimitedMailer(srv.mailer, cfg.SMTP.Send.Throttle, domainLimits)
+	}
+	if len(srv.receivers) == 0 {
+		if len(cfg.IMAP.Host) > 0 {
+			imapReceiver := imap.NewReceiver(&cfg.IMAP)
+			imapReceiver.ReturnPath = returnPath
+			srv.receivers = append(srv.receivers, imapReceiver)
+		}
+		if cfg.SMTP.Receive.Addr != "" {
+			smtpReceiver, err := smtp.NewReceiver(&cfg.SMTP)
+			if err != nil {
+				return nil, err
+			}
+			smtpReceiver.ReturnPath = returnPath
+			srv.receivers = append(srv.receivers, smtpReceiver)
+		}
+	}
+	webhooks, err := webhook.NewReceiver(&cfg.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks.Enabled() {
+		srv.webhooks = webhooks
+		srv.receivers = append(srv.receivers, webhooks)
 	}
 
 	// Initialize services
@@ -117,19 +228,31 @@ func New(cfg *config.Config, opts ...Option) (*Server, error) {
 	q := srv.db.QueueRepository()
 	// create mailer implementation from config
 	trackingHost := cfg.Server.Public.URL
-	maxAttempts := cfg.SMTP.Send.Attempts
 
 	templateService := template.NewService()
 	srv.listService = service.NewListService(srv.db)
-	srv.deliveryService = service.NewDeliveryService(srv.db, templateService, q, srv.mailer, trackingHost, maxAttempts)
+	senders := senderPolicy(cfg.SMTP)
+	srv.deliveryService = service.NewDeliveryService(srv.db, templateService, q, srv.mailer, trackingHost, srv.retryPolicy(), senders, keyring, bouncePolicy(cfg.Bounce))
 	srv.campaignService = service.NewCampaignService(
 		srv.db,
 		srv.deliveryService,
+		senders,
 	)
 
 	srv.templateService = service.NewTemplateService(srv.db)
 
-	srv.trackingService = service.NewTrackingService(srv.db)
+	if !cfg.Tracking.UnsignedLinksBefore.IsZero() {
+		log.Printf("Warning: tracking.unsigned_links_before is set; unsigned tracking URLs of deliveries created before %s are accepted. Remove it once that mail has aged out", cfg.Tracking.UnsignedLinksBefore.Format(time.RFC3339))
+	}
+	srv.trackingService = service.NewTrackingService(srv.db, keyring, cfg.Tracking.UnsignedLinksBefore)
+
+	srv.queueService = service.NewQueueService(srv.db)
+
+	srv.suppressionService = service.NewSuppressionService(srv.db)
+
+	srv.auditService = service.NewAuditService(srv.db)
+
+	srv.subscriptionService = service.NewSubscriptionService(srv.db, srv.listService, srv.deliveryService, keyring, trackingHost)
 
 	srv.startTime = time.Now()
 	srv.promReg = NewPrometheusRegistry()
@@ -155,32 +278,79 @@ func (s *Server) registerAdminRoutes() {
 	deliveryHandler := admin.NewDeliveryHandler(s.deliveryService, s.templateService)
 	subscriberHandler := admin.NewSubscriberHandler(s.listService)
 	templateHandler := admin.NewTemplateHandler(s.templateService, s.deliveryService)
+	queueHandler := admin.NewQueueHandler(s.queueService)
+	suppressionHandler := admin.NewSuppressionHandler(s.suppressionService)
+	apiKeyHandler := admin.NewAPIKeyHandler(s.apiKeyService)
+	auditHandler := admin.NewAuditHandler(s.auditService)
 
 	s.adminRouter.Route("/api", func(r chi.Router) {
-		// register monitoring (health + prometheus metrics) using helper functions
+		// register monitoring (health + prometheus metrics) using helper functions;
+		// they stay unauthenticated for probes and scrapers
 		RegisterMetricsHandler(r, s.promReg)
 		RegisterHealthHandler(r, s.startTime)
 
-		listHandler.RegisterRoutes(r)
-		campaignHandler.RegisterRoutes(r)
-		deliveryHandler.RegisterRoutes(r)
-		subscriberHandler.RegisterRoutes(r)
-		templateHandler.RegisterRoutes(r)
+		r.Group(func(r chi.Router) {
+			if len(s.adminAuth) > 0 {
+				r.Use(auth.Middleware(s.adminAuth...))
+			}
+			r.Use(audit.Middleware(s.adminRouter, s.auditService, s.auditLoaders()))
+			listHandler.RegisterRoutes(r)
+			campaignHandler.RegisterRoutes(r)
+			deliveryHandler.RegisterRoutes(r)
+			subscriberHandler.RegisterRoutes(r)
+			templateHandler.RegisterRoutes(r)
+			queueHandler.RegisterRoutes(r)
+			suppressionHandler.RegisterRoutes(r)
+			apiKeyHandler.RegisterRoutes(r)
+			auditHandler.RegisterRoutes(r)
+		})
 	})
 }
 
+// auditLoaders load the entities whose changed fields are recorded in the audit log.
+func (s *Server) auditLoaders() map[string]audit.Loader {
+	return map[string]audit.Loader{
+		"campaign": func(ctx context.Context, id string) (interface{}, error) {
+			return s.campaignService.GetCampaign(ctx, id)
+		},
+		"list": func(ctx context.Context, id string) (interface{}, error) {
+			return s.listService.GetList(ctx, id)
+		},
+		"subscriber": func(ctx context.Context, id string) (interface{}, error) {
+			return s.listService.GetSubscriber(ctx, id)
+		},
+		"template": func(ctx context.Context, id string) (interface{}, error) {
+			return s.templateService.GetTemplate(ctx, id)
+		},
+	}
+}
+
 func (s *Server) registerPublicRoutes() {
 	trackingHandler := public.NewTrackingHandler(&s.cfg.Tracking, s.trackingService)
+	unsubscribeHandler := public.NewUnsubscribeHandler(s.subscriptionService)
+	preferencesHandler := public.NewPreferencesHandler(&s.cfg.Preferences, s.subscriptionService, template.NewService())
+	subscribeHandler := public.NewSubscribeHandler(s.subscriptionService)
 
 	// Public tracking routes (open / click)
 	trackingHandler.RegisterRoutes(s.publicRouter)
+	// One-click unsubscribe links from List-Unsubscribe headers and templates
+	unsubscribeHandler.RegisterRoutes(s.publicRouter)
+	// Hosted preference center linked from templates
+	preferencesHandler.RegisterRoutes(s.publicRouter)
+	// Double opt-in subscribe forms and confirmation links
+	subscribeHandler.RegisterRoutes(s.publicRouter)
+	// Bounce and complaint webhooks of email service providers
+	if s.webhooks != nil {
+		s.webhooks.RegisterRoutes(s.publicRouter)
+	}
 }
 
 // Serve starts the admin and public API servers.
 func (s *Server) Serve() {
 	s.adminServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.cfg.Server.Admin.Port),
-		Handler: s.adminRouter,
+		Addr:      fmt.Sprintf(":%d", s.cfg.Server.Admin.Port),
+		Handler:   s.adminRouter,
+		TLSConfig: s.adminTLS,
 	}
 
 	s.publicServer = &http.Server{
@@ -188,6 +358,9 @@ func (s *Server) Serve() {
 		Handler: s.publicRouter,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
 	// start background scheduler and worker
 	q := s.db.QueueRepository()
 	// start scheduler: enqueue scheduled deliveries every minute
@@ -196,44 +369,93 @@ func (s *Server) Serve() {
 		defer ticker.Stop()
 		for {
 			processed := true
-			for processed {
+			for processed && ctx.Err() == nil {
 				processed = s.enqueueDueDeliveries() > 0
 			}
 
-			<-ticker.C
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
-	// start worker(s) - single worker for now
-	worker := NewWorker(s.db, q)
-	_ = worker.SetHandler("delivery", s.deliveryService.HandleDeliveryQueuedItem)
+	// return items of crashed workers to the queue once their lease expires
+	if s.cfg.Queue.Lease > 0 && s.cfg.Queue.ReapInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(s.cfg.Queue.ReapInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.releaseExpiredQueueItems()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// delete double opt-in subscriptions that were never confirmed
+	if s.cfg.Subscription.ConfirmationExpiry > 0 {
+		go func() {
+			ticker := time.NewTicker(pendingExpiryInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.expirePendingSubscriptions()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	s.workerPool = NewWorkerPool(s.db, q, s.retryPolicy(), WorkerPoolConfig{
+		Workers:      s.cfg.Queue.Workers,
+		BatchSize:    s.cfg.SMTP.Send.BatchSize,
+		PollInterval: s.cfg.Queue.PollInterval,
+		Concurrency:  s.cfg.Queue.Concurrency,
+	})
+	_ = s.workerPool.SetHandler("delivery", s.deliveryService.HandleDeliveryQueuedItem)
 	hostname, _ := os.Hostname()
-	go worker.Start(context.Background(), hostname+":"+uuid.NewString())
+	s.workerPool.Start(ctx, hostname+":"+uuid.NewString())
 
-	if s.receiver != nil {
-		ctx := context.Background()
-		events, err := s.receiver.Start(ctx)
+	for _, r := range s.receivers {
+		events, err := r.Start(ctx)
 		if err != nil {
-			log.Printf("imap receiver failed to start: %v", err)
-		} else {
-			go func() {
-				for {
-					event, ok := <-events
-					if !ok {
-						break
-					}
-					log.Printf("MAIL RECEIVED: %+v", event)
-					if err := s.deliveryService.HandleBouncedMail(ctx, event); err != nil {
-						log.Printf("HandleBouncedMail failed: %+v", err)
+			log.Printf("receiver %T failed to start: %v", r, err)
+			continue
+		}
+		go func() {
+			for {
+				event, ok := <-events
+				if !ok {
+					break
+				}
+				log.Printf("MAIL RECEIVED: %+v", event)
+				if event.Type == receiver.EventTypeComplaint {
+					if err := s.deliveryService.HandleComplaint(ctx, event); err != nil {
+						log.Printf("HandleComplaint failed: %+v", err)
 					}
+				} else if err := s.deliveryService.HandleBouncedMail(ctx, event); err != nil {
+					log.Printf("HandleBouncedMail failed: %+v", err)
 				}
-			}()
-		}
+			}
+		}()
 	}
 
 	go func() {
 		log.Printf("Starting admin server on port %d", s.cfg.Server.Admin.Port)
-		if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.adminTLS != nil {
+			err = s.adminServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.adminServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Could not start admin server: %v", err)
 		}
 	}()
@@ -246,6 +468,169 @@ func (s *Server) Serve() {
 	}()
 }
 
+// newDKIMSigner loads the configured DKIM keys.
+func newDKIMSigner(cfg config.DKIMConfig) (*dkim.Signer, error) {
+	keys := make([]dkim.Key, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		pemData := []byte(k.PrivateKey)
+		if k.PrivateKeyFile != "" {
+			var err error
+			if pemData, err = os.ReadFile(k.PrivateKeyFile); err != nil {
+				return nil, fmt.Errorf("dkim key for %s: %w", k.Domain, err)
+			}
+		}
+		signer, err := dkim.ParsePrivateKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("dkim key for %s: %w", k.Domain, err)
+		}
+		keys = append(keys, dkim.Key{Domain: k.Domain, Selector: k.Selector, Signer: signer})
+	}
+	return dkim.NewSigner(keys, dkim.Options{
+		Headers:                cfg.Headers,
+		HeaderCanonicalization: cfg.HeaderCanonicalization,
+		BodyCanonicalization:   cfg.BodyCanonicalization,
+	})
+}
+
+// newAdminTLSConfig loads the certificate of the admin server and the CAs client
+// certificates are verified with. It returns nil when TLS is not configured.
+func newAdminTLSConfig(cfg config.AdminTLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, fmt.Errorf("server.admin.tls.client_ca_file requires cert_file and key_file")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("admin tls: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if cfg.ClientCAFile != "" {
+		pemData, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("admin tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("admin tls: no certificates in %s", cfg.ClientCAFile)
+		}
+		// requests without a certificate may still authenticate with another method
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// newAdminAuthenticators creates the authenticators of the configured methods. keys
+// verifies the stored API keys accepted by the api_key method.
+func newAdminAuthenticators(cfg config.AdminAuthConfig, tlsConfig *tls.Config, keys auth.APIKeyVerifier) ([]auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+	for _, method := range cfg.Methods {
+		switch method {
+		case auth.MethodAPIKey:
+			a, err := auth.NewAPIKeyAuthenticator(cfg.APIKeys)
+			if err != nil {
+				return nil, err
+			}
+			// configured keys have all scopes; keys created through the API only theirs
+			authenticators = append(authenticators, a, auth.NewStoredKeyAuthenticator(keys))
+		case auth.MethodJWT:
+			a, err := auth.NewJWTAuthenticator(cfg.JWT)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, a)
+		case auth.MethodMTLS:
+			if tlsConfig == nil || tlsConfig.ClientCAs == nil {
+				return nil, fmt.Errorf("mtls auth requires server.admin.tls with client_ca_file")
+			}
+			authenticators = append(authenticators, auth.NewCertificateAuthenticator(cfg.MTLS))
+		default:
+			return nil, fmt.Errorf("unknown admin auth method '%s'", method)
+		}
+	}
+	return authenticators, nil
+}
+
+// senderPolicy restricts campaign senders to the configured domains, or to the
+// domain of the default sender when none are configured.
+func senderPolicy(cfg config.SMTPConfig) mailer.SenderPolicy {
+	domains := cfg.AllowedSenderDomains
+	if len(domains) == 0 {
+		if at := strings.LastIndexByte(cfg.From.Email, '@'); at >= 0 {
+			domains = []string{cfg.From.Email[at+1:]}
+		}
+	}
+	return mailer.SenderPolicy{AllowedDomains: domains}
+}
+
+// bouncePolicy converts the bounce configuration into the delivery service's policy.
+func bouncePolicy(cfg config.BounceConfig) service.BouncePolicy {
+	return service.BouncePolicy{
+		HardBounce:       cfg.HardBounce,
+		SoftBounceLimit:  cfg.SoftBounceLimit,
+		SoftBounceWindow: cfg.SoftBounceWindow,
+		Complaint:        cfg.Complaint,
+	}
+}
+
+// newKeyring creates the keyring that signs links in outgoing mail. Keys are required
+// when mail links to the public server: a random key would invalidate the links on
+// restart, and replicas would reject each other's links. Otherwise a random key is used.
+func newKeyring(cfg config.SecurityConfig, publicURL string) (*signing.Keyring, error) {
+	if len(cfg.SigningKeys) == 0 {
+		if publicURL != "" {
+			return nil, fmt.Errorf("security.signing_keys must be configured when server.public.url is set")
+		}
+		log.Printf("Warning: security.signing_keys is not configured; signed links stop working after a restart")
+		key, err := signing.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		return signing.NewKeyring(key)
+	}
+	keys := make([]signing.Key, 0, len(cfg.SigningKeys))
+	for _, k := range cfg.SigningKeys {
+		keys = append(keys, signing.Key{ID: k.ID, Secret: []byte(k.Secret)})
+	}
+	return signing.NewKeyring(keys...)
+}
+
+// retryPolicy returns the queue retry policy from the configuration.
+func (s *Server) retryPolicy() queue.RetryPolicy {
+	r := s.cfg.Queue.Retry
+	return queue.RetryPolicy{
+		MaxAttempts:     r.MaxAttempts,
+		InitialInterval: r.InitialInterval,
+		MaxInterval:     r.MaxInterval,
+		Multiplier:      r.Multiplier,
+		Jitter:          r.Jitter,
+	}
+}
+
+// releaseExpiredQueueItems returns reserved items whose lease expired to pending.
+func (s *Server) releaseExpiredQueueItems() {
+	reservedBefore := time.Now().Add(-s.cfg.Queue.Lease).Unix()
+	n, err := s.db.QueueRepository().ReleaseExpired(context.Background(), reservedBefore, s.cfg.Queue.Retry.MaxAttempts)
+	if err != nil {
+		log.Printf("reaper: ReleaseExpired failed: %v", err)
+	} else if n > 0 {
+		log.Printf("reaper: released %d expired queue items", n)
+	}
+}
+
+// expirePendingSubscriptions deletes pending memberships older than the confirmation expiry.
+func (s *Server) expirePendingSubscriptions() {
+	before := time.Now().Add(-s.cfg.Subscription.ConfirmationExpiry)
+	n, err := s.subscriptionService.ExpirePendingSubscriptions(context.Background(), before)
+	if err != nil {
+		log.Printf("subscriptions: ExpirePendingSubscriptions failed: %v", err)
+	} else if n > 0 {
+		log.Printf("subscriptions: deleted %d unconfirmed subscriptions", n)
+	}
+}
+
 // enqueueDueDeliveries finds scheduled deliveries whose scheduled_at <= now and enqueues them.
 func (s *Server) enqueueDueDeliveries() int {
 	ctx := context.Background()
@@ -278,10 +663,16 @@ func (s *Server) enqueueDueDeliveries() int {
 	return len(deliveries)
 }
 
-// Shutdown gracefully shuts down the servers.
+// Shutdown gracefully shuts down the servers and the queue workers.
+// Workers stop claiming new items and in-flight items are finished before it returns,
+// unless ctx expires first.
 func (s *Server) Shutdown(ctx context.Context) error {
 	var errs []error
 
+	if s.cancel != nil {
+		s.cancel()
+	}
+
 	if err := s.adminServer.Shutdown(ctx); err != nil {
 		errs = append(errs, fmt.Errorf("admin server shutdown failed: %w", err))
 	}
@@ -290,6 +681,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		errs = append(errs, fmt.Errorf("public server shutdown failed: %w", err))
 	}
 
+	if s.workerPool != nil {
+		if err := s.workerPool.Wait(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("worker shutdown failed: %w", err))
+		}
+	}
+
 	if len(errs) > 0 {
 		// Return first error; could be aggregated if desired.
 		return errs[0]
@@ -5,106 +5,312 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/headmail/headmail/pkg/queue"
 	"github.com/headmail/headmail/pkg/repository"
 )
 
-// Worker is a simple queue worker that processes generic queue items.
-// For now it understands items of Type == "delivery" where Payload is {"delivery_id":"<id>"}.
-// The mail send is a stub (logged).
-type Worker struct {
+// WorkerPoolConfig configures a WorkerPool.
+type WorkerPoolConfig struct {
+	// Workers is the number of items processed in parallel.
+	Workers int
+	// BatchSize is the maximum number of items claimed per poll. Defaults to Workers.
+	BatchSize int
+	// PollInterval is how long to wait when the queue is empty or claiming failed.
+	PollInterval time.Duration
+	// Concurrency optionally caps the number of items of a given type processed
+	// at the same time. Types without an entry are only limited by Workers.
+	Concurrency map[string]int
+}
+
+// WorkerPool claims queue items in batches and hands them to a fixed number of
+// worker goroutines which run the handler registered for the item type.
+type WorkerPool struct {
 	db       repository.DB
 	q        queue.Queue
 	handlers map[string]queue.Handler
+	retry    queue.RetryPolicy
+
+	workers      int
+	batchSize    int
+	pollInterval time.Duration
+	// per-type semaphores
+	slots map[string]chan struct{}
 
-	// claim limit per iteration
-	limit int
-	// sleep duration when queue empty or error
-	idleSleep time.Duration
+	items chan *queue.QueueItem
+	wg    sync.WaitGroup
 }
 
-// NewWorker constructs a Worker.
-func NewWorker(db repository.DB, q queue.Queue) *Worker {
-	return &Worker{
-		db:        db,
-		q:         q,
-		handlers:  make(map[string]queue.Handler),
-		limit:     1,
-		idleSleep: time.Second,
+// NewWorkerPool constructs a WorkerPool. Items whose handler fails are retried according to retry.
+func NewWorkerPool(db repository.DB, q queue.Queue, retry queue.RetryPolicy, cfg WorkerPoolConfig) *WorkerPool {
+	p := &WorkerPool{
+		db:           db,
+		q:            q,
+		handlers:     make(map[string]queue.Handler),
+		retry:        retry,
+		workers:      cfg.Workers,
+		batchSize:    cfg.BatchSize,
+		pollInterval: cfg.PollInterval,
+		slots:        make(map[string]chan struct{}),
+		items:        make(chan *queue.QueueItem),
+	}
+	if p.workers <= 0 {
+		p.workers = 1
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = p.workers
 	}
+	if p.pollInterval <= 0 {
+		p.pollInterval = time.Second
+	}
+	for typ, n := range cfg.Concurrency {
+		if n > 0 {
+			p.slots[typ] = make(chan struct{}, n)
+		}
+	}
+	return p
 }
 
-func (w *Worker) SetHandler(name string, handler queue.Handler) error {
-	w.handlers[name] = handler
+func (p *WorkerPool) SetHandler(name string, handler queue.Handler) error {
+	p.handlers[name] = handler
 	return nil
 }
 
-// Start runs the worker loop until ctx is cancelled.
-func (w *Worker) Start(ctx context.Context, workerID string) {
-	log.Printf("worker %s started", workerID)
+// Start launches the dispatcher and the workers and returns immediately.
+// Cancelling ctx stops claiming new items; items already handed to a worker
+// are finished with a context that is not cancelled. Use Wait to block until
+// they are done.
+func (p *WorkerPool) Start(ctx context.Context, workerID string) {
+	log.Printf("worker pool %s started with %d workers", workerID, p.workers)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.dispatch(ctx, workerID)
+	}()
+
+	runCtx := context.WithoutCancel(ctx)
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for it := range p.items {
+				if err := p.processItem(runCtx, workerID, it); err != nil {
+					log.Printf("worker %s: failed processing item %s: %v", workerID, it.ID, err)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until the pool has stopped and all in-flight items are finished,
+// or until ctx is done.
+func (p *WorkerPool) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for in-flight queue items: %w", ctx.Err())
+	}
+}
+
+// dispatch claims items and feeds them to the workers until ctx is cancelled.
+func (p *WorkerPool) dispatch(ctx context.Context, workerID string) {
+	defer close(p.items)
+
 	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("worker %s stopping: context done", workerID)
+		if ctx.Err() != nil {
+			log.Printf("worker pool %s stopping: context done", workerID)
 			return
-		default:
 		}
 
-		items, err := w.q.Claim(ctx, workerID, w.limit)
-		if err != nil {
+		items, err := p.claim(ctx, workerID)
+		if err != nil && ctx.Err() == nil {
 			log.Printf("worker %s: claim error: %v", workerID, err)
-			time.Sleep(w.idleSleep)
 		}
 		if len(items) == 0 {
-			time.Sleep(w.idleSleep)
+			if !p.sleep(ctx) {
+				return
+			}
+			continue
 		}
 
-		for _, it := range items {
-			// process each item, do not block the whole worker loop on one failing item
-			if err := w.processItem(ctx, workerID, it); err != nil {
-				log.Printf("worker %s: failed processing item %s: %v", workerID, it.ID, err)
+		for i, it := range items {
+			select {
+			case p.items <- it:
+			case <-ctx.Done():
+				p.release(workerID, items[i:])
+				log.Printf("worker pool %s stopping: context done", workerID)
+				return
 			}
 		}
 	}
 }
 
-func (w *Worker) processItem(ctx context.Context, workerID string, it *queue.QueueItem) error {
-	handler, ok := w.handlers[it.Type]
+// claim reserves the next batch of items. Items of types with a concurrency limit are
+// only claimed for free slots of their type. The slots are taken before claiming and
+// given back by processItem, so claimed items never wait for a slot while their lease
+// runs. Items claimed before an error are returned with it.
+func (p *WorkerPool) claim(ctx context.Context, workerID string) ([]*queue.QueueItem, error) {
+	var items []*queue.QueueItem
+	limited := make([]string, 0, len(p.slots))
+	for typ, slots := range p.slots {
+		limited = append(limited, typ)
+		n := acquire(slots, p.batchSize-len(items))
+		if n == 0 {
+			continue
+		}
+		claimed, err := p.q.Claim(ctx, workerID, n, queue.ClaimFilter{Types: []string{typ}})
+		for i := len(claimed); i < n; i++ {
+			<-slots
+		}
+		items = append(items, claimed...)
+		if err != nil {
+			return items, err
+		}
+	}
+	if n := p.batchSize - len(items); n > 0 {
+		claimed, err := p.q.Claim(ctx, workerID, n, queue.ClaimFilter{ExcludeTypes: limited})
+		items = append(items, claimed...)
+		if err != nil {
+			return items, err
+		}
+	}
+	return items, nil
+}
+
+// acquire takes up to n free slots without blocking and returns how many it took.
+func acquire(slots chan struct{}, n int) int {
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return i
+		}
+	}
+	return n
+}
+
+// sleep waits for the poll interval. It returns false if ctx was cancelled meanwhile.
+func (p *WorkerPool) sleep(ctx context.Context) bool {
+	timer := time.NewTimer(p.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release returns claimed items that were never handed to a worker to the queue. They
+// get back the attempt of their claim, as they were not tried.
+func (p *WorkerPool) release(workerID string, items []*queue.QueueItem) {
+	now := time.Now().Unix()
+	for _, it := range items {
+		if slots, ok := p.slots[it.Type]; ok {
+			<-slots
+		}
+		if err := p.q.Defer(context.Background(), it.ID, workerID, now); err != nil {
+			log.Printf("worker: failed to release item %s: %v", it.ID, err)
+		}
+	}
+}
+
+func (p *WorkerPool) processItem(ctx context.Context, workerID string, it *queue.QueueItem) error {
+	// the slot of the item's type was taken when it was claimed
+	if slots, ok := p.slots[it.Type]; ok {
+		defer func() { <-slots }()
+	}
+
+	handler, ok := p.handlers[it.Type]
 	if !ok {
-		// No handler registered: treat as permanent failure (ack to remove).
-		return fmt.Errorf("no handler for '%s'", it.Type)
+		// No handler registered: treat as permanent failure.
+		err := fmt.Errorf("no handler for '%s'", it.Type)
+		_ = p.q.Fail(ctx, it.ID, workerID, err.Error(), nil)
+		return err
 	}
 
 	// Start DB transaction so handler can update domain and we can ack atomically.
-	txCtx, err := w.db.Begin(ctx)
+	txCtx, err := p.db.Begin(ctx)
 	if err != nil {
 		// If we cannot start a transaction, mark item for retry.
-		_ = w.q.Fail(ctx, it.ID, err.Error())
+		p.fail(ctx, workerID, it, err)
 		return err
 	}
 
 	// Execute handler with transactional context.
 	if err := handler(txCtx, workerID, it); err != nil {
-		_ = w.db.Rollback(txCtx)
-		_ = w.q.Fail(ctx, it.ID, err.Error())
+		var deferred *queue.DeferError
+		if errors.As(err, &deferred) {
+			_ = p.db.Rollback(txCtx)
+			return p.deferItem(ctx, workerID, it, deferred.Delay)
+		}
+		var commit *queue.CommitError
+		if !errors.As(err, &commit) {
+			_ = p.db.Rollback(txCtx)
+			p.fail(ctx, workerID, it, err)
+			return err
+		}
+		// The handler recorded the failure; store it together with the retry of the item.
+		if ferr := p.fail(txCtx, workerID, it, err); ferr != nil {
+			_ = p.db.Rollback(txCtx)
+			return ferr
+		}
+		if cerr := p.db.Commit(txCtx); cerr != nil {
+			p.fail(ctx, workerID, it, cerr)
+			return cerr
+		}
 		return err
 	}
 
 	// Ack the queue item within the same transaction and commit.
-	if err := w.q.Ack(txCtx, it.ID); err != nil {
-		_ = w.db.Rollback(txCtx)
-		_ = w.q.Fail(ctx, it.ID, err.Error())
+	if err := p.q.Ack(txCtx, it.ID, workerID); err != nil {
+		_ = p.db.Rollback(txCtx)
+		p.fail(ctx, workerID, it, err)
+		return err
+	}
+
+	if err := p.db.Commit(txCtx); err != nil {
+		p.fail(ctx, workerID, it, err)
 		return err
 	}
 
-	if err := w.db.Commit(txCtx); err != nil {
-		_ = w.q.Fail(ctx, it.ID, err.Error())
+	return nil
+}
+
+// deferItem returns the item to the queue after delay without using up an attempt.
+func (p *WorkerPool) deferItem(ctx context.Context, workerID string, it *queue.QueueItem, delay time.Duration) error {
+	// available_at has a resolution of seconds; round up so the item is not claimed early
+	availableAt := time.Now().Add(delay + time.Second - 1).Unix()
+	if err := p.q.Defer(ctx, it.ID, workerID, availableAt); err != nil {
+		log.Printf("worker: failed to defer item %s: %v", it.ID, err)
 		return err
 	}
+	return nil
+}
 
+// fail reschedules the item with backoff, or dead-letters it once its attempts are used up.
+func (p *WorkerPool) fail(ctx context.Context, workerID string, it *queue.QueueItem, cause error) error {
+	retryAt := p.retry.NextRetry(it.Attempts, time.Now())
+	if retryAt == nil {
+		log.Printf("worker: item %s dead-lettered after %d attempts", it.ID, it.Attempts)
+	}
+	if err := p.q.Fail(ctx, it.ID, workerID, cause.Error(), retryAt); err != nil {
+		log.Printf("worker: failed to record failure of item %s: %v", it.ID, err)
+		return err
+	}
 	return nil
 }
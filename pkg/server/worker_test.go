@@ -0,0 +1,266 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/internal/db/sqlite"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) repository.DB {
+	db, err := sqlite.New(config.DatabaseConfig{
+		URL: "file:" + uuid.NewString() + "?mode=memory&cache=shared",
+	})
+	require.NoError(t, err)
+	_, err = db.MigrateUp(context.Background(), 0)
+	require.NoError(t, err)
+	sqlDB, err := db.DB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func enqueueItems(t *testing.T, q queue.Queue, typ string, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, q.Enqueue(context.Background(), &queue.QueueItem{
+			ID:      uuid.NewString(),
+			Type:    typ,
+			Payload: json.RawMessage(`{}`),
+		}))
+	}
+}
+
+func TestWorkerPool_TypeConcurrency(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "delivery", 12)
+
+	var running, maxRunning, processed int32
+	pool := NewWorkerPool(db, q, queue.DefaultRetryPolicy, WorkerPoolConfig{
+		Workers:      4,
+		BatchSize:    5,
+		PollInterval: 10 * time.Millisecond,
+		Concurrency:  map[string]int{"delivery": 2},
+	})
+	_ = pool.SetHandler("delivery", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 12 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, pool.Wait(context.Background()))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+	_, pending, err := q.List(context.Background(), queue.Filter{Status: []queue.Status{queue.StatusPending, queue.StatusReserved}}, 0, 1)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
+
+func TestWorkerPool_SaturatedTypeHoldsNoLeases(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "slow", 3)
+	enqueueItems(t, q, "fast", 2)
+
+	unblock := make(chan struct{})
+	var fast int32
+	pool := NewWorkerPool(db, q, queue.DefaultRetryPolicy, WorkerPoolConfig{
+		Workers:      3,
+		PollInterval: 10 * time.Millisecond,
+		Concurrency:  map[string]int{"slow": 1},
+	})
+	_ = pool.SetHandler("slow", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		<-unblock
+		return nil
+	})
+	_ = pool.SetHandler("fast", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		atomic.AddInt32(&fast, 1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+
+	// other types are not starved while the slow type is saturated
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&fast) == 2 }, 5*time.Second, 10*time.Millisecond)
+	// only the item being processed is leased; the others wait unclaimed
+	_, reserved, err := q.List(context.Background(), queue.Filter{Type: "slow", Status: []queue.Status{queue.StatusReserved}}, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reserved)
+
+	close(unblock)
+	require.Eventually(t, func() bool {
+		_, done, err := q.List(context.Background(), queue.Filter{Type: "slow", Status: []queue.Status{queue.StatusDone}}, 0, 1)
+		return err == nil && done == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, pool.Wait(context.Background()))
+}
+
+func TestWorkerPool_WaitsForInFlight(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "delivery", 1)
+
+	started := make(chan struct{})
+	var once sync.Once
+	var finished atomic.Bool
+	pool := NewWorkerPool(db, q, queue.DefaultRetryPolicy, WorkerPoolConfig{
+		Workers:      2,
+		PollInterval: 10 * time.Millisecond,
+	})
+	_ = pool.SetHandler("delivery", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		once.Do(func() { close(started) })
+		time.Sleep(100 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		finished.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+	<-started
+	cancel()
+
+	require.NoError(t, pool.Wait(context.Background()))
+	assert.True(t, finished.Load(), "in-flight item finished with an uncancelled context")
+
+	items, _, err := q.List(context.Background(), queue.Filter{}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, queue.StatusDone, items[0].Status)
+}
+
+func TestWorkerPool_ReleasesUnstartedItems(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "delivery", 3)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var once sync.Once
+	pool := NewWorkerPool(db, q, queue.DefaultRetryPolicy, WorkerPoolConfig{
+		Workers:      1,
+		BatchSize:    3,
+		PollInterval: 10 * time.Millisecond,
+	})
+	_ = pool.SetHandler("delivery", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		once.Do(func() { close(started) })
+		<-unblock
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+	<-started
+	// the other two items are claimed but wait for the busy worker
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	require.NoError(t, pool.Wait(context.Background()))
+
+	items, _, err := q.List(context.Background(), queue.Filter{Status: []queue.Status{queue.StatusPending}}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, it := range items {
+		assert.Equal(t, 0, it.Attempts, "a shutdown does not use up attempts")
+		assert.Nil(t, it.ReservedBy)
+	}
+}
+
+func TestWorkerPool_CommitError(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "recorded", 1)
+	enqueueItems(t, q, "plain", 1)
+
+	var processed int32
+	pool := NewWorkerPool(db, q, queue.RetryPolicy{MaxAttempts: 2, InitialInterval: time.Hour}, WorkerPoolConfig{PollInterval: 10 * time.Millisecond})
+	handler := func(wrap func(error) error) queue.Handler {
+		return func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+			defer atomic.AddInt32(&processed, 1)
+			// a side effect of the handler's transaction
+			require.NoError(t, q.Enqueue(ctx, &queue.QueueItem{ID: uuid.NewString(), Type: "marker:" + it.Type, Payload: json.RawMessage(`{}`)}))
+			return wrap(assert.AnError)
+		}
+	}
+	_ = pool.SetHandler("recorded", handler(func(err error) error { return &queue.CommitError{Err: err} }))
+	_ = pool.SetHandler("plain", handler(func(err error) error { return err }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 2 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, pool.Wait(context.Background()))
+
+	_, markers, err := q.List(context.Background(), queue.Filter{Type: "marker:recorded"}, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, markers, "the transaction of a CommitError is committed")
+	_, markers, err = q.List(context.Background(), queue.Filter{Type: "marker:plain"}, 0, 1)
+	require.NoError(t, err)
+	assert.Zero(t, markers, "the transaction of other errors is rolled back")
+
+	for _, typ := range []string{"recorded", "plain"} {
+		items, _, err := q.List(context.Background(), queue.Filter{Type: typ}, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, queue.StatusPending, items[0].Status, typ)
+		assert.Greater(t, items[0].AvailableAt, time.Now().Unix(), "%s is retried with backoff", typ)
+		require.NotNil(t, items[0].LastError)
+		assert.Equal(t, assert.AnError.Error(), *items[0].LastError)
+	}
+}
+
+func TestWorkerPool_DeferError(t *testing.T) {
+	db := newTestDB(t)
+	q := db.QueueRepository()
+	enqueueItems(t, q, "limited", 1)
+
+	var processed int32
+	pool := NewWorkerPool(db, q, queue.RetryPolicy{MaxAttempts: 1, InitialInterval: time.Hour}, WorkerPoolConfig{PollInterval: 10 * time.Millisecond})
+	_ = pool.SetHandler("limited", func(ctx context.Context, workerID string, it *queue.QueueItem) error {
+		defer atomic.AddInt32(&processed, 1)
+		return &queue.DeferError{Delay: time.Minute, Err: assert.AnError}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx, "test")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&processed) == 1 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, pool.Wait(context.Background()))
+
+	// the item is rescheduled instead of dead-lettered, though MaxAttempts is reached
+	items, _, err := q.List(context.Background(), queue.Filter{Type: "limited"}, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, queue.StatusPending, items[0].Status)
+	assert.Equal(t, 0, items[0].Attempts)
+	assert.GreaterOrEqual(t, items[0].AvailableAt, time.Now().Add(59*time.Second).Unix())
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+const (
+	// apiKeyBytes is the number of random bytes of a key.
+	apiKeyBytes = 32
+	// apiKeyPrefixLen is the number of characters of a key kept to recognize it.
+	apiKeyPrefixLen = len(domain.APIKeyPrefix) + 8
+	// apiKeyLastUsedResolution limits how often the last use of a key is written.
+	apiKeyLastUsedResolution = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned for keys with a missing name or unknown scopes.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyRevoked is returned when rotating a revoked key.
+	ErrAPIKeyRevoked = errors.New("api key is revoked")
+	// ErrAPIKeyScopeNotHeld is returned when the caller would create, rotate or revoke a
+	// key with a scope the caller does not hold itself.
+	ErrAPIKeyScopeNotHeld = errors.New("scope not held by the caller")
+)
+
+// APIKeyServiceProvider defines the interface for managing stored admin API keys.
+type APIKeyServiceProvider interface {
+	// CreateAPIKey stores a new key and returns it; the key is not retrievable later.
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) (string, error)
+	// RotateAPIKey replaces the key, keeping its name and scopes, and returns the new key.
+	RotateAPIKey(ctx context.Context, id string) (*domain.APIKey, string, error)
+	// RevokeAPIKey rejects the key from now on.
+	RevokeAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context, pagination repository.Pagination) ([]*domain.APIKey, int, error)
+	auth.APIKeyVerifier
+}
+
+// APIKeyService provides business logic for stored admin API keys.
+type APIKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new APIKeyService.
+func NewAPIKeyService(db repository.DB) *APIKeyService {
+	return &APIKeyService{
+		repo: db.APIKeyRepository(),
+	}
+}
+
+// CreateAPIKey validates the name and scopes, generates the key and stores its hash.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, key *domain.APIKey) (string, error) {
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidAPIKey)
+	}
+	scopes, err := normalizeScopes(key.Scopes)
+	if err != nil {
+		return "", err
+	}
+	key.Scopes = scopes
+	if err := checkScopesHeld(ctx, scopes); err != nil {
+		return "", err
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+	key.ID = uuid.NewString()
+	key.Prefix = secret[:apiKeyPrefixLen]
+	key.Hash = hashAPIKey(secret)
+	key.LastUsedAt = nil
+	key.RevokedAt = nil
+	key.CreatedAt = now
+	key.UpdatedAt = now
+	if err := s.repo.Create(ctx, key); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RotateAPIKey replaces the key of an unrevoked entry. The previous key is rejected
+// immediately.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id string) (*domain.APIKey, string, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkScopesHeld(ctx, key.Scopes); err != nil {
+		return nil, "", err
+	}
+	if key.RevokedAt != nil {
+		return nil, "", ErrAPIKeyRevoked
+	}
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key.Prefix = secret[:apiKeyPrefixLen]
+	key.Hash = hashAPIKey(secret)
+	key.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// RevokeAPIKey marks the key revoked. Revoked keys are kept to show their last use;
+// revoking a revoked key changes nothing.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScopesHeld(ctx, key.Scopes); err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	now := time.Now().Unix()
+	key.RevokedAt = &now
+	key.UpdatedAt = now
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAPIKey retrieves a key by its ID.
+func (s *APIKeyService) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListAPIKeys lists keys, newest first.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, pagination repository.Pagination) ([]*domain.APIKey, int, error) {
+	return s.repo.List(ctx, pagination)
+}
+
+// VerifyAPIKey implements auth.APIKeyVerifier and records the use of the key.
+func (s *APIKeyService) VerifyAPIKey(ctx context.Context, secret string) (*domain.APIKey, error) {
+	key, err := s.repo.GetByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("%w: unknown api key", auth.ErrInvalidCredentials)
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key '%s' is revoked", auth.ErrInvalidCredentials, key.Name)
+	}
+
+	now := time.Now().Unix()
+	if key.LastUsedAt == nil || now-*key.LastUsedAt >= int64(apiKeyLastUsedResolution/time.Second) {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			log.Printf("api key %s: record last use failed: %v", key.ID, err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+// checkScopesHeld returns ErrAPIKeyScopeNotHeld unless the principal of ctx holds every
+// scope. A key can only manage keys that grant no more than itself, so a narrow key
+// cannot take over a broader one by rotating it. Calls without a principal are allowed.
+func checkScopesHeld(ctx context.Context, scopes []string) error {
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil {
+		return nil
+	}
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return fmt.Errorf("%w: %s", ErrAPIKeyScopeNotHeld, scope)
+		}
+	}
+	return nil
+}
+
+// normalizeScopes checks that scopes are known and returns them sorted without
+// duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(auth.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope '%s'", ErrInvalidAPIKey, scope)
+		}
+		normalized = append(normalized, scope)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+// generateAPIKey returns a new random key.
+func generateAPIKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return domain.APIKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashAPIKey returns the hex encoded SHA-256 hash of key as stored.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/auth"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	svc := NewAPIKeyService(db)
+
+	key := &domain.APIKey{Name: " mailer ", Scopes: []string{auth.ScopeTxSend, auth.ScopeDeliveriesRead, auth.ScopeTxSend}}
+	secret, err := svc.CreateAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, domain.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(secret, key.Prefix))
+	assert.NotContains(t, key.Hash, secret[len(domain.APIKeyPrefix):], "only the hash is stored")
+	assert.Equal(t, "mailer", key.Name)
+	assert.Equal(t, []string{auth.ScopeDeliveriesRead, auth.ScopeTxSend}, key.Scopes)
+
+	for _, invalid := range []*domain.APIKey{
+		{Scopes: []string{auth.ScopeTxSend}},
+		{Name: "none"},
+		{Name: "unknown", Scopes: []string{"everything"}},
+	} {
+		_, err := svc.CreateAPIKey(ctx, invalid)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	}
+
+	verified, err := svc.VerifyAPIKey(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, verified.ID)
+	stored, err := svc.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt, "the use of the key is recorded")
+	_, err = svc.VerifyAPIKey(ctx, domain.APIKeyPrefix+"unknown")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	// rotation replaces the key and keeps the entry
+	rotated, newSecret, err := svc.RotateAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, newSecret)
+	assert.Equal(t, key.Scopes, rotated.Scopes)
+	_, err = svc.VerifyAPIKey(ctx, secret)
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = svc.VerifyAPIKey(ctx, newSecret)
+	require.NoError(t, err)
+
+	revoked, err := svc.RevokeAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = svc.VerifyAPIKey(ctx, newSecret)
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, _, err = svc.RotateAPIKey(ctx, key.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyRevoked)
+
+	keys, total, err := svc.ListAPIKeys(ctx, repository.Pagination{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, keys, 1)
+}
+
+func TestAPIKeyService_ScopesHeld(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	svc := NewAPIKeyService(db)
+
+	broad := &domain.APIKey{Name: "admin", Scopes: []string{auth.ScopeAPIKeysWrite, auth.ScopeTxSend}}
+	_, err := svc.CreateAPIKey(ctx, broad)
+	require.NoError(t, err)
+
+	// a key that may manage keys but not send cannot create, rotate or revoke a key that sends
+	narrow := auth.WithPrincipal(ctx, &auth.Principal{Method: auth.MethodAPIKey, Subject: "keys", Scopes: []string{auth.ScopeAPIKeysWrite}})
+	_, err = svc.CreateAPIKey(narrow, &domain.APIKey{Name: "sender", Scopes: []string{auth.ScopeTxSend}})
+	assert.ErrorIs(t, err, ErrAPIKeyScopeNotHeld)
+	_, _, err = svc.RotateAPIKey(narrow, broad.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyScopeNotHeld)
+	_, err = svc.RevokeAPIKey(narrow, broad.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyScopeNotHeld)
+	stored, err := svc.GetAPIKey(ctx, broad.ID)
+	require.NoError(t, err)
+	assert.Equal(t, broad.Hash, stored.Hash)
+	assert.Nil(t, stored.RevokedAt)
+
+	// keys within the caller's scopes can be managed
+	own := &domain.APIKey{Name: "keys", Scopes: []string{auth.ScopeAPIKeysWrite}}
+	_, err = svc.CreateAPIKey(narrow, own)
+	require.NoError(t, err)
+	_, _, err = svc.RotateAPIKey(narrow, own.ID)
+	require.NoError(t, err)
+	_, err = svc.RevokeAPIKey(narrow, own.ID)
+	require.NoError(t, err)
+
+	// principals without scope restrictions manage every key
+	full := auth.WithPrincipal(ctx, &auth.Principal{Method: auth.MethodJWT, Subject: "ops"})
+	_, _, err = svc.RotateAPIKey(full, broad.ID)
+	require.NoError(t, err)
+}
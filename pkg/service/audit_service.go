@@ -0,0 +1,48 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// AuditServiceProvider defines the interface for the audit log of admin mutations.
+type AuditServiceProvider interface {
+	// RecordAudit stores an entry, setting its ID and creation time.
+	RecordAudit(ctx context.Context, entry *domain.AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter repository.AuditFilter, pagination repository.Pagination) ([]*domain.AuditEntry, int, error)
+}
+
+// AuditService provides business logic for the audit log.
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(db repository.DB) *AuditService {
+	return &AuditService{
+		repo: db.AuditRepository(),
+	}
+}
+
+// RecordAudit stores an entry.
+func (s *AuditService) RecordAudit(ctx context.Context, entry *domain.AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.NewString()
+	}
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+	return s.repo.Create(ctx, entry)
+}
+
+// ListAuditEntries lists entries matching the filter, newest first.
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter repository.AuditFilter, pagination repository.Pagination) ([]*domain.AuditEntry, int, error) {
+	return s.repo.List(ctx, filter, pagination)
+}
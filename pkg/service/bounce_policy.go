@@ -0,0 +1,99 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// BouncePolicy decides when bounces and complaints change the status of a subscriber
+// on all of their lists.
+type BouncePolicy struct {
+	// HardBounce marks the subscriber bounced at the first permanent bounce.
+	HardBounce bool
+	// SoftBounceLimit marks the subscriber bounced once this many deliveries to the
+	// address bounced within SoftBounceWindow. Zero disables the soft bounce policy.
+	SoftBounceLimit  int
+	SoftBounceWindow time.Duration
+	// Complaint marks the subscriber complained at the first spam complaint.
+	Complaint bool
+}
+
+// Policy names recorded in subscriber status change events.
+const (
+	bouncePolicyHardBounce = "hard_bounce"
+	bouncePolicySoftBounce = "soft_bounce"
+	bouncePolicyComplaint  = "complaint"
+)
+
+// applyBouncePolicy changes the list status of the subscriber owning the recipient of
+// deliveryID when the bounce or complaint event meets the configured policy. Every
+// change is recorded as a subscriber status change event on the delivery.
+func (s *DeliveryService) applyBouncePolicy(ctx context.Context, deliveryID string, eventType domain.EventType, permanent bool) error {
+	d, err := s.repo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	data := map[string]interface{}{}
+	var status domain.SubscriberListStatus
+	switch {
+	case eventType == domain.EventTypeComplained:
+		if !s.bounces.Complaint {
+			return nil
+		}
+		status = domain.SubscriberListStatusComplained
+		data["policy"] = bouncePolicyComplaint
+	case permanent && s.bounces.HardBounce:
+		status = domain.SubscriberListStatusBounced
+		data["policy"] = bouncePolicyHardBounce
+	case s.bounces.SoftBounceLimit > 0:
+		since := now.Add(-s.bounces.SoftBounceWindow).Unix()
+		n, err := s.eventRepo.CountDeliveriesByRecipient(ctx, d.Email, domain.EventTypeBounced, since)
+		if err != nil {
+			return err
+		}
+		if n < s.bounces.SoftBounceLimit {
+			return nil
+		}
+		status = domain.SubscriberListStatusBounced
+		data["policy"] = bouncePolicySoftBounce
+		data["bounces"] = n
+		data["window"] = s.bounces.SoftBounceWindow.String()
+	default:
+		return nil
+	}
+
+	subscriber, err := s.db.SubscriberRepository().GetByEmail(ctx, d.Email)
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			// transactional mail to addresses that are not subscribers
+			return nil
+		}
+		return err
+	}
+
+	return repository.Transactional0(s.db, ctx, func(txCtx context.Context) error {
+		n, err := s.db.SubscriberRepository().SetListStatus(txCtx, subscriber.ID, "", status, now.Unix())
+		if err != nil || n == 0 {
+			return err
+		}
+		data["subscriber_id"] = subscriber.ID
+		data["status"] = status
+		data["lists"] = n
+		return s.eventRepo.Create(txCtx, &domain.DeliveryEvent{
+			DeliveryID: deliveryID,
+			EventType:  domain.EventTypeSubscriberStatusChanged,
+			EventData:  data,
+			CreatedAt:  now.Unix(),
+		})
+	})
+}
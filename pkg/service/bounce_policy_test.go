@@ -0,0 +1,134 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/headmail/headmail/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryService_BouncePolicy(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	svc := NewDeliveryService(db, template.NewService(), db.QueueRepository(), nil, "", queue.DefaultRetryPolicy, mailer.SenderPolicy{}, newTestKeyring(t), BouncePolicy{
+		HardBounce:       true,
+		SoftBounceLimit:  2,
+		SoftBounceWindow: 24 * time.Hour,
+		Complaint:        true,
+	})
+
+	for _, id := range []string{"news", "offers"} {
+		require.NoError(t, db.ListRepository().Create(ctx, &domain.List{ID: id, Name: id}))
+	}
+	for _, email := range []string{"hard", "soft", "spam"} {
+		id := "sub-" + email
+		require.NoError(t, db.SubscriberRepository().Create(ctx, &domain.Subscriber{ID: id, Email: email + "@example.com", Status: domain.SubscriberStatusEnabled}))
+		require.NoError(t, db.ListRepository().AddSubscribers(ctx, "news", []string{id}))
+		require.NoError(t, db.ListRepository().AddSubscribers(ctx, "offers", []string{id}))
+	}
+
+	campaignID := "camp-1"
+	require.NoError(t, db.CampaignRepository().Create(ctx, &domain.Campaign{ID: campaignID, Name: campaignID, Status: domain.CampaignStatusSent, Tags: []string{}}))
+	deliver := func(id string, email string) {
+		require.NoError(t, db.DeliveryRepository().Create(ctx, &domain.Delivery{
+			ID:         id,
+			CampaignID: &campaignID,
+			Type:       domain.DeliveryTypeCampaign,
+			Status:     domain.DeliveryStatusSent,
+			Email:      email,
+			CreatedAt:  time.Now().Unix(),
+		}))
+	}
+	listStatuses := func(subscriberID string) map[string]domain.SubscriberListStatus {
+		sub, err := db.SubscriberRepository().GetByID(ctx, subscriberID)
+		require.NoError(t, err)
+		statuses := map[string]domain.SubscriberListStatus{}
+		for _, l := range sub.Lists {
+			statuses[l.ListID] = l.Status
+		}
+		return statuses
+	}
+	bounced := map[string]domain.SubscriberListStatus{"news": domain.SubscriberListStatusBounced, "offers": domain.SubscriberListStatusBounced}
+	confirmed := map[string]domain.SubscriberListStatus{"news": domain.SubscriberListStatusConfirmed, "offers": domain.SubscriberListStatusConfirmed}
+
+	// a transient bounce is only recorded
+	deliver("del-hard", "hard@example.com")
+	require.NoError(t, svc.HandleBouncedMail(ctx, &receiver.Event{DeliveryID: "del-hard", BounceType: receiver.BounceTypeTransient, Status: "4.4.1"}))
+	got, err := svc.GetDelivery(ctx, "del-hard")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusSent, got.Status)
+	assert.Equal(t, confirmed, listStatuses("sub-hard"))
+
+	// a hard bounce applies immediately
+	require.NoError(t, svc.HandleBouncedMail(ctx, &receiver.Event{DeliveryID: "del-hard", Permanent: true}))
+	assert.Equal(t, bounced, listStatuses("sub-hard"))
+
+	// soft bounces apply once the limit is reached within the window
+	for i := 1; i <= 2; i++ {
+		id := fmt.Sprintf("del-soft-%d", i)
+		deliver(id, "soft@example.com")
+		// a second bounce report for the same delivery is not counted twice
+		for j := 0; j < i; j++ {
+			require.NoError(t, svc.HandleBouncedMail(ctx, &receiver.Event{DeliveryID: id, Reason: "mailbox full (4.2.2)"}))
+		}
+		if i == 1 {
+			assert.Equal(t, confirmed, listStatuses("sub-soft"))
+		}
+	}
+	assert.Equal(t, bounced, listStatuses("sub-soft"))
+
+	// a hard bounce after a complaint does not replace it
+	require.NoError(t, db.SubscriberRepository().Create(ctx, &domain.Subscriber{ID: "sub-both", Email: "both@example.com", Status: domain.SubscriberStatusEnabled}))
+	require.NoError(t, db.ListRepository().AddSubscribers(ctx, "news", []string{"sub-both"}))
+	require.NoError(t, db.ListRepository().AddSubscribers(ctx, "offers", []string{"sub-both"}))
+	_, err = db.SubscriberRepository().SetListStatus(ctx, "sub-both", "news", domain.SubscriberListStatusComplained, time.Now().Unix())
+	require.NoError(t, err)
+	deliver("del-both", "both@example.com")
+	require.NoError(t, svc.HandleBouncedMail(ctx, &receiver.Event{DeliveryID: "del-both", Permanent: true}))
+	assert.Equal(t, map[string]domain.SubscriberListStatus{"news": domain.SubscriberListStatusComplained, "offers": domain.SubscriberListStatusBounced}, listStatuses("sub-both"))
+
+	// a complaint suppresses the address and marks the subscriber complained
+	deliver("del-spam", "spam@example.com")
+	for i := 0; i < 2; i++ {
+		require.NoError(t, svc.HandleComplaint(ctx, &receiver.Event{DeliveryID: "del-spam", Type: receiver.EventTypeComplaint, FeedbackType: "abuse"}))
+	}
+	assert.Equal(t, map[string]domain.SubscriberListStatus{"news": domain.SubscriberListStatusComplained, "offers": domain.SubscriberListStatusComplained}, listStatuses("sub-spam"))
+	suppression, err := matchSuppression(ctx, db.SuppressionRepository(), "spam@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, suppression)
+	assert.Equal(t, domain.SuppressionReasonComplaint, suppression.Reason)
+	spam, err := svc.GetDelivery(ctx, "del-spam")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusSent, spam.Status)
+	assert.Equal(t, 2, spam.ComplaintCount)
+	campaign, err := db.CampaignRepository().GetByID(ctx, campaignID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, campaign.ComplaintCount, "only the first complaint about a delivery is counted")
+
+	// every change leaves an event explaining it
+	events, err := db.EventRepository().ListByCampaignAndRange(ctx, []string{campaignID}, 0, time.Now().Unix()+1)
+	require.NoError(t, err)
+	policies := map[string]string{}
+	for _, ev := range events {
+		if ev.EventType == domain.EventTypeSubscriberStatusChanged {
+			policies[ev.DeliveryID] = fmt.Sprint(ev.EventData["policy"])
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"del-hard":   bouncePolicyHardBounce,
+		"del-both":   bouncePolicyHardBounce,
+		"del-soft-2": bouncePolicySoftBounce,
+		"del-spam":   bouncePolicyComplaint,
+	}, policies)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/headmail/headmail/pkg/api/admin/dto"
 	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
 	"github.com/headmail/headmail/pkg/repository"
 )
 
@@ -33,6 +34,9 @@ type CampaignServiceProvider interface {
 	// GetCampaignStats returns time-bucketed opens and clicks for given campaign IDs.
 	// granularity: "hour" or "day"
 	GetCampaignStats(ctx context.Context, campaignIDs []string, from time.Time, to time.Time, granularity string) (*dto.CampaignStatsResponse, error)
+	// ListCampaignLinks returns the tracked links of a campaign with their click counts,
+	// most clicked first.
+	ListCampaignLinks(ctx context.Context, campaignID string, pagination repository.Pagination) ([]*domain.Link, int, error)
 }
 
 // CampaignService provides business logic for campaign management.
@@ -43,12 +47,14 @@ type CampaignService struct {
 	subscriberRepo  repository.SubscriberRepository
 	templateRepo    repository.TemplateRepository
 	deliveryService DeliveryServiceProvider
+	senders         mailer.SenderPolicy
 }
 
 // NewCampaignService creates a new CampaignService.
 func NewCampaignService(
 	db repository.DB,
 	deliveryService DeliveryServiceProvider,
+	senders mailer.SenderPolicy,
 ) *CampaignService {
 	return &CampaignService{
 		db:              db,
@@ -57,11 +63,16 @@ func NewCampaignService(
 		subscriberRepo:  db.SubscriberRepository(),
 		deliveryService: deliveryService,
 		templateRepo:    db.TemplateRepository(),
+		senders:         senders,
 	}
 }
 
 // CreateCampaign creates a new campaign or upserts when requested.
 func (s *CampaignService) CreateCampaign(ctx context.Context, campaign *domain.Campaign, upsert bool) error {
+	if err := s.validateCampaignInput(ctx, campaign); err != nil {
+		return err
+	}
+
 	// If no ID provided, generate one and create.
 	if campaign.ID == "" {
 		campaign.ID = uuid.NewString()
@@ -71,10 +82,6 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, campaign *domain.C
 		return s.repo.Create(ctx, campaign)
 	}
 
-	if err := s.validateCampaignInput(ctx, campaign); err != nil {
-		return err
-	}
-
 	// ID provided: check existence
 	existing, err := s.repo.GetByID(ctx, campaign.ID)
 	if err != nil {
@@ -107,6 +114,14 @@ func (s *CampaignService) GetCampaign(ctx context.Context, id string) (*domain.C
 	return s.repo.GetByID(ctx, id)
 }
 
+// ListCampaignLinks returns the tracked links of a campaign.
+func (s *CampaignService) ListCampaignLinks(ctx context.Context, campaignID string, pagination repository.Pagination) ([]*domain.Link, int, error) {
+	if _, err := s.repo.GetByID(ctx, campaignID); err != nil {
+		return nil, 0, err
+	}
+	return s.db.LinkRepository().ListByCampaign(ctx, campaignID, pagination)
+}
+
 // UpdateCampaign updates an existing campaign.
 func (s *CampaignService) UpdateCampaign(ctx context.Context, campaign *domain.Campaign) error {
 	// When updating, set UpdatedAt to current time
@@ -206,11 +221,15 @@ func (s *CampaignService) CreateDeliveries(ctx context.Context, campaignID strin
 				if err != nil {
 					return 0, err // Or handle error more gracefully
 				}
+				// unsubscribing from this delivery leaves only this list
+				delivery.ListID = &listID
 				deliveries = append(deliveries, delivery)
 				processedEmails[subscriber.Email] = true
 			}
 		}
 
+		// deliveries to suppressed recipients are created with the suppressed status and never sent
+		txCtx = withLinkCache(txCtx)
 		for _, delivery := range deliveries {
 			if err := s.deliveryService.CreateDelivery(txCtx, delivery, campaign.TemplateMJML); err != nil {
 				return 0, err
@@ -221,7 +240,7 @@ func (s *CampaignService) CreateDeliveries(ctx context.Context, campaignID strin
 		// deliveries are deduplicated by email earlier in this function, so len(deliveries)
 		// represents unique recipients for this call.
 		if len(deliveries) > 0 {
-			if err := s.repo.IncrementStats(txCtx, campaign.ID, len(deliveries), 0, 0, 0, 0, 0); err != nil {
+			if err := s.repo.IncrementStats(txCtx, campaign.ID, len(deliveries), 0, 0, 0, 0, 0, 0); err != nil {
 				return 0, err
 			}
 		}
@@ -231,6 +250,9 @@ func (s *CampaignService) CreateDeliveries(ctx context.Context, campaignID strin
 }
 
 func (s *CampaignService) validateCampaignInput(ctx context.Context, campaign *domain.Campaign) error {
+	if err := s.senders.Check(campaign.FromEmail, campaign.Headers); err != nil {
+		return err
+	}
 	if campaign.TemplateID != nil {
 		if _, err := s.templateRepo.GetByID(ctx, *campaign.TemplateID); err != nil {
 			return err
@@ -245,6 +267,9 @@ func (s *CampaignService) createDeliveryFromCampaign(campaign *domain.Campaign,
 		Name:  name,
 		Email: email,
 		Tags:  campaign.Tags,
+
+		FromName:  campaign.FromName,
+		FromEmail: campaign.FromEmail,
 	}
 	delivery.CampaignID = &campaign.ID
 
@@ -20,6 +20,8 @@ func TestCreateDelivery_RendersTemplates_UsingTestify(t *testing.T) {
 
 	campaign := &domain.Campaign{
 		ID:           "camp-1",
+		FromName:     "Acme News",
+		FromEmail:    "news@acme.example",
 		Subject:      "Hello {{ .name }}",
 		TemplateMJML: "<mjml><mj-body><mj-section><mj-column><mj-text>Company: {{ .company }} - Hi {{ .name }}</mj-text></mj-column></mj-section></mj-body></mjml>",
 		Data: map[string]interface{}{
@@ -46,6 +48,8 @@ func TestCreateDelivery_RendersTemplates_UsingTestify(t *testing.T) {
 	assert.Equal(t, "bob@example.com", delivery.Email)
 	assert.Equal(t, "Bob", delivery.Name)
 	assert.Equal(t, "Hello Bob", delivery.Subject)
+	assert.Equal(t, "Acme News", delivery.FromName)
+	assert.Equal(t, "news@acme.example", delivery.FromEmail)
 
 	assert.Contains(t, delivery.BodyHTML, "Hi Bob")
 	assert.Contains(t, delivery.BodyHTML, "Company: Acme")
@@ -12,6 +12,7 @@ import (
 	"log"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Boostport/mjml-go"
@@ -24,6 +25,7 @@ import (
 	"github.com/headmail/headmail/pkg/queue"
 	"github.com/headmail/headmail/pkg/receiver"
 	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/signing"
 )
 
 type deliveryQueueData struct {
@@ -45,6 +47,8 @@ type DeliveryServiceProvider interface {
 
 	// Handle bounced mail from receiver
 	HandleBouncedMail(ctx context.Context, event *receiver.Event) error
+	// Handle spam complaints from receiver
+	HandleComplaint(ctx context.Context, event *receiver.Event) error
 
 	// SendNow performs an immediate synchronous send attempt for the specified delivery ID.
 	SendNow(ctx context.Context, deliveryID string) (*domain.Delivery, error)
@@ -61,27 +65,38 @@ type DeliveryService struct {
 	templateService *template.Service
 	repo            repository.DeliveryRepository
 	eventRepo       repository.EventRepository
+	suppressionRepo repository.SuppressionRepository
+	linkRepo        repository.LinkRepository
 	queue           queue.Queue
 	mailer          mailer.Mailer
 	trackingHost    string
-	maxAttempts     int
+	retry           queue.RetryPolicy
+	senders         mailer.SenderPolicy
+	keyring         *signing.Keyring
+	bounces         BouncePolicy
 }
 
 // NewDeliveryService creates a new DeliveryService.
-func NewDeliveryService(db repository.DB, templateService *template.Service, q queue.Queue, m mailer.Mailer, trackingHost string, maxAttempts int) *DeliveryService {
+func NewDeliveryService(db repository.DB, templateService *template.Service, q queue.Queue, m mailer.Mailer, trackingHost string, retry queue.RetryPolicy, senders mailer.SenderPolicy, keyring *signing.Keyring, bounces BouncePolicy) *DeliveryService {
 	return &DeliveryService{
 		db:              db,
 		templateService: templateService,
 		repo:            db.DeliveryRepository(),
 		eventRepo:       db.EventRepository(),
+		suppressionRepo: db.SuppressionRepository(),
+		linkRepo:        db.LinkRepository(),
 		queue:           q,
 		mailer:          m,
 		trackingHost:    trackingHost,
-		maxAttempts:     maxAttempts,
+		retry:           retry,
+		senders:         senders,
+		keyring:         keyring,
+		bounces:         bounces,
 	}
 }
 
-// CreateDelivery creates a new delivery and enqueues immediate deliveries.
+// CreateDelivery creates a new delivery and enqueues immediate deliveries. Deliveries
+// to suppressed recipients are stored with the suppressed status and never enqueued.
 func (s *DeliveryService) CreateDelivery(ctx context.Context, delivery *domain.Delivery, templateMjml string) error {
 	delivery.ID = uuid.NewString()
 
@@ -93,12 +108,19 @@ func (s *DeliveryService) CreateDelivery(ctx context.Context, delivery *domain.D
 	if delivery.Status == "" {
 		return errors.New("invalid status")
 	}
-
-	if err := s.RenderToDelivery(ctx, delivery, templateMjml); err != nil {
+	if err := s.senders.Check(delivery.FromEmail, delivery.Headers); err != nil {
 		return err
 	}
 
 	return repository.Transactional0(s.db, ctx, func(txCtx context.Context) error {
+		// links of campaign mail are stored along with the delivery
+		if err := s.RenderToDelivery(txCtx, delivery, templateMjml); err != nil {
+			return err
+		}
+		if _, err := s.suppress(txCtx, delivery); err != nil {
+			return err
+		}
+
 		// create delivery
 		if err := s.repo.Create(txCtx, delivery); err != nil {
 			return err
@@ -172,20 +194,34 @@ func (s *DeliveryService) HandleDeliveryQueuedItem(ctx context.Context, workerID
 		return nil
 	}
 
-	err = s.mailer.Send(ctx, d)
-	now := time.Now().Unix()
+	// the recipient may have been suppressed after the delivery was created
+	suppressed, err := s.suppress(ctx, d)
 	if err != nil {
+		return err
+	}
+	if suppressed {
+		log.Printf("worker %s: delivery %s not sent: %s", workerID, d.ID, *d.FailureReason)
+		return s.repo.Update(ctx, d)
+	}
+
+	sendErr := s.send(ctx, d)
+	var limited *mailer.RateLimitError
+	if errors.As(sendErr, &limited) {
+		// nothing was sent; the worker pool returns the item once the limit allows it
+		return &queue.DeferError{Delay: limited.Delay, Err: sendErr}
+	}
+	now := time.Now().Unix()
+	if sendErr != nil {
+		// The worker pool retries the item with the queue retry policy; the delivery
+		// stays queued until the item is dead-lettered.
+		reason := sendErr.Error()
 		d.FailedAt = &now
+		d.FailureReason = &reason
 		d.Attempts++
-		if d.Attempts >= s.maxAttempts {
+		if s.retry.Exhausted(item.Attempts) {
 			d.Status = domain.DeliveryStatusFailed
-		} else {
-			d.Status = domain.DeliveryStatusScheduled
-			nextScheduledAt := now + 300 // 5 minutes
-			d.ScheduledAt = &nextScheduledAt
 		}
-
-		log.Printf("worker %s: mail send failed for delivery %s: %v", workerID, d.ID, err)
+		log.Printf("worker %s: mail send failed for delivery %s: %v", workerID, d.ID, sendErr)
 	} else {
 		d.SentAt = &now
 		d.Status = domain.DeliveryStatusSent
@@ -198,20 +234,26 @@ func (s *DeliveryService) HandleDeliveryQueuedItem(ctx context.Context, workerID
 	if d.CampaignID != nil && *d.CampaignID != "" {
 		if d.Status == domain.DeliveryStatusSent {
 			// delivered: transition to Sent
-			if err := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 1, 0, 0, 0, 0); err != nil {
+			if err := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 1, 0, 0, 0, 0, 0); err != nil {
 				log.Printf("worker %s: failed to increment campaign delivered count for %s: %v", workerID, *d.CampaignID, err)
 			}
 		} else if d.Status == domain.DeliveryStatusFailed {
 			// failed: transition to Failed
-			if err := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 0, 1, 0, 0, 0); err != nil {
+			if err := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 0, 1, 0, 0, 0, 0); err != nil {
 				log.Printf("worker %s: failed to increment campaign failed count for %s: %v", workerID, *d.CampaignID, err)
 			}
 		}
 	}
 
+	if sendErr != nil {
+		return &queue.CommitError{Err: sendErr}
+	}
 	return nil
 }
 
+// HandleBouncedMail records a bounce, marks the delivery bounced and applies the bounce
+// policies. Transient bounces (delays the sending server still retries) are only recorded
+// as deferred events.
 func (s *DeliveryService) HandleBouncedMail(ctx context.Context, data *receiver.Event) error {
 	now := time.Now()
 	ev := &domain.DeliveryEvent{
@@ -225,6 +267,16 @@ func (s *DeliveryService) HandleBouncedMail(ctx context.Context, data *receiver.
 			"reason":     data.Reason,
 		},
 	}
+	if data.BounceType != "" {
+		ev.EventData["bounce_type"] = data.BounceType
+		ev.EventData["status"] = data.Status
+		ev.EventData["diagnostic_code"] = data.DiagnosticCode
+		ev.EventData["remote_mta"] = data.RemoteMTA
+	}
+	if data.BounceType == receiver.BounceTypeTransient {
+		ev.EventType = domain.EventTypeDeferred
+		return s.eventRepo.Create(ctx, ev)
+	}
 
 	// Atomically increment bounce count (repository will handle race conditions)
 	isFirstBounce, err := s.repo.IncrementCount(ctx, data.DeliveryID, domain.EventTypeBounced)
@@ -234,7 +286,7 @@ func (s *DeliveryService) HandleBouncedMail(ctx context.Context, data *receiver.
 		// If this is the first bounce for the delivery, increment campaign-level bounce counter
 		if d, derr := s.repo.GetByID(ctx, data.DeliveryID); derr == nil {
 			if d.CampaignID != nil && *d.CampaignID != "" {
-				if cerr := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 0, 0, 1); cerr != nil {
+				if cerr := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 0, 0, 1, 0); cerr != nil {
 					log.Printf("imap receiver: failed to increment campaign bounce count for %s: %v", *d.CampaignID, cerr)
 				}
 			}
@@ -248,9 +300,65 @@ func (s *DeliveryService) HandleBouncedMail(ctx context.Context, data *receiver.
 		log.Printf("imap receiver: failed to save event: %v", err)
 	}
 
+	// hard bounces suppress the address so no campaign or transactional mail is sent to it again
+	if data.Permanent {
+		if err := s.suppressRecipient(ctx, data.DeliveryID, domain.SuppressionReasonHardBounce, domain.SuppressionSourceBounce); err != nil {
+			log.Printf("imap receiver: failed to suppress bounced recipient of %s: %v", data.DeliveryID, err)
+		}
+	}
+	if err := s.applyBouncePolicy(ctx, data.DeliveryID, domain.EventTypeBounced, data.Permanent); err != nil {
+		log.Printf("imap receiver: failed to apply bounce policy to %s: %v", data.DeliveryID, err)
+	}
+
 	return s.UpdateDeliveryStatus(ctx, data.DeliveryID, domain.DeliveryStatusBounced)
 }
 
+// HandleComplaint records a spam complaint about a delivery, counts it on the delivery and
+// its campaign, suppresses the recipient and applies the complaint policy. The delivery
+// status is left unchanged since the mail was delivered.
+func (s *DeliveryService) HandleComplaint(ctx context.Context, data *receiver.Event) error {
+	ev := &domain.DeliveryEvent{
+		DeliveryID: data.DeliveryID,
+		CreatedAt:  time.Now().Unix(),
+		EventType:  domain.EventTypeComplained,
+		EventData: map[string]interface{}{
+			"recipients": data.BouncedRecipients,
+			"subject":    data.Subject,
+			"message_id": data.MessageID,
+			"reason":     data.Reason,
+		},
+	}
+	if data.FeedbackType != "" {
+		ev.EventData["feedback_type"] = data.FeedbackType
+	}
+
+	// only the first complaint about a delivery counts towards the campaign
+	isFirstComplaint, err := s.repo.IncrementCount(ctx, data.DeliveryID, domain.EventTypeComplained)
+	if err != nil {
+		return err
+	}
+	if isFirstComplaint {
+		if d, derr := s.repo.GetByID(ctx, data.DeliveryID); derr == nil {
+			if d.CampaignID != nil && *d.CampaignID != "" {
+				if cerr := s.db.CampaignRepository().IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 0, 0, 0, 1); cerr != nil {
+					log.Printf("imap receiver: failed to increment campaign complaint count for %s: %v", *d.CampaignID, cerr)
+				}
+			}
+		} else {
+			log.Printf("imap receiver: failed to load delivery %s for campaign complaint increment: %v", data.DeliveryID, derr)
+		}
+	}
+
+	if err := s.eventRepo.Create(ctx, ev); err != nil {
+		return err
+	}
+
+	if err := s.suppressRecipient(ctx, data.DeliveryID, domain.SuppressionReasonComplaint, domain.SuppressionSourceComplaint); err != nil {
+		log.Printf("imap receiver: failed to suppress complaining recipient of %s: %v", data.DeliveryID, err)
+	}
+	return s.applyBouncePolicy(ctx, data.DeliveryID, domain.EventTypeComplained, false)
+}
+
 /*
 SendNow performs an immediate synchronous send attempt for the specified delivery ID.
 It will perform the send in-process (not via queue), update delivery status and campaign counters
@@ -265,17 +373,32 @@ func (s *DeliveryService) SendNow(ctx context.Context, deliveryID string) (*doma
 
 	prevStatus := d.Status
 
-	err = s.mailer.Send(ctx, d)
+	suppressed, err := s.suppress(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if suppressed {
+		if err := s.repo.Update(ctx, d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	err = s.send(ctx, d)
+	var limited *mailer.RateLimitError
+	if errors.As(err, &limited) {
+		// nothing was sent and the delivery is left unchanged
+		return nil, err
+	}
 	now := time.Now().Unix()
 	if err != nil {
 		d.FailedAt = &now
 		d.Attempts++
-		if d.Attempts >= s.maxAttempts {
+		if retryAt := s.retry.NextRetry(d.Attempts, time.Now()); retryAt == nil {
 			d.Status = domain.DeliveryStatusFailed
 		} else {
 			d.Status = domain.DeliveryStatusScheduled
-			nextScheduledAt := now + 300
-			d.ScheduledAt = &nextScheduledAt
+			d.ScheduledAt = retryAt
 		}
 	} else {
 		d.SentAt = &now
@@ -291,12 +414,12 @@ func (s *DeliveryService) SendNow(ctx context.Context, deliveryID string) (*doma
 	if d.CampaignID != nil && *d.CampaignID != "" {
 		cid := *d.CampaignID
 		if prevStatus != domain.DeliveryStatusSent && d.Status == domain.DeliveryStatusSent {
-			if cerr := s.db.CampaignRepository().IncrementStats(ctx, cid, 0, 1, 0, 0, 0, 0); cerr != nil {
+			if cerr := s.db.CampaignRepository().IncrementStats(ctx, cid, 0, 1, 0, 0, 0, 0, 0); cerr != nil {
 				log.Printf("SendNow: failed to increment campaign delivered count for %s: %v", cid, cerr)
 			}
 		}
 		if prevStatus != domain.DeliveryStatusFailed && d.Status == domain.DeliveryStatusFailed {
-			if cerr := s.db.CampaignRepository().IncrementStats(ctx, cid, 0, 0, 1, 0, 0, 0); cerr != nil {
+			if cerr := s.db.CampaignRepository().IncrementStats(ctx, cid, 0, 0, 1, 0, 0, 0, 0); cerr != nil {
 				log.Printf("SendNow: failed to increment campaign failed count for %s: %v", cid, cerr)
 			}
 		}
@@ -331,6 +454,36 @@ func (s *DeliveryService) Retry(ctx context.Context, deliveryID string) (*domain
 	return s.SendNow(ctx, deliveryID)
 }
 
+// suppressRecipient adds the recipient of a delivery to the suppression list.
+func (s *DeliveryService) suppressRecipient(ctx context.Context, deliveryID string, reason domain.SuppressionReason, source string) error {
+	d, err := s.repo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	suppression := &domain.Suppression{
+		Type:   domain.SuppressionTypeEmail,
+		Value:  d.Email,
+		Reason: reason,
+		Source: source,
+	}
+	if err := prepareSuppression(suppression); err != nil {
+		return err
+	}
+	return s.suppressionRepo.Upsert(ctx, suppression)
+}
+
+// suppress marks d as suppressed when its recipient is on the suppression list.
+func (s *DeliveryService) suppress(ctx context.Context, d *domain.Delivery) (bool, error) {
+	suppression, err := matchSuppression(ctx, s.suppressionRepo, d.Email)
+	if err != nil || suppression == nil {
+		return false, err
+	}
+	reason := fmt.Sprintf("recipient suppressed by %s entry '%s' (%s)", suppression.Type, suppression.Value, suppression.Reason)
+	d.Status = domain.DeliveryStatusSuppressed
+	d.FailureReason = &reason
+	return true, nil
+}
+
 func (s *DeliveryService) RenderToDelivery(ctx context.Context, dest *domain.Delivery, templateMjml string) error {
 	var err error
 
@@ -341,6 +494,12 @@ func (s *DeliveryService) RenderToDelivery(ctx context.Context, dest *domain.Del
 	templateData["name"] = dest.Name
 	templateData["email"] = dest.Email
 	templateData["deliveryId"] = dest.ID
+	if u := s.unsubscribeURL(dest.ID); u != "" {
+		templateData["unsubscribeUrl"] = u
+	}
+	if u := s.preferencesURL(ctx, dest.Email); u != "" {
+		templateData["preferencesUrl"] = u
+	}
 
 	dest.Subject, err = s.templateService.Render(dest.Subject, templateData)
 	if err != nil {
@@ -359,7 +518,7 @@ func (s *DeliveryService) RenderToDelivery(ctx context.Context, dest *domain.Del
 
 	// inject tracking into HTML before sending (rewrite links + add tracking pixel)
 	if dest.BodyHTML != "" && s.trackingHost != "" {
-		dest.BodyHTML = s.injectTracking(dest.ID, dest.BodyHTML)
+		dest.BodyHTML = s.injectTracking(dest.ID, dest.BodyHTML, s.linkResolver(ctx, dest))
 	}
 
 	return nil
@@ -369,13 +528,174 @@ func (s *DeliveryService) RenderToDelivery(ctx context.Context, dest *domain.Del
 func (s *DeliveryService) sendMail(d *domain.Delivery) error {
 	// Delegate to configured mailer implementation.
 	// Mailer implementations are responsible for building headers/body and sending.
-	return s.mailer.Send(context.Background(), d)
+	return s.send(context.Background(), d)
+}
+
+// send hands d to the mailer with List-Unsubscribe headers added to campaign mail.
+// The headers are not persisted, so retries always link to the current public URL.
+func (s *DeliveryService) send(ctx context.Context, d *domain.Delivery) error {
+	headers := d.Headers
+	defer func() { d.Headers = headers }()
+	d.Headers = s.unsubscribeHeaders(d)
+	return s.mailer.Send(ctx, d)
+}
+
+// unsubscribeHeaders returns the headers of d with one-click unsubscribe headers
+// (RFC 2369, RFC 8058) added. Transactional mail and deliveries that set their own
+// List-Unsubscribe header are left unchanged.
+func (s *DeliveryService) unsubscribeHeaders(d *domain.Delivery) map[string]string {
+	if d.Type != domain.DeliveryTypeCampaign {
+		return d.Headers
+	}
+	for name := range d.Headers {
+		if strings.EqualFold(name, "List-Unsubscribe") {
+			return d.Headers
+		}
+	}
+	u := s.unsubscribeURL(d.ID)
+	if u == "" {
+		return d.Headers
+	}
+
+	headers := make(map[string]string, len(d.Headers)+2)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers["List-Unsubscribe"] = "<" + u + ">"
+	headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	return headers
+}
+
+// unsubscribeURL returns the public one-click unsubscribe URL of a delivery, or ""
+// when no public URL or signing keyring is configured.
+func (s *DeliveryService) unsubscribeURL(deliveryID string) string {
+	if deliveryID == "" || s.keyring == nil {
+		return ""
+	}
+	return s.publicLink("/u/" + UnsubscribeToken(s.keyring, deliveryID))
+}
+
+// preferencesURL returns the preference center URL of the subscriber with the given
+// email, or "" when the recipient is not a subscriber.
+func (s *DeliveryService) preferencesURL(ctx context.Context, email string) string {
+	if email == "" || s.keyring == nil || s.trackingHost == "" {
+		return ""
+	}
+	subscriber, err := s.db.SubscriberRepository().GetByEmail(ctx, email)
+	if err != nil {
+		return ""
+	}
+	return s.publicLink("/p/" + PreferencesToken(s.keyring, subscriber.ID))
+}
+
+func (s *DeliveryService) publicLink(path string) string {
+	return publicLink(s.trackingHost, path)
+}
+
+// publicLink returns the absolute URL of path on the public server at publicURL,
+// or "" when no public URL is configured.
+func publicLink(publicURL string, path string) string {
+	if publicURL == "" {
+		return ""
+	}
+	if !strings.HasPrefix(publicURL, "http://") && !strings.HasPrefix(publicURL, "https://") {
+		publicURL = "https://" + publicURL
+	}
+	return strings.TrimRight(publicURL, "/") + path
+}
+
+// maxCampaignLinks limits the links stored per campaign. Targets beyond it, typically
+// URLs templated per recipient, embed their target in the click URL instead.
+const maxCampaignLinks = 100
+
+// errCampaignLinkLimit is returned by a linkResolver once the campaign has
+// maxCampaignLinks links.
+var errCampaignLinkLimit = errors.New("campaign link limit reached")
+
+// publicRecipientPaths are the paths of per-recipient links to the public server, such
+// as unsubscribe links, which are not click tracked.
+var publicRecipientPaths = []string{"/r/", "/u/", "/p/", "/subscribe/confirm/"}
+
+// linkResolver resolves a target URL to the ID of its stored link.
+type linkResolver func(target string) (string, error)
+
+type linkCacheKey struct{}
+
+// linkCache holds the outcome of resolving links within a transaction, so rendering
+// many deliveries of a campaign looks up each target once. It must not outlive the
+// transaction, whose links are gone if it rolls back.
+type linkCache struct {
+	mu       sync.Mutex
+	resolved map[string]error // by link ID; nil or errCampaignLinkLimit
+}
+
+// withLinkCache returns a context caching the links resolved with it.
+func withLinkCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, linkCacheKey{}, &linkCache{resolved: make(map[string]error)})
+}
+
+// linkResolver returns a linkResolver storing the links of the campaign of d, or nil
+// for transactional mail, whose links embed their target. Links are written with ctx,
+// i.e. within the transaction creating the delivery.
+func (s *DeliveryService) linkResolver(ctx context.Context, d *domain.Delivery) linkResolver {
+	if d.CampaignID == nil || *d.CampaignID == "" {
+		return nil
+	}
+	campaignID := *d.CampaignID
+	cache, _ := ctx.Value(linkCacheKey{}).(*linkCache)
+	return func(target string) (string, error) {
+		id := LinkID(campaignID, target)
+		if cache != nil {
+			cache.mu.Lock()
+			err, ok := cache.resolved[id]
+			cache.mu.Unlock()
+			if ok {
+				return id, err
+			}
+		}
+		id, err := s.resolveLink(ctx, campaignID, id, target)
+		if cache != nil && (err == nil || errors.Is(err, errCampaignLinkLimit)) {
+			cache.mu.Lock()
+			cache.resolved[id] = err
+			cache.mu.Unlock()
+		}
+		return id, err
+	}
+}
+
+// resolveLink returns the ID of the stored link of the campaign to target, storing it
+// unless the campaign has maxCampaignLinks links.
+func (s *DeliveryService) resolveLink(ctx context.Context, campaignID string, id string, target string) (string, error) {
+	link, err := s.linkRepo.GetByID(ctx, id)
+	var notFound *repository.ErrNotFound
+	if errors.As(err, &notFound) {
+		link = &domain.Link{
+			ID:         id,
+			CampaignID: campaignID,
+			URL:        target,
+			CreatedAt:  time.Now().Unix(),
+		}
+		var stored bool
+		if stored, err = s.linkRepo.UpsertLimited(ctx, link, maxCampaignLinks); err == nil && !stored {
+			return id, errCampaignLinkLimit
+		}
+	}
+	if err != nil {
+		return id, err
+	}
+	if link.URL != target {
+		return id, fmt.Errorf("link id %s of %s is taken by %s", link.ID, target, link.URL)
+	}
+	return link.ID, nil
 }
 
 // injectTracking rewrites only anchor tag href attributes in the provided HTML
 // to route through the click tracker and appends an open-tracking 1x1 image.
-// Uses an HTML parser to modify only <a> href attributes.
-func (s *DeliveryService) injectTracking(deliveryID, htmlStr string) string {
+// Uses an HTML parser to modify only <a> href attributes. Links resolved by links
+// point to their short ID; without links, or when resolving fails, the target URL
+// is embedded. Tracking URLs are signed with the keyring so they cannot be pointed
+// at other targets or deliveries.
+func (s *DeliveryService) injectTracking(deliveryID, htmlStr string, links linkResolver) string {
 	// parse HTML document
 	doc, err := html.Parse(strings.NewReader(htmlStr))
 	if err != nil {
@@ -383,6 +703,14 @@ func (s *DeliveryService) injectTracking(deliveryID, htmlStr string) string {
 		return s.appendPixel(htmlStr, deliveryID)
 	}
 
+	trackingURL := s.trackingHost
+	if !strings.HasPrefix(trackingURL, "http://") && !strings.HasPrefix(trackingURL, "https://") {
+		trackingURL = "https://" + strings.TrimRight(trackingURL, "/")
+	}
+	prefix := trackingURL + "/r/" + deliveryID + "/"
+	// each distinct target is resolved once per message
+	resolved := make(map[string]string)
+
 	var walk func(*html.Node)
 	walk = func(n *html.Node) {
 		if n.Type == html.ElementNode && strings.EqualFold(n.Data, "a") {
@@ -390,15 +718,14 @@ func (s *DeliveryService) injectTracking(deliveryID, htmlStr string) string {
 				if strings.EqualFold(a.Key, "href") {
 					orig := a.Val
 					lower := strings.ToLower(orig)
-					if !strings.HasPrefix(lower, "http") || strings.Contains(orig, "/r/"+deliveryID+"/c") {
+					if !strings.HasPrefix(lower, "http") || s.isRecipientLink(orig) {
 						continue
 					}
-					encoded := url.QueryEscape(orig)
-					trackingURL := s.trackingHost
-					if !strings.HasPrefix(trackingURL, "http://") && !strings.HasPrefix(trackingURL, "https://") {
-						trackingURL = "https://" + strings.TrimRight(trackingURL, "/")
+					newHref, ok := resolved[orig]
+					if !ok {
+						newHref = s.clickURL(prefix, deliveryID, orig, links)
+						resolved[orig] = newHref
 					}
-					newHref := trackingURL + "/r/" + deliveryID + "/c?u=" + encoded
 					n.Attr[i].Val = newHref
 				}
 			}
@@ -419,13 +746,51 @@ func (s *DeliveryService) injectTracking(deliveryID, htmlStr string) string {
 	return s.appendPixel(out, deliveryID)
 }
 
+// clickURL returns the click tracking URL of a delivery for target. prefix is the
+// tracking URL of the delivery up to and including the slash after its ID.
+func (s *DeliveryService) clickURL(prefix, deliveryID, target string, links linkResolver) string {
+	if links != nil {
+		linkID, err := links(target)
+		if err == nil {
+			u := prefix + "l/" + linkID
+			if s.keyring != nil {
+				u += "?s=" + LinkSignature(s.keyring, deliveryID, linkID)
+			}
+			return u
+		}
+		if !errors.Is(err, errCampaignLinkLimit) {
+			log.Printf("tracking: failed to store link for delivery %s: %v", deliveryID, err)
+		}
+	}
+	u := prefix + "c?u=" + url.QueryEscape(target)
+	if s.keyring != nil {
+		u += "&s=" + ClickSignature(s.keyring, deliveryID, target)
+	}
+	return u
+}
+
+// isRecipientLink reports whether href is a per-recipient link to the public server,
+// e.g. an unsubscribe or tracking link.
+func (s *DeliveryService) isRecipientLink(href string) bool {
+	for _, path := range publicRecipientPaths {
+		if strings.HasPrefix(href, s.publicLink(path)) {
+			return true
+		}
+	}
+	return false
+}
+
 // appendPixel appends the tracking pixel before </body> if present, otherwise at end.
 func (s *DeliveryService) appendPixel(htmlStr, deliveryID string) string {
 	pixelURL := s.trackingHost
 	if !strings.HasPrefix(pixelURL, "http://") && !strings.HasPrefix(pixelURL, "https://") {
 		pixelURL = "https://" + strings.TrimRight(pixelURL, "/")
 	}
-	pixel := `<img src="` + pixelURL + `/r/` + deliveryID + `/o" width="1" height="1" style="display:none" alt="">`
+	pixelURL += "/r/" + deliveryID + "/o"
+	if s.keyring != nil {
+		pixelURL += "?s=" + OpenSignature(s.keyring, deliveryID)
+	}
+	pixel := `<img src="` + pixelURL + `" width="1" height="1" style="display:none" alt="">`
 	lower := strings.ToLower(htmlStr)
 	if idx := strings.LastIndex(lower, "</body>"); idx != -1 {
 		return htmlStr[:idx] + pixel + htmlStr[idx:]
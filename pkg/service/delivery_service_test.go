@@ -1,9 +1,25 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
 	"log"
+	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/signing"
+	"github.com/headmail/headmail/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInjectTracking_TableDriven(t *testing.T) {
@@ -44,7 +60,7 @@ func TestInjectTracking_TableDriven(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			out := s.injectTracking(deliveryID, tc.in)
+			out := s.injectTracking(deliveryID, tc.in, nil)
 			log.Printf("%s", out)
 			for _, want := range tc.expected {
 				if !strings.Contains(out, want) {
@@ -88,3 +104,226 @@ func TestAppendPixel_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestInjectTracking_Signed(t *testing.T) {
+	oldKey, err := signing.GenerateKey()
+	require.NoError(t, err)
+	oldKeyring, err := signing.NewKeyring(oldKey)
+	require.NoError(t, err)
+	s := &DeliveryService{trackingHost: "https://tracking.example.com", keyring: oldKeyring}
+	ctx := context.Background()
+
+	out := s.injectTracking("del-123", `<html><body><a href="https://example.com/a?x=1&amp;y=%20">link</a></body></html>`, nil)
+	hrefs := regexp.MustCompile(`(?:href|src)="([^"]+)"`).FindAllStringSubmatch(out, -1)
+	require.Len(t, hrefs, 2)
+	click, err := url.Parse(html.UnescapeString(hrefs[0][1]))
+	require.NoError(t, err)
+	assert.Equal(t, "/r/del-123/c", click.Path)
+	target, sig := click.Query().Get("u"), click.Query().Get("s")
+	assert.Equal(t, "https://example.com/a?x=1&y=%20", target)
+	open, err := url.Parse(html.UnescapeString(hrefs[1][1]))
+	require.NoError(t, err)
+	assert.Equal(t, "/r/del-123/o", open.Path)
+
+	// links stay valid after a new key is put in front of the old one
+	newKey, err := signing.GenerateKey()
+	require.NoError(t, err)
+	keyring, err := signing.NewKeyring(newKey, oldKey)
+	require.NoError(t, err)
+	tracking := &TrackingService{keyring: keyring}
+	assert.True(t, tracking.VerifyClick(ctx, "del-123", target, sig))
+	assert.True(t, tracking.VerifyOpen(ctx, "del-123", open.Query().Get("s")))
+
+	assert.False(t, tracking.VerifyClick(ctx, "del-123", "https://evil.example.com", sig), "other targets are rejected")
+	assert.False(t, tracking.VerifyClick(ctx, "del-456", target, sig), "other deliveries are rejected")
+	assert.False(t, tracking.VerifyOpen(ctx, "del-456", open.Query().Get("s")))
+	assert.False(t, tracking.VerifyClick(ctx, "del-123", target, ""))
+	assert.False(t, (&TrackingService{keyring: newTestKeyring(t)}).VerifyClick(ctx, "del-123", target, sig), "links of unknown keys are rejected")
+}
+
+func TestTrackingService_UnsignedBefore(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	cutover := time.Now()
+	target := "https://example.com/a?b=1&c=2"
+	for id, createdAt := range map[string]int64{"del-old": cutover.Add(-time.Hour).Unix(), "del-new": cutover.Add(time.Second).Unix()} {
+		require.NoError(t, db.DeliveryRepository().Create(ctx, &domain.Delivery{
+			ID:        id,
+			Type:      domain.DeliveryTypeTransaction,
+			Status:    domain.DeliveryStatusSent,
+			Email:     "bob@example.com",
+			BodyHTML:  `<a href="https://tracking.example.com/r/` + id + `/c?u=` + url.QueryEscape(target) + `">a</a>`,
+			CreatedAt: createdAt,
+		}))
+	}
+	campaignID := "camp-old"
+	require.NoError(t, db.CampaignRepository().Create(ctx, &domain.Campaign{ID: campaignID, Name: "News", Status: domain.CampaignStatusSent}))
+	require.NoError(t, db.DeliveryRepository().Create(ctx, &domain.Delivery{
+		ID:         "del-campaign",
+		CampaignID: &campaignID,
+		Type:       domain.DeliveryTypeCampaign,
+		Status:     domain.DeliveryStatusSent,
+		Email:      "bob@example.com",
+		CreatedAt:  cutover.Add(-time.Hour).Unix(),
+	}))
+	require.NoError(t, db.LinkRepository().Upsert(ctx, &domain.Link{ID: LinkID(campaignID, "https://example.com/b"), CampaignID: campaignID, URL: "https://example.com/b"}))
+
+	// unsigned URLs of mail sent before the cutover keep working
+	tracking := NewTrackingService(db, newTestKeyring(t), cutover)
+	assert.True(t, tracking.VerifyClick(ctx, "del-old", target, ""))
+	assert.True(t, tracking.VerifyClick(ctx, "del-campaign", "https://example.com/b", ""))
+	assert.True(t, tracking.VerifyOpen(ctx, "del-old", ""))
+	// but only redirect to targets the mail linked to
+	assert.False(t, tracking.VerifyClick(ctx, "del-old", "https://evil.example.com", ""))
+	assert.False(t, tracking.VerifyClick(ctx, "del-old", "https://example.com/a", ""), "prefixes of targets are rejected")
+	assert.False(t, tracking.VerifyClick(ctx, "del-campaign", "https://evil.example.com", ""))
+	assert.False(t, tracking.VerifyClick(ctx, "del-new", target, ""))
+	assert.False(t, tracking.VerifyOpen(ctx, "del-new", ""))
+	assert.False(t, tracking.VerifyClick(ctx, "del-unknown", "https://example.com", ""))
+	assert.False(t, tracking.VerifyClick(ctx, "del-old", "https://example.com", "0000000000000000"), "signed URLs are verified")
+
+	tracking = NewTrackingService(db, newTestKeyring(t), time.Time{})
+	assert.False(t, tracking.VerifyClick(ctx, "del-old", target, ""))
+}
+
+func TestInjectTracking_ShortLinks(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	keyring := newTestKeyring(t)
+	campaignID := "camp-1"
+	require.NoError(t, db.CampaignRepository().Create(ctx, &domain.Campaign{ID: campaignID, Name: "News", Status: domain.CampaignStatusSending}))
+	d := &domain.Delivery{ID: "del-1", CampaignID: &campaignID, Type: domain.DeliveryTypeCampaign, Status: domain.DeliveryStatusSent, Email: "bob@example.com"}
+	require.NoError(t, db.DeliveryRepository().Create(ctx, d))
+
+	s := NewDeliveryService(db, nil, nil, nil, "https://tracking.example.com", queue.DefaultRetryPolicy, mailer.SenderPolicy{}, keyring, BouncePolicy{})
+	target := "https://example.com/a?x=1&y=%20"
+	out := s.injectTracking(d.ID, `<html><body><a href="https://example.com/a?x=1&amp;y=%20">one</a><a href="https://example.com/a?x=1&amp;y=%20">two</a></body></html>`, s.linkResolver(ctx, d))
+	assert.NotContains(t, out, "example.com/a", "targets are not embedded")
+	hrefs := regexp.MustCompile(`href="([^"]+)"`).FindAllStringSubmatch(out, -1)
+	require.Len(t, hrefs, 2)
+	assert.Equal(t, hrefs[0][1], hrefs[1][1], "a target has one link")
+	click, err := url.Parse(html.UnescapeString(hrefs[0][1]))
+	require.NoError(t, err)
+	linkID := LinkID(campaignID, target)
+	assert.Equal(t, "/r/del-1/l/"+linkID, click.Path)
+
+	tracking := NewTrackingService(db, keyring, time.Time{})
+	sig := click.Query().Get("s")
+	assert.True(t, tracking.VerifyLink(d.ID, linkID, sig))
+	assert.False(t, tracking.VerifyLink("del-2", linkID, sig))
+	link, err := tracking.GetLink(ctx, linkID)
+	require.NoError(t, err)
+	assert.Equal(t, target, link.URL)
+	require.NoError(t, tracking.LogLinkClick(ctx, d.ID, link, nil, nil))
+
+	campaigns := NewCampaignService(db, s, mailer.SenderPolicy{})
+	links, total, err := campaigns.ListCampaignLinks(ctx, campaignID, repository.Pagination{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, links, 1)
+	assert.Equal(t, 1, links[0].ClickCount)
+	delivery, err := db.DeliveryRepository().GetByID(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivery.ClickCount)
+
+	// transactional mail embeds its targets
+	assert.Nil(t, s.linkResolver(ctx, &domain.Delivery{ID: "tx-1"}))
+
+	// per-recipient links to the public server are left alone
+	for _, href := range []string{
+		"https://tracking.example.com/u/token",
+		"https://tracking.example.com/p/token",
+		"https://tracking.example.com/subscribe/confirm/token",
+	} {
+		out := s.injectTracking(d.ID, `<a href="`+href+`">x</a>`, s.linkResolver(ctx, d))
+		assert.Contains(t, out, `href="`+href+`"`)
+	}
+
+	// targets beyond the campaign's link limit embed their target
+	var body strings.Builder
+	for i := 0; i < maxCampaignLinks; i++ {
+		fmt.Fprintf(&body, `<a href="https://example.com/r/%d">x</a>`, i)
+	}
+	out = s.injectTracking(d.ID, body.String(), s.linkResolver(ctx, d))
+	assert.Contains(t, out, "/r/del-1/c?u="+url.QueryEscape(fmt.Sprintf("https://example.com/r/%d", maxCampaignLinks-1)))
+	_, total, err = campaigns.ListCampaignLinks(ctx, campaignID, repository.Pagination{Page: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, maxCampaignLinks, total)
+	// links stored before the limit was reached are still used
+	out = s.injectTracking(d.ID, `<a href="https://example.com/a?x=1&amp;y=%20">one</a>`, s.linkResolver(ctx, d))
+	assert.Contains(t, out, "/r/del-1/l/"+linkID)
+}
+
+// countingLinks counts the lookups of a LinkRepository.
+type countingLinks struct {
+	repository.LinkRepository
+	lookups int
+}
+
+func (r *countingLinks) GetByID(ctx context.Context, id string) (*domain.Link, error) {
+	r.lookups++
+	return r.LinkRepository.GetByID(ctx, id)
+}
+
+func TestLinkResolver_Cache(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	campaignID := "camp-1"
+	require.NoError(t, db.CampaignRepository().Create(ctx, &domain.Campaign{ID: campaignID, Name: "News", Status: domain.CampaignStatusSending}))
+	s := NewDeliveryService(db, nil, nil, nil, "https://tracking.example.com", queue.DefaultRetryPolicy, mailer.SenderPolicy{}, newTestKeyring(t), BouncePolicy{})
+	links := &countingLinks{LinkRepository: db.LinkRepository()}
+	s.linkRepo = links
+
+	// deliveries rendered with a cache look up each target once
+	cached := withLinkCache(ctx)
+	for _, id := range []string{"del-1", "del-2", "del-3"} {
+		d := &domain.Delivery{ID: id, CampaignID: &campaignID}
+		got, err := s.linkResolver(cached, d)("https://example.com/a")
+		require.NoError(t, err)
+		assert.Equal(t, LinkID(campaignID, "https://example.com/a"), got)
+	}
+	assert.Equal(t, 1, links.lookups)
+
+	// without a cache every delivery looks up its targets
+	for _, id := range []string{"del-4", "del-5"} {
+		_, err := s.linkResolver(ctx, &domain.Delivery{ID: id, CampaignID: &campaignID})("https://example.com/a")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, links.lookups)
+}
+
+// failingMailer fails every send.
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, *domain.Delivery) error {
+	return errors.New("connection refused")
+}
+
+func TestHandleDeliveryQueuedItem_SendFailure(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	svc := NewDeliveryService(db, template.NewService(), db.QueueRepository(), failingMailer{}, "", queue.RetryPolicy{MaxAttempts: 2}, mailer.SenderPolicy{}, newTestKeyring(t), BouncePolicy{})
+	d := &domain.Delivery{Type: domain.DeliveryTypeTransaction, Status: domain.DeliveryStatusScheduled, Email: "bob@example.com"}
+	require.NoError(t, svc.CreateDelivery(ctx, d, "<mjml><mj-body></mj-body></mjml>"))
+	items, err := db.QueueRepository().Claim(ctx, "worker-1", 1, queue.ClaimFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// the send error is returned for the worker pool to retry the item
+	err = svc.HandleDeliveryQueuedItem(ctx, "worker-1", items[0])
+	var commit *queue.CommitError
+	require.ErrorAs(t, err, &commit)
+	got, err := svc.GetDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusQueued, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+	require.NotNil(t, got.FailureReason)
+	assert.Equal(t, "connection refused", *got.FailureReason)
+
+	// the delivery fails with the last attempt of the item
+	items[0].Attempts = 2
+	require.ErrorAs(t, svc.HandleDeliveryQueuedItem(ctx, "worker-1", items[0]), &commit)
+	got, err = svc.GetDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusFailed, got.Status)
+}
@@ -32,6 +32,9 @@ type ListServiceProvider interface {
 
 	// Replace subscribers in a list atomically
 	ReplaceSubscribersInList(ctx context.Context, listID string, subscriberIDs []string) error
+
+	// UpdateSubscriptions sets the status of a subscriber's memberships, keyed by list ID.
+	UpdateSubscriptions(ctx context.Context, subscriberID string, statuses map[string]domain.SubscriberListStatus) error
 }
 
 // ListService provides business logic for list management.
@@ -154,3 +157,20 @@ func (s *ListService) ReplaceSubscribersInList(ctx context.Context, listID strin
 		return s.listRepo.ReplaceSubscribers(txCtx, listID, subscriberIDs)
 	})
 }
+
+// UpdateSubscriptions sets the status of a subscriber's memberships, keyed by list ID,
+// within a transaction. Lists the subscriber is not a member of are ignored.
+func (s *ListService) UpdateSubscriptions(ctx context.Context, subscriberID string, statuses map[string]domain.SubscriberListStatus) error {
+	now := time.Now().Unix()
+	return repository.Transactional0(s.db, ctx, func(txCtx context.Context) error {
+		for listID, status := range statuses {
+			if listID == "" {
+				continue
+			}
+			if _, err := s.subscriberRepo.SetListStatus(txCtx, subscriberID, listID, status, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
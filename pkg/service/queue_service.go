@@ -0,0 +1,75 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// ErrNotPurgeable is returned when asked to purge items that are still in flight.
+var ErrNotPurgeable = errors.New("only done and dead queue items can be purged")
+
+// QueueServiceProvider defines the interface for inspecting and maintaining the work queue.
+type QueueServiceProvider interface {
+	ListItems(ctx context.Context, filter queue.Filter, pagination repository.Pagination) ([]*queue.QueueItem, int, error)
+	GetItem(ctx context.Context, id string) (*queue.QueueItem, error)
+	// ReplayItem returns a dead item to the queue.
+	ReplayItem(ctx context.Context, id string) error
+	// ReplayItems returns all dead items matching filter to the queue.
+	ReplayItems(ctx context.Context, filter queue.Filter) (int, error)
+	// PurgeItems deletes finished (done or dead) items matching filter.
+	PurgeItems(ctx context.Context, filter queue.Filter) (int, error)
+}
+
+// QueueService provides business logic for queue inspection.
+type QueueService struct {
+	queue queue.Queue
+}
+
+// NewQueueService creates a new QueueService.
+func NewQueueService(db repository.DB) *QueueService {
+	return &QueueService{
+		queue: db.QueueRepository(),
+	}
+}
+
+// ListItems lists queue items matching the filter.
+func (s *QueueService) ListItems(ctx context.Context, filter queue.Filter, pagination repository.Pagination) ([]*queue.QueueItem, int, error) {
+	offset := (pagination.Page - 1) * pagination.Limit
+	return s.queue.List(ctx, filter, offset, pagination.Limit)
+}
+
+// GetItem retrieves a queue item by its ID.
+func (s *QueueService) GetItem(ctx context.Context, id string) (*queue.QueueItem, error) {
+	return s.queue.Get(ctx, id)
+}
+
+// ReplayItem returns a dead item to the queue.
+func (s *QueueService) ReplayItem(ctx context.Context, id string) error {
+	return s.queue.Replay(ctx, id)
+}
+
+// ReplayItems returns all dead items matching the filter to the queue.
+func (s *QueueService) ReplayItems(ctx context.Context, filter queue.Filter) (int, error) {
+	return s.queue.ReplayMatching(ctx, filter)
+}
+
+// PurgeItems deletes done and dead items matching the filter. Pending and reserved
+// items are never purged; when no status is given both done and dead items are purged.
+func (s *QueueService) PurgeItems(ctx context.Context, filter queue.Filter) (int, error) {
+	if len(filter.Status) == 0 {
+		filter.Status = []queue.Status{queue.StatusDone, queue.StatusDead}
+	}
+	for _, status := range filter.Status {
+		if status != queue.StatusDone && status != queue.StatusDead {
+			return 0, fmt.Errorf("%w: got status '%s'", ErrNotPurgeable, status)
+		}
+	}
+	return s.queue.Purge(ctx, filter)
+}
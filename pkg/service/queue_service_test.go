@@ -0,0 +1,21 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeItems_RejectsInFlightStatuses(t *testing.T) {
+	svc := &QueueService{}
+
+	for _, status := range []queue.Status{queue.StatusPending, queue.StatusReserved} {
+		_, err := svc.PurgeItems(context.Background(), queue.Filter{Status: []queue.Status{queue.StatusDone, status}})
+		assert.ErrorIs(t, err, ErrNotPurgeable)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/signing"
+)
+
+const (
+	// UnsubscribePurpose is the signing purpose of unsubscribe tokens.
+	UnsubscribePurpose = "unsubscribe"
+	// PreferencesPurpose is the signing purpose of preference center tokens.
+	PreferencesPurpose = "preferences"
+	// ConfirmationPurpose is the signing purpose of double opt-in confirmation tokens.
+	ConfirmationPurpose = "confirm"
+)
+
+// confirmationResendInterval limits how often confirmation mail is sent for the same
+// pending subscription, so the subscribe form cannot be used to flood an inbox.
+const confirmationResendInterval = 10 * time.Minute
+
+// ErrInvalidSubscription is returned for public subscription requests that cannot be
+// accepted, e.g. an invalid email address or a list without a confirmation template.
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// SubscriptionServiceProvider defines the interface for recipient-facing subscription management.
+type SubscriptionServiceProvider interface {
+	// ResolveUnsubscribe verifies an unsubscribe token and returns the delivery it was issued for.
+	ResolveUnsubscribe(ctx context.Context, token string) (*domain.Delivery, error)
+	// Unsubscribe removes the recipient of the token's delivery from the list the delivery
+	// was sent to, or from all lists when it was not sent to a list. It returns the delivery.
+	Unsubscribe(ctx context.Context, token string, ua *string, ip *string) (*domain.Delivery, error)
+
+	// GetPreferences verifies a preference center token and returns the subscriber's lists.
+	GetPreferences(ctx context.Context, token string) (*Preferences, error)
+	// UpdatePreferences keeps the subscriber subscribed to the given lists and unsubscribes
+	// them from their other lists, or from all lists when unsubscribeAll is set.
+	UpdatePreferences(ctx context.Context, token string, subscribed []string, unsubscribeAll bool) (*Preferences, error)
+
+	// Subscribe adds pending memberships of the given lists for email and sends a
+	// confirmation mail per list. Lists the recipient is already confirmed in are skipped.
+	Subscribe(ctx context.Context, email string, name string, listIDs []string) error
+	// ResolveConfirmation verifies a confirmation token and returns the membership it confirms.
+	ResolveConfirmation(ctx context.Context, token string) (*Confirmation, error)
+	// ConfirmSubscription confirms the pending membership of a confirmation token.
+	ConfirmSubscription(ctx context.Context, token string) (*Confirmation, error)
+	// ExpirePendingSubscriptions deletes pending memberships not confirmed since before
+	// the given time and returns the number deleted.
+	ExpirePendingSubscriptions(ctx context.Context, before time.Time) (int, error)
+}
+
+// Preferences is the state shown in the preference center.
+type Preferences struct {
+	Subscriber *domain.Subscriber
+	Lists      []ListPreference
+}
+
+// ListPreference is one list membership of a subscriber.
+type ListPreference struct {
+	List   *domain.List
+	Status domain.SubscriberListStatus
+}
+
+// Editable reports whether the subscriber may change the membership. Memberships
+// that were ended by bounces or complaints cannot be resubscribed.
+func (p ListPreference) Editable() bool {
+	return p.Status == domain.SubscriberListStatusConfirmed || p.Status == domain.SubscriberListStatusUnsubscribed
+}
+
+// Confirmation is the list membership a confirmation token was issued for.
+type Confirmation struct {
+	Subscriber *domain.Subscriber
+	List       *domain.List
+	Status     domain.SubscriberListStatus
+}
+
+// SubscriptionService provides business logic for recipients subscribing and unsubscribing.
+type SubscriptionService struct {
+	db              repository.DB
+	listService     ListServiceProvider
+	deliveryService DeliveryServiceProvider
+	listRepo        repository.ListRepository
+	deliveryRepo    repository.DeliveryRepository
+	subscriberRepo  repository.SubscriberRepository
+	templateRepo    repository.TemplateRepository
+	eventRepo       repository.EventRepository
+	keyring         *signing.Keyring
+	publicURL       string
+}
+
+// NewSubscriptionService creates a new SubscriptionService. publicURL is the base URL
+// of the public server, used for links in confirmation mail.
+func NewSubscriptionService(db repository.DB, listService ListServiceProvider, deliveryService DeliveryServiceProvider, keyring *signing.Keyring, publicURL string) *SubscriptionService {
+	return &SubscriptionService{
+		db:              db,
+		listService:     listService,
+		deliveryService: deliveryService,
+		listRepo:        db.ListRepository(),
+		deliveryRepo:    db.DeliveryRepository(),
+		subscriberRepo:  db.SubscriberRepository(),
+		templateRepo:    db.TemplateRepository(),
+		eventRepo:       db.EventRepository(),
+		keyring:         keyring,
+		publicURL:       publicURL,
+	}
+}
+
+func (s *SubscriptionService) ResolveUnsubscribe(ctx context.Context, token string) (*domain.Delivery, error) {
+	deliveryID, err := s.keyring.Verify(UnsubscribePurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliveryRepo.GetByID(ctx, deliveryID)
+}
+
+func (s *SubscriptionService) Unsubscribe(ctx context.Context, token string, ua *string, ip *string) (*domain.Delivery, error) {
+	d, err := s.ResolveUnsubscribe(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber, err := s.subscriberRepo.GetByEmail(ctx, d.Email)
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			// the recipient is not on any list; nothing to do
+			return d, nil
+		}
+		return nil, err
+	}
+
+	// only confirmed and pending memberships are unsubscribed; those ended by a bounce
+	// or complaint keep their status
+	listID := ""
+	if d.ListID != nil {
+		listID = *d.ListID
+	}
+
+	now := time.Now().Unix()
+	err = repository.Transactional0(s.db, ctx, func(txCtx context.Context) error {
+		n, err := s.subscriberRepo.SetListStatus(txCtx, subscriber.ID, listID, domain.SubscriberListStatusUnsubscribed, now)
+		if err != nil {
+			return err
+		}
+		// repeated requests (e.g. a client retrying the one-click POST) record a single event
+		if n == 0 {
+			return nil
+		}
+		return s.eventRepo.Create(txCtx, &domain.DeliveryEvent{
+			DeliveryID: d.ID,
+			EventType:  domain.EventTypeUnsubscribed,
+			EventData:  map[string]interface{}{"list_id": listID},
+			UserAgent:  ua,
+			IPAddress:  ip,
+			CreatedAt:  now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *SubscriptionService) GetPreferences(ctx context.Context, token string) (*Preferences, error) {
+	subscriberID, err := s.keyring.Verify(PreferencesPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	subscriber, err := s.subscriberRepo.GetByID(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	if subscriber.Status == domain.SubscriberStatusDeleted {
+		return nil, &repository.ErrNotFound{Entity: "Subscriber", ID: subscriberID}
+	}
+
+	prefs := &Preferences{Subscriber: subscriber}
+	for _, membership := range subscriber.Lists {
+		list, err := s.listRepo.GetByID(ctx, membership.ListID)
+		if err != nil {
+			var notFound *repository.ErrNotFound
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return nil, err
+		}
+		if list.DeletedAt != nil {
+			continue
+		}
+		prefs.Lists = append(prefs.Lists, ListPreference{List: list, Status: membership.Status})
+	}
+	sort.Slice(prefs.Lists, func(i, j int) bool {
+		return prefs.Lists[i].List.Name < prefs.Lists[j].List.Name
+	})
+	return prefs, nil
+}
+
+func (s *SubscriptionService) UpdatePreferences(ctx context.Context, token string, subscribed []string, unsubscribeAll bool) (*Preferences, error) {
+	prefs, err := s.GetPreferences(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool, len(subscribed))
+	for _, id := range subscribed {
+		keep[id] = !unsubscribeAll
+	}
+	changes := make(map[string]domain.SubscriberListStatus)
+	for _, p := range prefs.Lists {
+		if !p.Editable() {
+			continue
+		}
+		want := domain.SubscriberListStatusUnsubscribed
+		if keep[p.List.ID] {
+			want = domain.SubscriberListStatusConfirmed
+		}
+		if want != p.Status {
+			changes[p.List.ID] = want
+		}
+	}
+	if len(changes) == 0 {
+		return prefs, nil
+	}
+
+	if err := s.listService.UpdateSubscriptions(ctx, prefs.Subscriber.ID, changes); err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(ctx, token)
+}
+
+func (s *SubscriptionService) Subscribe(ctx context.Context, email string, name string, listIDs []string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != strings.TrimSpace(email) {
+		return fmt.Errorf("%w: invalid email address", ErrInvalidSubscription)
+	}
+	if len(listIDs) == 0 {
+		return fmt.Errorf("%w: no list given", ErrInvalidSubscription)
+	}
+	if s.publicURL == "" {
+		return errors.New("public url is not configured")
+	}
+
+	var lists []*domain.List
+	seen := make(map[string]bool, len(listIDs))
+	for _, id := range listIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		list, err := s.listRepo.GetByID(ctx, id)
+		if err != nil {
+			var notFound *repository.ErrNotFound
+			if !errors.As(err, &notFound) {
+				return err
+			}
+		}
+		if list == nil || list.DeletedAt != nil || list.ConfirmationTemplateID == nil {
+			return fmt.Errorf("%w: list %s does not accept subscriptions", ErrInvalidSubscription, id)
+		}
+		lists = append(lists, list)
+	}
+
+	now := time.Now().Unix()
+	return repository.Transactional0(s.db, ctx, func(txCtx context.Context) error {
+		subscriber, err := s.subscriberRepo.GetByEmail(txCtx, addr.Address)
+		var notFound *repository.ErrNotFound
+		isNew := errors.As(err, &notFound)
+		switch {
+		case isNew:
+			subscriber = &domain.Subscriber{
+				ID:        uuid.NewString(),
+				Email:     addr.Address,
+				Name:      name,
+				Status:    domain.SubscriberStatusEnabled,
+				CreatedAt: now,
+			}
+		case err != nil:
+			return err
+		case subscriber.Status == domain.SubscriberStatusDisabled:
+			// disabled by an operator; accept the request without sending mail
+			return nil
+		case subscriber.Status == domain.SubscriberStatusDeleted:
+			subscriber.Status = domain.SubscriberStatusEnabled
+		}
+
+		memberships := make(map[string]domain.SubscriberList, len(subscriber.Lists))
+		for _, m := range subscriber.Lists {
+			memberships[m.ListID] = m
+		}
+		var pending []domain.SubscriberList
+		var confirm []*domain.List
+		for _, list := range lists {
+			m, ok := memberships[list.ID]
+			if ok && m.Status == domain.SubscriberListStatusConfirmed {
+				continue
+			}
+			if ok && m.Status == domain.SubscriberListStatusPending && m.UpdatedAt > now-int64(confirmationResendInterval.Seconds()) {
+				continue
+			}
+			pending = append(pending, domain.SubscriberList{
+				ListID:    list.ID,
+				Status:    domain.SubscriberListStatusPending,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			confirm = append(confirm, list)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		subscriber.Lists = pending
+		subscriber.UpdatedAt = now
+		if isNew {
+			err = s.subscriberRepo.Create(txCtx, subscriber)
+		} else {
+			err = s.subscriberRepo.Update(txCtx, subscriber)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, list := range confirm {
+			if err := s.sendConfirmation(txCtx, subscriber, list); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sendConfirmation sends the list's confirmation template to the subscriber as a
+// transactional delivery.
+func (s *SubscriptionService) sendConfirmation(ctx context.Context, subscriber *domain.Subscriber, list *domain.List) error {
+	tmpl, err := s.templateRepo.GetByID(ctx, *list.ConfirmationTemplateID)
+	if err != nil {
+		return err
+	}
+	token := ConfirmationToken(s.keyring, subscriber.ID, list.ID)
+	delivery := &domain.Delivery{
+		ListID:  &list.ID,
+		Type:    domain.DeliveryTypeTransaction,
+		Status:  domain.DeliveryStatusScheduled,
+		Name:    subscriber.Name,
+		Email:   subscriber.Email,
+		Subject: tmpl.Subject,
+		Data: map[string]interface{}{
+			"confirmUrl":  publicLink(s.publicURL, "/subscribe/confirm/"+token),
+			"listId":      list.ID,
+			"listName":    list.Name,
+			"template_id": tmpl.ID,
+		},
+	}
+	return s.deliveryService.CreateDelivery(ctx, delivery, tmpl.BodyMJML)
+}
+
+func (s *SubscriptionService) ResolveConfirmation(ctx context.Context, token string) (*Confirmation, error) {
+	payload, err := s.keyring.Verify(ConfirmationPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	subscriberID, listID, ok := strings.Cut(payload, "\n")
+	if !ok {
+		return nil, signing.ErrInvalidToken
+	}
+
+	subscriber, err := s.subscriberRepo.GetByID(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	if subscriber.Status == domain.SubscriberStatusDeleted {
+		return nil, &repository.ErrNotFound{Entity: "Subscriber", ID: subscriberID}
+	}
+	list, err := s.listRepo.GetByID(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	if list.DeletedAt != nil {
+		return nil, &repository.ErrNotFound{Entity: "List", ID: listID}
+	}
+
+	for _, m := range subscriber.Lists {
+		if m.ListID == listID {
+			return &Confirmation{Subscriber: subscriber, List: list, Status: m.Status}, nil
+		}
+	}
+	// the pending membership expired
+	return nil, &repository.ErrNotFound{Entity: "SubscriberList", ID: listID}
+}
+
+func (s *SubscriptionService) ConfirmSubscription(ctx context.Context, token string) (*Confirmation, error) {
+	c, err := s.ResolveConfirmation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Status {
+	case domain.SubscriberListStatusConfirmed:
+		return c, nil
+	case domain.SubscriberListStatusPending:
+	default:
+		// the recipient left the list after the link was sent; an old link must not resubscribe them
+		return nil, &repository.ErrNotFound{Entity: "SubscriberList", ID: c.List.ID}
+	}
+
+	changes := map[string]domain.SubscriberListStatus{c.List.ID: domain.SubscriberListStatusConfirmed}
+	if err := s.listService.UpdateSubscriptions(ctx, c.Subscriber.ID, changes); err != nil {
+		return nil, err
+	}
+	c.Status = domain.SubscriberListStatusConfirmed
+	return c, nil
+}
+
+func (s *SubscriptionService) ExpirePendingSubscriptions(ctx context.Context, before time.Time) (int, error) {
+	return s.subscriberRepo.DeleteListMemberships(ctx, domain.SubscriberListStatusPending, before.Unix())
+}
+
+// UnsubscribeToken returns the unsubscribe token of a delivery.
+func UnsubscribeToken(keyring *signing.Keyring, deliveryID string) string {
+	return keyring.Sign(UnsubscribePurpose, deliveryID)
+}
+
+// PreferencesToken returns the preference center token of a subscriber.
+func PreferencesToken(keyring *signing.Keyring, subscriberID string) string {
+	return keyring.Sign(PreferencesPurpose, subscriberID)
+}
+
+// ConfirmationToken returns the confirmation token of a subscriber's pending membership in a list.
+func ConfirmationToken(keyring *signing.Keyring, subscriberID string, listID string) string {
+	return keyring.Sign(ConfirmationPurpose, subscriberID+"\n"+listID)
+}
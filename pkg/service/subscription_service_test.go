@@ -0,0 +1,281 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/internal/db/sqlite"
+	"github.com/headmail/headmail/pkg/config"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/signing"
+	"github.com/headmail/headmail/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) repository.DB {
+	db, err := sqlite.New(config.DatabaseConfig{
+		URL: "file:" + uuid.NewString() + "?mode=memory&cache=shared",
+	})
+	require.NoError(t, err)
+	_, err = db.MigrateUp(context.Background(), 0)
+	require.NoError(t, err)
+	sqlDB, err := db.DB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func newTestKeyring(t *testing.T) *signing.Keyring {
+	key, err := signing.GenerateKey()
+	require.NoError(t, err)
+	keyring, err := signing.NewKeyring(key)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestSubscriptionService_Unsubscribe(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	keyring := newTestKeyring(t)
+	svc := NewSubscriptionService(db, NewListService(db), nil, keyring, "")
+
+	for _, id := range []string{"news", "offers"} {
+		require.NoError(t, db.ListRepository().Create(ctx, &domain.List{ID: id, Name: id}))
+	}
+	require.NoError(t, db.SubscriberRepository().Create(ctx, &domain.Subscriber{ID: "sub-1", Email: "bob@example.com", Status: domain.SubscriberStatusEnabled}))
+	require.NoError(t, db.ListRepository().AddSubscribers(ctx, "news", []string{"sub-1"}))
+	require.NoError(t, db.ListRepository().AddSubscribers(ctx, "offers", []string{"sub-1"}))
+
+	campaignID, listID := "camp-1", "news"
+	require.NoError(t, db.DeliveryRepository().Create(ctx, &domain.Delivery{
+		ID:         "del-1",
+		CampaignID: &campaignID,
+		ListID:     &listID,
+		Type:       domain.DeliveryTypeCampaign,
+		Status:     domain.DeliveryStatusSent,
+		Email:      "bob@example.com",
+	}))
+	token := UnsubscribeToken(keyring, "del-1")
+
+	// a retried one-click POST must not record a second event
+	for i := 0; i < 2; i++ {
+		d, err := svc.Unsubscribe(ctx, token, nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "del-1", d.ID)
+	}
+
+	sub, err := db.SubscriberRepository().GetByID(ctx, "sub-1")
+	require.NoError(t, err)
+	statuses := map[string]domain.SubscriberListStatus{}
+	for _, l := range sub.Lists {
+		statuses[l.ListID] = l.Status
+	}
+	assert.Equal(t, domain.SubscriberListStatusUnsubscribed, statuses["news"])
+	assert.Equal(t, domain.SubscriberListStatusConfirmed, statuses["offers"])
+
+	events, err := db.EventRepository().ListByCampaignAndRange(ctx, []string{campaignID}, 0, time.Now().Unix()+1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.EventTypeUnsubscribed, events[0].EventType)
+	assert.Equal(t, "news", events[0].EventData["list_id"])
+
+	_, err = svc.Unsubscribe(ctx, token[:len(token)-2], nil, nil)
+	assert.ErrorIs(t, err, signing.ErrInvalidToken)
+
+	// a delivery without a list unsubscribes from all lists, but keeps the memberships
+	// ended by a bounce or complaint
+	require.NoError(t, db.ListRepository().Create(ctx, &domain.List{ID: "alerts", Name: "alerts"}))
+	require.NoError(t, db.ListRepository().AddSubscribers(ctx, "alerts", []string{"sub-1"}))
+	_, err = db.SubscriberRepository().SetListStatus(ctx, "sub-1", "offers", domain.SubscriberListStatusBounced, time.Now().Unix())
+	require.NoError(t, err)
+	require.NoError(t, db.DeliveryRepository().Create(ctx, &domain.Delivery{
+		ID:         "del-2",
+		CampaignID: &campaignID,
+		Type:       domain.DeliveryTypeCampaign,
+		Status:     domain.DeliveryStatusSent,
+		Email:      "bob@example.com",
+	}))
+	_, err = svc.Unsubscribe(ctx, UnsubscribeToken(keyring, "del-2"), nil, nil)
+	require.NoError(t, err)
+	sub, err = db.SubscriberRepository().GetByID(ctx, "sub-1")
+	require.NoError(t, err)
+	statuses = map[string]domain.SubscriberListStatus{}
+	for _, l := range sub.Lists {
+		statuses[l.ListID] = l.Status
+	}
+	assert.Equal(t, map[string]domain.SubscriberListStatus{
+		"news":   domain.SubscriberListStatusUnsubscribed,
+		"offers": domain.SubscriberListStatusBounced,
+		"alerts": domain.SubscriberListStatusUnsubscribed,
+	}, statuses)
+}
+
+func TestSubscriptionService_Preferences(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	keyring := newTestKeyring(t)
+	lists := NewListService(db)
+	svc := NewSubscriptionService(db, lists, nil, keyring, "")
+
+	for _, id := range []string{"a-news", "b-offers", "c-events"} {
+		require.NoError(t, lists.CreateList(ctx, &domain.List{ID: id, Name: id}))
+	}
+	require.NoError(t, db.SubscriberRepository().Create(ctx, &domain.Subscriber{ID: "sub-1", Email: "bob@example.com", Status: domain.SubscriberStatusEnabled}))
+	for _, id := range []string{"a-news", "b-offers", "c-events"} {
+		require.NoError(t, lists.PatchSubscribersInList(ctx, id, []string{"sub-1"}, nil))
+	}
+	require.NoError(t, lists.UpdateSubscriptions(ctx, "sub-1", map[string]domain.SubscriberListStatus{"c-events": domain.SubscriberListStatusBounced}))
+	token := PreferencesToken(keyring, "sub-1")
+
+	statuses := func(p *Preferences) map[string]domain.SubscriberListStatus {
+		m := map[string]domain.SubscriberListStatus{}
+		for _, l := range p.Lists {
+			m[l.List.ID] = l.Status
+		}
+		return m
+	}
+
+	prefs, err := svc.GetPreferences(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", prefs.Subscriber.Email)
+	require.Len(t, prefs.Lists, 3)
+	assert.Equal(t, "a-news", prefs.Lists[0].List.ID)
+	assert.False(t, prefs.Lists[2].Editable())
+
+	// unchecked lists are unsubscribed; bounced memberships cannot be resubscribed
+	prefs, err = svc.UpdatePreferences(ctx, token, []string{"a-news", "c-events"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.SubscriberListStatus{
+		"a-news":   domain.SubscriberListStatusConfirmed,
+		"b-offers": domain.SubscriberListStatusUnsubscribed,
+		"c-events": domain.SubscriberListStatusBounced,
+	}, statuses(prefs))
+	count, err := lists.GetSubscriberCount(ctx, "b-offers")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// resubscribe
+	prefs, err = svc.UpdatePreferences(ctx, token, []string{"a-news", "b-offers"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SubscriberListStatusConfirmed, statuses(prefs)["b-offers"])
+
+	prefs, err = svc.UpdatePreferences(ctx, token, []string{"a-news"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SubscriberListStatusUnsubscribed, statuses(prefs)["a-news"])
+	assert.Equal(t, domain.SubscriberListStatusUnsubscribed, statuses(prefs)["b-offers"])
+
+	// unsubscribe tokens are not accepted by the preference center
+	_, err = svc.GetPreferences(ctx, UnsubscribeToken(keyring, "sub-1"))
+	assert.ErrorIs(t, err, signing.ErrInvalidToken)
+}
+
+func TestSubscriptionService_DoubleOptIn(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	keyring := newTestKeyring(t)
+	lists := NewListService(db)
+	deliveries := NewDeliveryService(db, template.NewService(), db.QueueRepository(), nil, "https://mail.example.com", queue.DefaultRetryPolicy, mailer.SenderPolicy{}, keyring, BouncePolicy{})
+	svc := NewSubscriptionService(db, lists, deliveries, keyring, "https://mail.example.com")
+
+	tmpl := &domain.Template{
+		Subject:  "Confirm {{ .listName }}",
+		BodyMJML: `<mjml><mj-body><mj-section><mj-column><mj-button href="{{ .confirmUrl }}">Confirm</mj-button></mj-column></mj-section></mj-body></mjml>`,
+	}
+	require.NoError(t, db.TemplateRepository().Create(ctx, tmpl))
+	require.NoError(t, lists.CreateList(ctx, &domain.List{ID: "news", Name: "News", ConfirmationTemplateID: &tmpl.ID}))
+	require.NoError(t, lists.CreateList(ctx, &domain.List{ID: "internal", Name: "Internal"}))
+
+	err := svc.Subscribe(ctx, "not an address", "", []string{"news"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	err = svc.Subscribe(ctx, "bob@example.com", "", []string{"internal"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+	sent := func(email string) []*domain.Delivery {
+		ds, _, err := db.DeliveryRepository().List(ctx, repository.DeliveryFilter{Email: email}, repository.Pagination{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		return ds
+	}
+	confirmToken := func(d *domain.Delivery) string {
+		u := d.Data["confirmUrl"].(string)
+		require.True(t, strings.HasPrefix(u, "https://mail.example.com/subscribe/confirm/"), u)
+		return u[strings.LastIndex(u, "/")+1:]
+	}
+
+	require.NoError(t, svc.Subscribe(ctx, "bob@example.com", "Bob", []string{"news"}))
+	ds := sent("bob@example.com")
+	require.Len(t, ds, 1)
+	assert.Equal(t, domain.DeliveryTypeTransaction, ds[0].Type)
+	assert.Equal(t, domain.DeliveryStatusQueued, ds[0].Status)
+	assert.Equal(t, "Confirm News", ds[0].Subject)
+	token := confirmToken(ds[0])
+	assert.Contains(t, ds[0].BodyHTML, token)
+
+	// pending members are not counted and a repeated request does not send another mail
+	count, err := lists.GetSubscriberCount(ctx, "news")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	require.NoError(t, svc.Subscribe(ctx, "bob@example.com", "Bob", []string{"news"}))
+	assert.Len(t, sent("bob@example.com"), 1)
+
+	c, err := svc.ResolveConfirmation(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SubscriberListStatusPending, c.Status)
+	assert.Equal(t, "bob@example.com", c.Subscriber.Email)
+
+	// confirming is idempotent
+	for i := 0; i < 2; i++ {
+		c, err = svc.ConfirmSubscription(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, domain.SubscriberListStatusConfirmed, c.Status)
+	}
+	count, err = lists.GetSubscriberCount(ctx, "news")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// unconfirmed subscriptions expire; confirmed ones are kept
+	require.NoError(t, svc.Subscribe(ctx, "eve@example.com", "", []string{"news"}))
+	ds = sent("eve@example.com")
+	require.Len(t, ds, 1)
+	n, err := svc.ExpirePendingSubscriptions(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = svc.ConfirmSubscription(ctx, confirmToken(ds[0]))
+	var notFound *repository.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+	count, err = lists.GetSubscriberCount(ctx, "news")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// an old link must not resubscribe a recipient who left the list
+	require.NoError(t, lists.UpdateSubscriptions(ctx, c.Subscriber.ID, map[string]domain.SubscriberListStatus{"news": domain.SubscriberListStatusUnsubscribed}))
+	_, err = svc.ConfirmSubscription(ctx, token)
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestUnsubscribeHeaders(t *testing.T) {
+	s := &DeliveryService{trackingHost: "tracking.example.com/", keyring: newTestKeyring(t)}
+
+	d := &domain.Delivery{ID: "del-1", Type: domain.DeliveryTypeCampaign, Headers: map[string]string{"X-Tag": "a"}}
+	headers := s.unsubscribeHeaders(d)
+	assert.Equal(t, "a", headers["X-Tag"])
+	assert.Equal(t, "List-Unsubscribe=One-Click", headers["List-Unsubscribe-Post"])
+	assert.True(t, strings.HasPrefix(headers["List-Unsubscribe"], "<https://tracking.example.com/u/"), headers["List-Unsubscribe"])
+	assert.Len(t, d.Headers, 1, "stored headers must not be modified")
+
+	// transactional mail and custom List-Unsubscribe headers are left alone
+	d.Type = domain.DeliveryTypeTransaction
+	assert.Equal(t, d.Headers, s.unsubscribeHeaders(d))
+	d.Type = domain.DeliveryTypeCampaign
+	d.Headers = map[string]string{"list-unsubscribe": "<mailto:leave@example.com>"}
+	assert.Equal(t, d.Headers, s.unsubscribeHeaders(d))
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/repository"
+)
+
+// ErrInvalidSuppression is returned for suppression entries with an invalid type or value.
+var ErrInvalidSuppression = errors.New("invalid suppression")
+
+// SuppressionServiceProvider defines the interface for managing the global suppression list.
+type SuppressionServiceProvider interface {
+	// CreateSuppression adds an entry, or updates the existing entry for the same value.
+	CreateSuppression(ctx context.Context, suppression *domain.Suppression) error
+	// ImportSuppressions adds or updates all entries atomically and returns their number.
+	ImportSuppressions(ctx context.Context, suppressions []*domain.Suppression) (int, error)
+	GetSuppression(ctx context.Context, id string) (*domain.Suppression, error)
+	DeleteSuppression(ctx context.Context, id string) error
+	ListSuppressions(ctx context.Context, filter repository.SuppressionFilter, pagination repository.Pagination) ([]*domain.Suppression, int, error)
+	// CheckSuppression returns the entry that suppresses email, or nil when it may be mailed.
+	CheckSuppression(ctx context.Context, email string) (*domain.Suppression, error)
+}
+
+// SuppressionService provides business logic for the global suppression list.
+type SuppressionService struct {
+	db   repository.DB
+	repo repository.SuppressionRepository
+}
+
+// NewSuppressionService creates a new SuppressionService.
+func NewSuppressionService(db repository.DB) *SuppressionService {
+	return &SuppressionService{
+		db:   db,
+		repo: db.SuppressionRepository(),
+	}
+}
+
+// CreateSuppression adds an entry. Source defaults to admin and reason to manual.
+func (s *SuppressionService) CreateSuppression(ctx context.Context, suppression *domain.Suppression) error {
+	if suppression.Source == "" {
+		suppression.Source = domain.SuppressionSourceAdmin
+	}
+	if err := prepareSuppression(suppression); err != nil {
+		return err
+	}
+	return s.repo.Upsert(ctx, suppression)
+}
+
+// ImportSuppressions adds entries in bulk. Source defaults to import and reason to manual.
+func (s *SuppressionService) ImportSuppressions(ctx context.Context, suppressions []*domain.Suppression) (int, error) {
+	for i, suppression := range suppressions {
+		if suppression.Source == "" {
+			suppression.Source = domain.SuppressionSourceImport
+		}
+		if err := prepareSuppression(suppression); err != nil {
+			return 0, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+	}
+	return repository.Transactional1(s.db, ctx, func(txCtx context.Context) (int, error) {
+		for _, suppression := range suppressions {
+			if err := s.repo.Upsert(txCtx, suppression); err != nil {
+				return 0, err
+			}
+		}
+		return len(suppressions), nil
+	})
+}
+
+// GetSuppression retrieves an entry by its ID.
+func (s *SuppressionService) GetSuppression(ctx context.Context, id string) (*domain.Suppression, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// DeleteSuppression deletes an entry by its ID.
+func (s *SuppressionService) DeleteSuppression(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// ListSuppressions lists entries matching the filter, newest first.
+func (s *SuppressionService) ListSuppressions(ctx context.Context, filter repository.SuppressionFilter, pagination repository.Pagination) ([]*domain.Suppression, int, error) {
+	return s.repo.List(ctx, filter, pagination)
+}
+
+// CheckSuppression returns the entry that suppresses email, or nil.
+func (s *SuppressionService) CheckSuppression(ctx context.Context, email string) (*domain.Suppression, error) {
+	return matchSuppression(ctx, s.repo, email)
+}
+
+// matchSuppression returns the unexpired entry that suppresses email by address or
+// domain, or nil when there is none.
+func matchSuppression(ctx context.Context, repo repository.SuppressionRepository, email string) (*domain.Suppression, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	domainName := email[strings.LastIndex(email, "@")+1:]
+	suppression, err := repo.Match(ctx, email, domainName, time.Now().Unix())
+	if err != nil {
+		var notFound *repository.ErrNotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return suppression, nil
+}
+
+// prepareSuppression normalizes and validates an entry and fills in ID, defaults and
+// timestamps. An empty type is derived from the value: addresses are emails, anything
+// else (including "@example.com") is a domain.
+func prepareSuppression(suppression *domain.Suppression) error {
+	value := strings.ToLower(strings.TrimSpace(suppression.Value))
+	if suppression.Type == "" {
+		suppression.Type = domain.SuppressionTypeDomain
+		if strings.Contains(strings.TrimPrefix(value, "@"), "@") {
+			suppression.Type = domain.SuppressionTypeEmail
+		}
+	}
+
+	switch suppression.Type {
+	case domain.SuppressionTypeEmail:
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return fmt.Errorf("%w: invalid email address '%s'", ErrInvalidSuppression, suppression.Value)
+		}
+	case domain.SuppressionTypeDomain:
+		value = strings.TrimPrefix(value, "@")
+		if value == "" || strings.ContainsAny(value, "@ \t") {
+			return fmt.Errorf("%w: invalid domain '%s'", ErrInvalidSuppression, suppression.Value)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type '%s'", ErrInvalidSuppression, suppression.Type)
+	}
+	suppression.Value = value
+
+	if suppression.Reason == "" {
+		suppression.Reason = domain.SuppressionReasonManual
+	}
+	if suppression.ID == "" {
+		suppression.ID = uuid.NewString()
+	}
+	now := time.Now().Unix()
+	if suppression.CreatedAt == 0 {
+		suppression.CreatedAt = now
+	}
+	suppression.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/headmail/headmail/pkg/domain"
+	"github.com/headmail/headmail/pkg/mailer"
+	"github.com/headmail/headmail/pkg/queue"
+	"github.com/headmail/headmail/pkg/receiver"
+	"github.com/headmail/headmail/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareSuppression(t *testing.T) {
+	s := &domain.Suppression{Value: " Bob@Example.COM "}
+	require.NoError(t, prepareSuppression(s))
+	assert.Equal(t, domain.SuppressionTypeEmail, s.Type)
+	assert.Equal(t, "bob@example.com", s.Value)
+	assert.Equal(t, domain.SuppressionReasonManual, s.Reason)
+	assert.NotEmpty(t, s.ID)
+
+	s = &domain.Suppression{Value: "@Spam.Example"}
+	require.NoError(t, prepareSuppression(s))
+	assert.Equal(t, domain.SuppressionTypeDomain, s.Type)
+	assert.Equal(t, "spam.example", s.Value)
+
+	for _, invalid := range []*domain.Suppression{
+		{Value: ""},
+		{Type: domain.SuppressionTypeEmail, Value: "not-an-address"},
+		{Type: "ip", Value: "127.0.0.1"},
+	} {
+		assert.ErrorIs(t, prepareSuppression(invalid), ErrInvalidSuppression)
+	}
+}
+
+func TestDeliveryService_Suppression(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	suppressions := NewSuppressionService(db)
+	// a nil mailer fails the test if a suppressed delivery is sent
+	svc := NewDeliveryService(db, template.NewService(), db.QueueRepository(), nil, "", queue.DefaultRetryPolicy, mailer.SenderPolicy{}, newTestKeyring(t), BouncePolicy{})
+	const body = "<mjml><mj-body></mj-body></mjml>"
+
+	require.NoError(t, suppressions.CreateSuppression(ctx, &domain.Suppression{Value: "blocked.example"}))
+	blocked := &domain.Delivery{Type: domain.DeliveryTypeTransaction, Status: domain.DeliveryStatusScheduled, Email: "Alice@Blocked.example"}
+	require.NoError(t, svc.CreateDelivery(ctx, blocked, body))
+	assert.Equal(t, domain.DeliveryStatusSuppressed, blocked.Status)
+	items, total, err := db.QueueRepository().List(ctx, queue.Filter{}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total, "suppressed deliveries are not enqueued")
+	assert.Empty(t, items)
+
+	// an address suppressed after the delivery was queued is not sent by the worker
+	d := &domain.Delivery{Type: domain.DeliveryTypeTransaction, Status: domain.DeliveryStatusScheduled, Email: "bob@example.com"}
+	require.NoError(t, svc.CreateDelivery(ctx, d, body))
+	items, _, err = db.QueueRepository().List(ctx, queue.Filter{}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, svc.HandleBouncedMail(ctx, &receiver.Event{DeliveryID: d.ID, Reason: "permanent failure (5.1.1)", Permanent: true}))
+	suppression, err := suppressions.CheckSuppression(ctx, "bob@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, suppression)
+	assert.Equal(t, domain.SuppressionReasonHardBounce, suppression.Reason)
+	assert.Equal(t, domain.SuppressionSourceBounce, suppression.Source)
+
+	require.NoError(t, svc.HandleDeliveryQueuedItem(ctx, "worker-1", items[0]))
+	got, err := svc.GetDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryStatusSuppressed, got.Status)
+	require.NotNil(t, got.FailureReason)
+	assert.Contains(t, *got.FailureReason, "bob@example.com")
+}
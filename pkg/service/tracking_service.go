@@ -5,17 +5,45 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/headmail/headmail/pkg/domain"
 	"github.com/headmail/headmail/pkg/repository"
+	"github.com/headmail/headmail/pkg/signing"
+)
+
+const (
+	// OpenPurpose is the signing purpose of open tracking URLs.
+	OpenPurpose = "open"
+	// ClickPurpose is the signing purpose of click tracking URLs.
+	ClickPurpose = "click"
+	// LinkPurpose is the signing purpose of short link click tracking URLs.
+	LinkPurpose = "link"
+
+	// linkIDLength is the length of link IDs, which carry 60 bits of the link hash.
+	linkIDLength = 10
 )
 
 // TrackingServiceProvider defines the interface for a tracking service.
 type TrackingServiceProvider interface {
 	LogOpenEvent(ctx context.Context, deliveryID string, ua *string, ip *string) error
 	LogClickEvent(ctx context.Context, deliveryID string, ua *string, ip *string, url string) error
+	// VerifyOpen reports whether sig is the signature of the open tracking URL of a delivery.
+	VerifyOpen(ctx context.Context, deliveryID string, sig string) bool
+	// VerifyClick reports whether sig is the signature of the click tracking URL of a
+	// delivery redirecting to target.
+	VerifyClick(ctx context.Context, deliveryID string, target string, sig string) bool
+	// VerifyLink reports whether sig is the signature of the short link tracking URL
+	// of a delivery.
+	VerifyLink(deliveryID string, linkID string, sig string) bool
+	GetLink(ctx context.Context, id string) (*domain.Link, error)
+	// LogLinkClick records a click of a delivery on a short link.
+	LogLinkClick(ctx context.Context, deliveryID string, link *domain.Link, ua *string, ip *string) error
 }
 
 // TrackingService provides business logic for tracking management.
@@ -23,15 +51,107 @@ type TrackingService struct {
 	deliveryRepo repository.DeliveryRepository
 	eventRepo    repository.EventRepository
 	campaignRepo repository.CampaignRepository
+	linkRepo     repository.LinkRepository
+	keyring      *signing.Keyring
+	// unsignedBefore accepts unsigned open and click URLs of deliveries created before it.
+	// It is a migration aid for mail sent before tracking URLs were signed.
+	unsignedBefore time.Time
 }
 
-// NewTrackingService creates a new TrackingService.
-func NewTrackingService(db repository.DB) *TrackingService {
+// NewTrackingService creates a new TrackingService. Tracking URLs are verified with
+// keyring, which must be the keyring of the DeliveryService signing them. Open and click
+// URLs without a signature are accepted for deliveries created before unsignedBefore, so
+// mail sent before tracking URLs were signed keeps working; the zero time accepts none.
+// Unsigned clicks only redirect to targets the delivery's mail linked to.
+func NewTrackingService(db repository.DB, keyring *signing.Keyring, unsignedBefore time.Time) *TrackingService {
 	return &TrackingService{
-		deliveryRepo: db.DeliveryRepository(),
-		eventRepo:    db.EventRepository(),
-		campaignRepo: db.CampaignRepository(),
+		deliveryRepo:   db.DeliveryRepository(),
+		eventRepo:      db.EventRepository(),
+		campaignRepo:   db.CampaignRepository(),
+		linkRepo:       db.LinkRepository(),
+		keyring:        keyring,
+		unsignedBefore: unsignedBefore,
+	}
+}
+
+// VerifyOpen checks the signature of an open tracking URL with every key of the
+// keyring, so URLs in mail signed with a retired key keep working.
+func (s *TrackingService) VerifyOpen(ctx context.Context, deliveryID string, sig string) bool {
+	if sig == "" {
+		_, ok := s.unsignedDelivery(ctx, deliveryID)
+		return ok
+	}
+	return s.keyring.VerifyTag(OpenPurpose, deliveryID, sig)
+}
+
+// VerifyClick checks the signature of a click tracking URL like VerifyOpen.
+func (s *TrackingService) VerifyClick(ctx context.Context, deliveryID string, target string, sig string) bool {
+	if sig == "" {
+		d, ok := s.unsignedDelivery(ctx, deliveryID)
+		return ok && s.linkedTarget(ctx, d, target)
 	}
+	return s.keyring.VerifyTag(ClickPurpose, clickPayload(deliveryID, target), sig)
+}
+
+// unsignedDelivery returns the delivery if it was created before tracking URLs were
+// signed, so its URLs carry no signature.
+func (s *TrackingService) unsignedDelivery(ctx context.Context, deliveryID string) (*domain.Delivery, bool) {
+	if s.unsignedBefore.IsZero() {
+		return nil, false
+	}
+	d, err := s.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil || d.CreatedAt >= s.unsignedBefore.Unix() {
+		return nil, false
+	}
+	return d, true
+}
+
+// linkedTarget reports whether the mail of d linked to target: its stored body has the
+// unsigned click URL of target, or its campaign has a stored link to target. This keeps
+// unsigned click URLs from redirecting anywhere else.
+func (s *TrackingService) linkedTarget(ctx context.Context, d *domain.Delivery, target string) bool {
+	// unsigned click URLs end with the escaped target and the closing quote of the href
+	if strings.Contains(d.BodyHTML, "/r/"+d.ID+"/c?u="+url.QueryEscape(target)+`"`) {
+		return true
+	}
+	if d.CampaignID == nil {
+		return false
+	}
+	link, err := s.linkRepo.GetByID(ctx, LinkID(*d.CampaignID, target))
+	return err == nil && link.URL == target
+}
+
+// VerifyLink checks the signature of a short link tracking URL like VerifyOpen.
+func (s *TrackingService) VerifyLink(deliveryID string, linkID string, sig string) bool {
+	return s.keyring.VerifyTag(LinkPurpose, clickPayload(deliveryID, linkID), sig)
+}
+
+// OpenSignature returns the signature of the open tracking URL of a delivery.
+func OpenSignature(keyring *signing.Keyring, deliveryID string) string {
+	return keyring.Tag(OpenPurpose, deliveryID)
+}
+
+// ClickSignature returns the signature of the click tracking URL of a delivery
+// redirecting to target.
+func ClickSignature(keyring *signing.Keyring, deliveryID string, target string) string {
+	return keyring.Tag(ClickPurpose, clickPayload(deliveryID, target))
+}
+
+// LinkSignature returns the signature of the short link tracking URL of a delivery.
+func LinkSignature(keyring *signing.Keyring, deliveryID string, linkID string) string {
+	return keyring.Tag(LinkPurpose, clickPayload(deliveryID, linkID))
+}
+
+// LinkID returns the short ID of the link to target in mail of a campaign. IDs are
+// derived from the campaign and target, so concurrent renders of a campaign agree on
+// them without coordination.
+func LinkID(campaignID string, target string) string {
+	sum := sha256.Sum256([]byte(campaignID + "\n" + target))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:linkIDLength]
+}
+
+func clickPayload(deliveryID string, target string) string {
+	return deliveryID + "\n" + target
 }
 
 func (s *TrackingService) LogOpenEvent(ctx context.Context, deliveryID string, ua *string, ip *string) error {
@@ -56,7 +176,7 @@ func (s *TrackingService) LogOpenEvent(ctx context.Context, deliveryID string, u
 	if isFirst {
 		if d, err := s.deliveryRepo.GetByID(ctx, deliveryID); err == nil {
 			if d.CampaignID != nil && *d.CampaignID != "" {
-				if err := s.campaignRepo.IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 1, 0, 0); err != nil {
+				if err := s.campaignRepo.IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 1, 0, 0, 0); err != nil {
 					log.Printf("tracking: failed to increment campaign open count for campaign %s: %v", *d.CampaignID, err)
 				}
 			}
@@ -70,13 +190,28 @@ func (s *TrackingService) LogOpenEvent(ctx context.Context, deliveryID string, u
 	return s.eventRepo.Create(ctx, ev)
 }
 
+func (s *TrackingService) GetLink(ctx context.Context, id string) (*domain.Link, error) {
+	return s.linkRepo.GetByID(ctx, id)
+}
+
+func (s *TrackingService) LogLinkClick(ctx context.Context, deliveryID string, link *domain.Link, ua *string, ip *string) error {
+	if err := s.linkRepo.IncrementClicks(ctx, link.ID); err != nil {
+		log.Printf("tracking: failed to increment click count for link %s: %v", link.ID, err)
+	}
+	return s.logClick(ctx, deliveryID, ua, ip, link.URL, map[string]interface{}{"url": link.URL, "link_id": link.ID})
+}
+
 func (s *TrackingService) LogClickEvent(ctx context.Context, deliveryID string, ua *string, ip *string, url string) error {
+	return s.logClick(ctx, deliveryID, ua, ip, url, map[string]interface{}{"url": url})
+}
+
+func (s *TrackingService) logClick(ctx context.Context, deliveryID string, ua *string, ip *string, url string, data map[string]interface{}) error {
 	now := time.Now()
 
 	ev := &domain.DeliveryEvent{
 		DeliveryID: deliveryID,
 		EventType:  domain.EventTypeClicked,
-		EventData:  map[string]interface{}{"url": url},
+		EventData:  data,
 		UserAgent:  ua,
 		IPAddress:  ip,
 		URL:        &url,
@@ -93,7 +228,7 @@ func (s *TrackingService) LogClickEvent(ctx context.Context, deliveryID string,
 	if isFirst {
 		if d, err := s.deliveryRepo.GetByID(ctx, deliveryID); err == nil {
 			if d.CampaignID != nil && *d.CampaignID != "" {
-				if err := s.campaignRepo.IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 0, 1, 0); err != nil {
+				if err := s.campaignRepo.IncrementStats(ctx, *d.CampaignID, 0, 0, 0, 0, 1, 0, 0); err != nil {
 					log.Printf("tracking: failed to increment campaign click count for campaign %s: %v", *d.CampaignID, err)
 				}
 			}
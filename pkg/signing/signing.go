@@ -0,0 +1,154 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+// Package signing issues and verifies HMAC-signed tokens and tags that are embedded in
+// mail sent to recipients, e.g. unsubscribe links and VERP return paths.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// macSize is the length of the truncated HMAC-SHA256 tag in bytes.
+const macSize = 16
+
+// tagSize is the length of the tags returned by Tag in bytes.
+const tagSize = 8
+
+// ErrInvalidToken is returned when a token is malformed, signed for another
+// purpose or not signed by any key of the keyring.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Key is one HMAC key of a Keyring.
+type Key struct {
+	// ID identifies the key inside tokens so that rotated keys can still verify.
+	ID     string
+	Secret []byte
+}
+
+// Keyring signs with its first key and verifies with all of them, so a new key
+// can be put in front while tokens issued with older keys keep working.
+type Keyring struct {
+	keys []Key
+}
+
+// NewKeyring creates a Keyring. The first key is used for signing.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("signing: at least one key is required")
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if !validKeyID(k.ID) {
+			return nil, fmt.Errorf("signing: invalid key id '%s'", k.ID)
+		}
+		if len(k.Secret) < 16 {
+			return nil, fmt.Errorf("signing: secret of key '%s' must be at least 16 bytes", k.ID)
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("signing: duplicate key id '%s'", k.ID)
+		}
+		seen[k.ID] = true
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// GenerateKey returns a random key.
+func GenerateKey() (Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+	return Key{ID: hex.EncodeToString(secret[:4]), Secret: secret}, nil
+}
+
+// Sign returns a URL-safe token carrying payload. purpose binds the token to one
+// use, so a token issued for one endpoint is not accepted by another.
+func (k *Keyring) Sign(purpose, payload string) string {
+	key := k.keys[0]
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := computeMAC(key, purpose, encoded)
+	return key.ID + "." + encoded + "." + base64.RawURLEncoding.EncodeToString(mac)
+}
+
+// Verify checks a token created by Sign for the same purpose and returns its payload.
+func (k *Keyring) Verify(purpose, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+	key, ok := k.key(parts[0])
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, computeMAC(key, purpose, parts[1])) {
+		return "", ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	return string(payload), nil
+}
+
+// Tag returns a short hex tag authenticating payload, for places too small for a token
+// such as the local part of an email address. Tags do not name their key, so VerifyTag
+// tries every key of the keyring.
+func (k *Keyring) Tag(purpose, payload string) string {
+	return hex.EncodeToString(computeMAC(k.keys[0], purpose, payload)[:tagSize])
+}
+
+// VerifyTag checks a tag created by Tag for the same purpose and payload.
+func (k *Keyring) VerifyTag(purpose, payload, tag string) bool {
+	mac, err := hex.DecodeString(strings.ToLower(tag))
+	if err != nil || len(mac) != tagSize {
+		return false
+	}
+	for _, key := range k.keys {
+		if hmac.Equal(mac, computeMAC(key, purpose, payload)[:tagSize]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Keyring) key(id string) (Key, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// validKeyID reports whether id is non-empty and only uses characters that are
+// URL safe and cannot be confused with the token separator.
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func computeMAC(key Key, purpose, encodedPayload string) []byte {
+	h := hmac.New(sha256.New, key.Secret)
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(key.ID))
+	h.Write([]byte{'.'})
+	h.Write([]byte(encodedPayload))
+	return h.Sum(nil)[:macSize]
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 JC-Lab
+// SPDX-License-Identifier: AGPL-3.0-or-later
+
+package signing
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(id string) Key {
+	return Key{ID: id, Secret: []byte(strings.Repeat(id, 32))[:32]}
+}
+
+func TestKeyring_SignVerify(t *testing.T) {
+	k, err := NewKeyring(newKey("k1"))
+	require.NoError(t, err)
+
+	token := k.Sign("unsubscribe", "delivery-1")
+	assert.Equal(t, token, url.PathEscape(token), "token must be URL safe")
+
+	payload, err := k.Verify("unsubscribe", token)
+	require.NoError(t, err)
+	assert.Equal(t, "delivery-1", payload)
+
+	// a token is only valid for its purpose
+	_, err = k.Verify("preferences", token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestKeyring_RejectsTampering(t *testing.T) {
+	k, err := NewKeyring(newKey("k1"))
+	require.NoError(t, err)
+	token := k.Sign("unsubscribe", "delivery-1")
+	parts := strings.Split(token, ".")
+
+	forged := k.Sign("unsubscribe", "delivery-2")
+	forgedParts := strings.Split(forged, ".")
+
+	for name, tampered := range map[string]string{
+		"payload swapped": parts[0] + "." + forgedParts[1] + "." + parts[2],
+		"unknown key":     "k2." + parts[1] + "." + parts[2],
+		"truncated":       parts[0] + "." + parts[1],
+		"empty":           "",
+		"bad mac":         parts[0] + "." + parts[1] + ".AAAA",
+	} {
+		_, err := k.Verify("unsubscribe", tampered)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := NewKeyring(newKey("k1"))
+	require.NoError(t, err)
+	token := old.Sign("unsubscribe", "delivery-1")
+
+	rotated, err := NewKeyring(newKey("k2"), newKey("k1"))
+	require.NoError(t, err)
+	payload, err := rotated.Verify("unsubscribe", token)
+	require.NoError(t, err)
+	assert.Equal(t, "delivery-1", payload)
+	assert.True(t, strings.HasPrefix(rotated.Sign("unsubscribe", "delivery-1"), "k2."))
+
+	// once the old key is removed its tokens are no longer accepted
+	retired, err := NewKeyring(newKey("k2"))
+	require.NoError(t, err)
+	_, err = retired.Verify("unsubscribe", token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewKeyring_Validation(t *testing.T) {
+	_, err := NewKeyring()
+	assert.Error(t, err)
+	_, err = NewKeyring(Key{ID: "k1", Secret: []byte("short")})
+	assert.Error(t, err)
+	_, err = NewKeyring(Key{ID: "k.1", Secret: newKey("k1").Secret})
+	assert.Error(t, err)
+	_, err = NewKeyring(newKey("k1"), newKey("k1"))
+	assert.Error(t, err)
+
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	_, err = NewKeyring(key)
+	assert.NoError(t, err)
+}
+
+func TestKeyring_Tag(t *testing.T) {
+	old, err := NewKeyring(newKey("k1"))
+	require.NoError(t, err)
+	tag := old.Tag("verp", "delivery-1")
+	assert.Len(t, tag, 16)
+	assert.True(t, old.VerifyTag("verp", "delivery-1", tag))
+	assert.True(t, old.VerifyTag("verp", "delivery-1", strings.ToUpper(tag)), "tags survive case folding")
+	assert.False(t, old.VerifyTag("verp", "delivery-2", tag))
+	assert.False(t, old.VerifyTag("unsubscribe", "delivery-1", tag))
+	assert.False(t, old.VerifyTag("verp", "delivery-1", tag[:8]))
+
+	// tags of a retired key still verify after rotation
+	rotated, err := NewKeyring(newKey("k2"), newKey("k1"))
+	require.NoError(t, err)
+	assert.True(t, rotated.VerifyTag("verp", "delivery-1", tag))
+	assert.NotEqual(t, tag, rotated.Tag("verp", "delivery-1"))
+}
@@ -5,6 +5,7 @@ package template
 
 import (
 	"bytes"
+	htmltemplate "html/template"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
@@ -20,9 +21,40 @@ func NewService() *Service {
 
 // Render renders a template string with the given data.
 func (s *Service) Render(templateStr string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("email").Funcs(funcMap()).Parse(templateStr)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// RenderHTML renders an HTML (or MJML) template string with the given data. Unlike
+// Render, values are escaped for their HTML context, so it is safe for pages that
+// show data entered by recipients.
+func (s *Service) RenderHTML(templateStr string, data map[string]interface{}) (string, error) {
+	tmpl, err := htmltemplate.New("page").Funcs(funcMap()).Parse(templateStr)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func funcMap() map[string]interface{} {
 	// Add i18n function to the template context
-	funcMap := sprig.TxtFuncMap()
-	funcMap["i18n"] = func(data map[string]interface{}, messageID string) (string, error) {
+	funcs := sprig.GenericFuncMap()
+	funcs["i18n"] = func(data map[string]interface{}, messageID string) (string, error) {
 		// Determine locale (default to "en")
 		locale, ok := data["locale"].(string)
 		if !ok || locale == "" {
@@ -51,16 +83,5 @@ func (s *Service) Render(templateStr string, data map[string]interface{}) (strin
 		}
 		return message, nil
 	}
-
-	tmpl, err := template.New("email").Funcs(funcMap).Parse(templateStr)
-	if err != nil {
-		return "", err
-	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
+	return funcs
 }